@@ -0,0 +1,26 @@
+package cdb
+
+// AccessPattern is a hint about how a database file will be read, which lets
+// the operating system tune its caching and read-ahead.
+type AccessPattern int
+
+const (
+	// AccessNormal gives no hint.
+	AccessNormal AccessPattern = iota
+	// AccessRandom hints that the file will mostly be used for lookups, so
+	// read-ahead is wasted.
+	AccessRandom
+	// AccessSequential hints that the file will mostly be scanned from start
+	// to end, for example with ForEachBytes.
+	AccessSequential
+)
+
+// AccessHint returns an Option that passes an access pattern hint to the
+// operating system when the file is opened. It uses posix_fadvise on Linux and
+// FILE_FLAG_RANDOM_ACCESS or FILE_FLAG_SEQUENTIAL_SCAN on Windows, and is
+// ignored elsewhere. It only applies to Open and LazyOpen.
+func AccessHint(p AccessPattern) Option {
+	return func(c *Cdb) {
+		c.access = p
+	}
+}
@@ -0,0 +1,34 @@
+//go:build linux && (amd64 || arm64 || riscv64 || ppc64 || ppc64le || s390x || mips64 || mips64le || loong64)
+
+package cdb
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	fadvRandom     = 1
+	fadvSequential = 2
+)
+
+// openFile opens the named file for reading and applies the access pattern
+// hint with posix_fadvise.
+func openFile(name string, access AccessPattern) (*os.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	advice := 0
+	switch access {
+	case AccessRandom:
+		advice = fadvRandom
+	case AccessSequential:
+		advice = fadvSequential
+	}
+	if advice != 0 {
+		// The hint is only advice, so failing to give it isn't an error.
+		syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, uintptr(advice), 0, 0)
+	}
+	return f, nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows && !(linux && (amd64 || arm64 || riscv64 || ppc64 || ppc64le || s390x || mips64 || mips64le || loong64))
+
+package cdb
+
+import "os"
+
+// openFile opens the named file for reading. Access pattern hints aren't
+// supported on this platform.
+func openFile(name string, access AccessPattern) (*os.File, error) {
+	return os.Open(name)
+}
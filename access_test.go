@@ -0,0 +1,28 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAccessHint(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(newDBBytes(records))
+	tmp.Close()
+
+	for _, p := range []AccessPattern{AccessNormal, AccessRandom, AccessSequential} {
+		db, err := Open(tmp.Name(), AccessHint(p))
+		if err != nil {
+			t.Fatalf("Open error: %v", err)
+		}
+		if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+			t.Errorf("access %v: expected 1, got: %s, %v", p, b, err)
+		}
+		db.Close()
+	}
+}
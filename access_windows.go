@@ -0,0 +1,38 @@
+package cdb
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	fileFlagRandomAccess   = 0x10000000
+	fileFlagSequentialScan = 0x08000000
+)
+
+// openFile opens the named file for reading, passing the access pattern hint
+// to CreateFile, which only accepts it when the file is opened.
+func openFile(name string, access AccessPattern) (*os.File, error) {
+	var flags uint32
+	switch access {
+	case AccessRandom:
+		flags = fileFlagRandomAccess
+	case AccessSequential:
+		flags = fileFlagSequentialScan
+	default:
+		return os.Open(name)
+	}
+	namep, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	// Share delete access too, so the database can be replaced by renaming
+	// over it while it is open.
+	h, err := syscall.CreateFile(namep, syscall.GENERIC_READ,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_ATTRIBUTE_NORMAL|flags, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return os.NewFile(uintptr(h), name), nil
+}
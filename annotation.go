@@ -0,0 +1,144 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+)
+
+// The annotations section starts with the number of annotations, followed by
+// an entry for each one, sorted by record position, followed by the metadata.
+// Each entry is the record position, and the position relative to the start of
+// the metadata and length of the record's metadata.
+const annotationEntrySize = 12
+
+type annotation struct {
+	pos  uint32
+	meta []byte
+}
+
+// Annotate attaches metadata, such as where the record came from, to the
+// record most recently written. The metadata is stored in an extension
+// section of the database, so it is invisible to lookups and to other cdb
+// implementations, and can be read with Cdb.Annotation.
+func (w *Writer) Annotate(meta []byte) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if w.nrecords == 0 {
+		return errors.New("cdb: Annotate called before Write")
+	}
+	if w.wide {
+		return errors.New("cdb: annotations aren't supported in 64 bit databases")
+	}
+	if w.dups != nil && w.dups.skipped {
+		// The record wasn't written, as a duplicate.
+		return nil
+	}
+	w.annotations = append(w.annotations, annotation{uint32(w.lastPos), append([]byte(nil), meta...)})
+	if len(w.annotations) == 1 {
+		w.annotationsSize = 4
+	}
+	w.annotationsSize += annotationEntrySize + uint64(len(meta))
+	return nil
+}
+
+// encodeAnnotations returns the annotations section for the annotations,
+// which must be sorted by record position.
+func encodeAnnotations(annotations []annotation) []byte {
+	size := 4 + len(annotations)*annotationEntrySize
+	for _, a := range annotations {
+		size += len(a.meta)
+	}
+	b := make([]byte, 4+len(annotations)*annotationEntrySize, size)
+	putNum(b, uint32(len(annotations)))
+	metaPos := uint32(0)
+	for i, a := range annotations {
+		entry := b[4+i*annotationEntrySize:]
+		putNum(entry, a.pos)
+		putNum(entry[4:], metaPos)
+		putNum(entry[8:], uint32(len(a.meta)))
+		metaPos += uint32(len(a.meta))
+	}
+	for _, a := range annotations {
+		b = append(b, a.meta...)
+	}
+	return b
+}
+
+// Annotation returns the metadata attached with Writer.Annotate to the record
+// at file position pos. Returns ErrNotFound if the record has no metadata,
+// which is always the case past 4GB, since 64 bit databases can't have
+// annotations.
+//
+// The position of the record last returned by an iterator is given by
+// CdbIterator.RecordPos.
+//
+// Threadsafe.
+func (c *Cdb) Annotation(pos uint64) ([]byte, error) {
+	if pos > math.MaxUint32 {
+		return nil, ErrNotFound
+	}
+	section, err := c.extension(extAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	if section == nil {
+		return nil, ErrNotFound
+	}
+	buf := make([]byte, annotationEntrySize)
+	if _, err := section.ReadAt(buf[:4], 0); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	count := int(binary.LittleEndian.Uint32(buf))
+	// Binary search the entries for the record.
+	var searchErr error
+	i := sort.Search(count, func(i int) bool {
+		if _, err := section.ReadAt(buf, int64(4+i*annotationEntrySize)); err != nil {
+			searchErr = unexpectedEOF(err)
+			return true
+		}
+		return uint64(binary.LittleEndian.Uint32(buf)) >= pos
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	if i == count {
+		return nil, ErrNotFound
+	}
+	if _, err := section.ReadAt(buf, int64(4+i*annotationEntrySize)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if uint64(binary.LittleEndian.Uint32(buf)) != pos {
+		return nil, ErrNotFound
+	}
+	metaPos := 4 + int64(count)*annotationEntrySize + int64(binary.LittleEndian.Uint32(buf[4:]))
+	meta := make([]byte, binary.LittleEndian.Uint32(buf[8:]))
+	if _, err := section.ReadAt(meta, metaPos); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return meta, nil
+}
+
+// RecordPos returns the file position of the record whose value was last
+// returned by NextBytes or NextReader.
+//
+// Not threadsafe.
+func (iter *CdbIterator) RecordPos() uint64 {
+	return iter.recordPos()
+}
+
+func (iter *CdbIterator) recordPos() uint64 {
+	return iter.dpos - 8 - uint64(len(iter.key))
+}
+
+// unexpectedEOF converts EOF to ErrUnexpectedEOF, for reads that should have
+// been complete.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package cdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAnnotation(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp)
+	for _, rec := range records {
+		for _, val := range rec.values {
+			if err := w.Write([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+			// Leave "two" unannotated.
+			if rec.key == "two" {
+				continue
+			}
+			if err := w.Annotate([]byte(fmt.Sprintf("source=%s", val))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(b))
+
+	iter := db.Iterate([]byte("three"))
+	for _, val := range []string{"3", "33", "333"} {
+		if _, err := iter.NextBytes(); err != nil {
+			t.Fatal(err)
+		}
+		meta, err := db.Annotation(iter.RecordPos())
+		if err != nil {
+			t.Fatalf("Annotation error: %v", err)
+		}
+		if expected := "source=" + val; string(meta) != expected {
+			t.Errorf("expected %s, got: %s", expected, meta)
+		}
+	}
+	// Positions past 4GB don't wrap around to annotated records.
+	if _, err := db.Annotation(1<<32 + iter.RecordPos()); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound past 4GB, got: %v", err)
+	}
+	iter = db.Iterate([]byte("two"))
+	if _, err := iter.NextBytes(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Annotation(iter.RecordPos()); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for an unannotated record, got: %v", err)
+	}
+
+	// The extension section doesn't change the records.
+	var dump bytes.Buffer
+	if err := Dump(&dump, bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump.Bytes(), data) {
+		t.Errorf("Dump: expected %q, got: %q", data, dump.Bytes())
+	}
+
+	// Databases without extensions have no annotations.
+	if _, err := newDB(records).Annotation(uint64(headerSize)); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound without extensions, got: %v", err)
+	}
+}
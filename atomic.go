@@ -0,0 +1,67 @@
+package cdb
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CreateAtomic builds a database at path, replacing any file there without
+// readers ever seeing a partial database. It calls build with a Writer to a
+// temporary file in the same directory, then closes the Writer, syncs the
+// file and renames it over path. If build or any step fails the temporary
+// file is removed and path is left alone.
+//
+// A new file gets mode 0644, and a replaced one keeps its mode. The directory
+// is synced after the rename where the platform supports it, so the new file
+// survives a crash.
+func CreateAtomic(path string, build func(*Writer) error, opts ...WriterOption) (err error) {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	w := NewWriter(f, opts...)
+	if err = build(w); err != nil {
+		w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir syncs the directory, so a rename in it is durable. It is best
+// effort, since not every platform can sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,76 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "data.cdb")
+
+	put := func(w *Writer) error {
+		for _, rec := range records {
+			for _, val := range rec.values {
+				if err := w.Put([]byte(rec.key), []byte(val)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := CreateAtomic(name, put); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, db, records)
+	db.Close()
+
+	// A failed build leaves the old database and no temporary file.
+	failed := errors.New("failed")
+	err = CreateAtomic(name, func(w *Writer) error {
+		w.Put([]byte("one"), []byte("changed"))
+		return failed
+	})
+	if err != failed {
+		t.Errorf("expected the build error, got: %v", err)
+	}
+	db, err = Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, db, records)
+	db.Close()
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the database in the directory, got %d files", len(entries))
+	}
+}
+
+func TestPutRecords(t *testing.T) {
+	w := NewBufferWriter()
+	if err := w.PutRecords(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, New(bytes.NewReader(b)), records)
+
+	for _, in := range []string{"+3,1:one-1\n\n", "+3,5:one->1\n\n", "garbage"} {
+		if err := NewBufferWriter().PutRecords(bytes.NewReader([]byte(in))); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
@@ -30,6 +30,36 @@ func init() {
 	benchRecordsBytes = newDBBytes(benchRecords)
 }
 
+// TestLookupAllocs checks that hits on a memory-backed reader allocate nothing
+// but the returned value.
+func TestLookupAllocs(t *testing.T) {
+	db := New(bytes.NewReader(benchRecordsBytes))
+	key := benchRecordsKeys[0]
+	for _, tc := range []struct {
+		name     string
+		fn       func()
+		expected float64
+	}{
+		{"Exists", func() { db.Exists(key) }, 0},
+		{"Bytes", func() { db.Bytes(key) }, 1},
+		{"Reader", func() { db.Reader(key) }, 1},
+	} {
+		if allocs := testing.AllocsPerRun(100, tc.fn); allocs > tc.expected {
+			t.Errorf("%s: expected at most %v allocs per hit, got: %v", tc.name, tc.expected, allocs)
+		}
+	}
+}
+
+func BenchmarkMemExists(b *testing.B) {
+	db := New(bytes.NewReader(benchRecordsBytes))
+	rng := rand.New(rand.NewSource(0))
+	numKeys := len(benchRecordsKeys)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = db.Exists(benchRecordsKeys[rng.Intn(numKeys)])
+	}
+}
 func BenchmarkMemBytes(b *testing.B) {
 	benchBytes(b, New(bytes.NewReader(benchRecordsBytes)))
 }
@@ -78,6 +108,7 @@ func BenchmarkMmapReader(b *testing.B) {
 func benchBytes(b *testing.B, db *Cdb) {
 	rng := rand.New(rand.NewSource(0))
 	numKeys := len(benchRecordsKeys)
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, _ = db.Bytes(benchRecordsKeys[rng.Intn(numKeys)])
@@ -87,6 +118,7 @@ func benchReader(b *testing.B, db *Cdb) {
 	rng := rand.New(rand.NewSource(0))
 	numKeys := len(benchRecordsKeys)
 	var buf bytes.Buffer
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		r, _ := db.Reader(benchRecordsKeys[rng.Intn(numKeys)])
@@ -0,0 +1,104 @@
+package cdb
+
+import (
+	"errors"
+	"io"
+)
+
+// BufferWriter is a Writer that builds the database in memory, for tests,
+// embedding databases in other files, or uploading them straight to an
+// object store, without a temporary file.
+//
+// Not threadsafe.
+type BufferWriter struct {
+	*Writer
+	buf *memBuffer
+	// closeErr is the result of Close.
+	closeErr error
+}
+
+// NewBufferWriter returns a Writer that builds a database in memory. Get it
+// with Bytes.
+func NewBufferWriter(opts ...WriterOption) *BufferWriter {
+	buf := &memBuffer{}
+	return &BufferWriter{Writer: NewWriter(buf, opts...), buf: buf}
+}
+
+// Close finishes the database.
+func (b *BufferWriter) Close() error {
+	if b.closed {
+		return ErrWriterClosed
+	}
+	b.closeErr = b.Writer.Close()
+	return b.closeErr
+}
+
+// Bytes finishes the database, if Close hasn't been called, and returns it.
+func (b *BufferWriter) Bytes() ([]byte, error) {
+	if !b.closed {
+		b.Close()
+	}
+	if b.closeErr != nil {
+		return nil, b.closeErr
+	}
+	return b.buf.b, nil
+}
+
+// memBuffer is an in-memory file.
+type memBuffer struct {
+	b   []byte
+	pos int64
+}
+
+func (m *memBuffer) Write(p []byte) (int, error) {
+	if end := m.pos + int64(len(p)); end > int64(len(m.b)) {
+		old := int64(len(m.b))
+		if end > int64(cap(m.b)) {
+			grown := make([]byte, end, 2*end)
+			copy(grown, m.b)
+			m.b = grown
+		} else {
+			m.b = m.b[:end]
+		}
+		if m.pos > old {
+			// Writing past the end leaves a hole of zeros.
+			clear(m.b[old:m.pos])
+		}
+	}
+	n := copy(m.b[m.pos:], p)
+	m.pos += int64(n)
+	return n, nil
+}
+
+func (m *memBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += m.pos
+	case io.SeekEnd:
+		offset += int64(len(m.b))
+	}
+	if offset < 0 {
+		return 0, errors.New("cdb: seek to a negative position")
+	}
+	m.pos = offset
+	return offset, nil
+}
+
+func (m *memBuffer) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Truncate cuts the buffer to size, for the KeepLast duplicate policy.
+func (m *memBuffer) Truncate(size int64) error {
+	if size < int64(len(m.b)) {
+		m.b = m.b[:size]
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package cdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBufferWriter(t *testing.T) {
+	w := NewBufferWriter(Duplicates(KeepLast))
+	for _, rec := range records {
+		for _, val := range rec.values {
+			if err := w.Put([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := w.Bytes(); err != nil || !bytes.Equal(again, b) {
+		t.Errorf("expected Bytes to return the database again, got: %v", err)
+	}
+	if err := w.Close(); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed, got: %v", err)
+	}
+	db := New(bytes.NewReader(b))
+	checkRecords(t, db, []rec{{"one", []string{"1"}}, {"two", []string{"22"}}, {"three", []string{"333"}}})
+	if err := db.Verify(); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestBufferWriterMatchesFile(t *testing.T) {
+	w := NewBufferWriter()
+	for _, rec := range records {
+		for _, val := range rec.values {
+			if err := w.Put([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, newDBBytes(records)) {
+		t.Error("expected the same database as one built in a file")
+	}
+}
@@ -0,0 +1,39 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// BuildTime returns a WriterOption that records t in the database as the
+// time it was built. It is stored in an extension section, so other cdb
+// implementations ignore it, and is read back with Cdb.BuildTime.
+func BuildTime(t time.Time) WriterOption {
+	return func(w *Writer) {
+		w.buildTime = t
+	}
+}
+
+// BuildTime returns the time recorded with the BuildTime WriterOption, or the
+// zero time if there isn't one.
+//
+// Threadsafe.
+func (c *Cdb) BuildTime() (time.Time, error) {
+	section, err := c.extension(extBuildTime)
+	if err != nil || section == nil {
+		return time.Time{}, err
+	}
+	buf := make([]byte, 8)
+	if _, err := section.ReadAt(buf, 0); err != nil {
+		return time.Time{}, unexpectedEOF(err)
+	}
+	return time.Unix(0, int64(binary.LittleEndian.Uint64(buf))), nil
+}
+
+// encodeBuildTime encodes t as the 64 bit little endian number of
+// nanoseconds since the Unix epoch.
+func encodeBuildTime(t time.Time) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(t.UnixNano()))
+	return buf
+}
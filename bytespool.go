@@ -0,0 +1,78 @@
+package cdb
+
+import "math/bits"
+
+// Value buffers are pooled in power of two size classes from minBufSize to
+// maxBufSize. Bigger values aren't pooled.
+const (
+	minBufShift   = 6
+	maxBufShift   = 20
+	numBufClasses = maxBufShift - minBufShift + 1
+)
+
+func noRelease() {}
+
+// BytesPooled is like Bytes, but reads the value into a buffer from a pool
+// kept by the Cdb, to cut allocations in services doing many lookups. The
+// value is only valid until release is called, after which its buffer may be
+// reused by another lookup. Release must be called at most once, and is
+// never nil, even when an error is returned.
+//
+// Values bigger than 1MB aren't pooled, and release does nothing for them.
+// Values passed through a ValueTransform keep the raw value's buffer until
+// release is called too, since the transform may return part of it.
+//
+// Threadsafe.
+func (c *Cdb) BytesPooled(key []byte) (value []byte, release func(), err error) {
+	defer c.observeSince(c.now())
+	iter := c.getIter(key)
+	err = iter.nextValue()
+	dpos, dlen := iter.dpos, iter.dlen
+	putIter(iter)
+	if err != nil {
+		return nil, noRelease, err
+	}
+
+	class := bufClass(dlen)
+	if class < 0 {
+		value = make([]byte, dlen)
+	} else {
+		bp, _ := c.bufPools[class].Get().(*[]byte)
+		if bp == nil {
+			b := make([]byte, 1<<(class+minBufShift))
+			bp = &b
+		}
+		value = (*bp)[:dlen]
+		release = func() { c.bufPools[class].Put(bp) }
+	}
+	if err := readFull(c.r, value, int64(dpos)); err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, noRelease, err
+	}
+	if release == nil {
+		release = noRelease
+	}
+	if c.transform != nil {
+		// The transform may return a slice of the raw value, such as the
+		// value without an envelope, so the buffer is kept until release.
+		if value, err = c.transform(key, value); err != nil {
+			release()
+			return nil, noRelease, err
+		}
+	}
+	return value, release, nil
+}
+
+// bufClass returns the size class for a value of n bytes, or -1 if values that
+// big aren't pooled.
+func bufClass(n uint32) int {
+	if n > 1<<maxBufShift {
+		return -1
+	}
+	if n <= 1<<minBufShift {
+		return 0
+	}
+	return bits.Len32(n-1) - minBufShift
+}
@@ -0,0 +1,68 @@
+package cdb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBytesPooled(t *testing.T) {
+	big := strings.Repeat("b", 2<<20)
+	db := New(bytes.NewReader(newDBBytes(append([]rec{{"big", []string{big}}}, records...))))
+	for _, rec := range append([]rec{{"big", []string{big}}}, records...) {
+		b, release, err := db.BytesPooled([]byte(rec.key))
+		if err != nil {
+			t.Fatalf("%s: BytesPooled error: %v", rec.key, err)
+		}
+		if string(b) != rec.values[0] {
+			t.Errorf("%s: expected %.10q, got: %.10q", rec.key, rec.values[0], b)
+		}
+		release()
+	}
+	if _, release, err := db.BytesPooled([]byte("missing")); err != ErrNotFound || release == nil {
+		t.Errorf("expected ErrNotFound and a release func, got: %v", err)
+	}
+
+	key := []byte("three")
+	allocs := testing.AllocsPerRun(100, func() {
+		_, release, _ := db.BytesPooled(key)
+		release()
+	})
+	if allocs > 1 {
+		t.Errorf("expected at most 1 alloc per lookup, got: %v", allocs)
+	}
+}
+
+func TestBufClass(t *testing.T) {
+	for _, tc := range []struct {
+		n     uint32
+		class int
+	}{
+		{0, 0}, {64, 0}, {65, 1}, {128, 1}, {129, 2}, {1 << 20, numBufClasses - 1}, {1<<20 + 1, -1},
+	} {
+		if class := bufClass(tc.n); class != tc.class {
+			t.Errorf("bufClass(%d): expected %d, got: %d", tc.n, tc.class, class)
+		}
+	}
+}
+
+func TestBytesPooledSlicingTransform(t *testing.T) {
+	// Strip the first byte, returning a slice of the raw value.
+	strip := ValueTransform(func(key, val []byte) ([]byte, error) {
+		return val[1:], nil
+	})
+	db := New(bytes.NewReader(newDBBytes([]rec{{"a", []string{"xalpha"}}, {"b", []string{"xbravo"}}})), strip)
+	a, releaseA, err := db.BytesPooled([]byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, releaseB, err := db.BytesPooled([]byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != "alpha" || string(b) != "bravo" {
+		t.Errorf("expected alpha and bravo, got: %q and %q", a, b)
+	}
+	releaseA()
+	releaseB()
+}
@@ -0,0 +1,130 @@
+package cdb
+
+import (
+	"container/list"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// CachedReaderAt is an io.ReaderAt that keeps the most recently used blocks
+// of another in memory. For databases on slow media or read over the network,
+// it turns most of the small reads of each lookup into cache hits.
+//
+// Threadsafe.
+type CachedReaderAt struct {
+	r         io.ReaderAt
+	blockSize int64
+	maxBlocks int
+
+	mu sync.Mutex
+	// lru holds the cached blocks, most recently used first, and blocks
+	// finds them by index.
+	lru    *list.List
+	blocks map[int64]*list.Element
+
+	hits, misses int64
+}
+
+// cachedBlock is a block of the underlying reader. Data is shorter than the
+// block size for the last block.
+type cachedBlock struct {
+	index int64
+	data  []byte
+}
+
+// The defaults NewCachedReaderAt uses for sizes that aren't positive.
+const (
+	defaultCacheBlockSize = 4096
+	defaultCacheBlocks    = 1024
+)
+
+// NewCachedReaderAt returns a CachedReaderAt reading r in blocks of blockSize
+// bytes, and keeping up to maxBlocks of them. Reads spanning more blocks than
+// it keeps go straight to r. The data read through it must not change.
+//
+// A blockSize that isn't positive is taken as 4096 bytes, and a maxBlocks
+// that isn't positive as 1024 blocks.
+func NewCachedReaderAt(r io.ReaderAt, blockSize, maxBlocks int) *CachedReaderAt {
+	if blockSize <= 0 {
+		blockSize = defaultCacheBlockSize
+	}
+	if maxBlocks <= 0 {
+		maxBlocks = defaultCacheBlocks
+	}
+	return &CachedReaderAt{
+		r:         r,
+		blockSize: int64(blockSize),
+		maxBlocks: maxBlocks,
+		lru:       list.New(),
+		blocks:    make(map[int64]*list.Element, maxBlocks),
+	}
+}
+
+func (c *CachedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	first, last := off/c.blockSize, (off+int64(len(p))-1)/c.blockSize
+	if last-first >= int64(c.maxBlocks) {
+		return c.r.ReadAt(p, off)
+	}
+	n := 0
+	for i := first; i <= last; i++ {
+		data, err := c.block(i)
+		if err != nil {
+			return n, err
+		}
+		start := off + int64(n) - i*c.blockSize
+		if start >= int64(len(data)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], data[start:])
+		if len(data) < int(c.blockSize) && n < len(p) {
+			return n, io.EOF
+		}
+	}
+	return n, nil
+}
+
+// Stats returns the number of blocks found in the cache, and the number read
+// from the underlying reader.
+func (c *CachedReaderAt) Stats() (hits, misses int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
+
+// block returns the data of block i, reading it if it isn't cached.
+// Concurrent misses of the same block may each read it.
+func (c *CachedReaderAt) block(i int64) ([]byte, error) {
+	c.mu.Lock()
+	if e, ok := c.blocks[i]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		atomic.AddInt64(&c.hits, 1)
+		return e.Value.(*cachedBlock).data, nil
+	}
+	c.mu.Unlock()
+	atomic.AddInt64(&c.misses, 1)
+
+	data := make([]byte, c.blockSize)
+	n, err := c.r.ReadAt(data, i*c.blockSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	data = data[:n]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.blocks[i]; ok {
+		// Another read got there first.
+		c.lru.MoveToFront(e)
+		return e.Value.(*cachedBlock).data, nil
+	}
+	c.blocks[i] = c.lru.PushFront(&cachedBlock{i, data})
+	if c.lru.Len() > c.maxBlocks {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.blocks, e.Value.(*cachedBlock).index)
+	}
+	return data, nil
+}
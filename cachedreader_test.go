@@ -0,0 +1,89 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCachedReaderAt(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789abcdef", 10))
+	src := &countingReaderAt{r: bytes.NewReader(data)}
+	c := NewCachedReaderAt(src, 16, 4)
+
+	for _, tc := range []struct {
+		off, n int
+		reads  int
+	}{
+		{0, 10, 1},   // Block 0.
+		{4, 20, 2},   // Blocks 0 and 1.
+		{30, 40, 5},  // Blocks 1 to 4, evicting 0.
+		{0, 1, 6},    // Block 0 again.
+		{0, 100, 7},  // More blocks than are kept, read straight through.
+		{64, 16, 7},  // Block 4 is still cached.
+		{150, 10, 8}, // The last, short, block.
+		{155, 5, 8},
+	} {
+		buf := make([]byte, tc.n)
+		if n, err := c.ReadAt(buf, int64(tc.off)); n != tc.n || err != nil {
+			t.Fatalf("%d+%d: expected %d bytes, got: %d, %v", tc.off, tc.n, tc.n, n, err)
+		}
+		if !bytes.Equal(buf, data[tc.off:tc.off+tc.n]) {
+			t.Errorf("%d+%d: expected %q, got: %q", tc.off, tc.n, data[tc.off:tc.off+tc.n], buf)
+		}
+		if src.reads != tc.reads {
+			t.Errorf("%d+%d: expected %d reads, got %d", tc.off, tc.n, tc.reads, src.reads)
+		}
+	}
+
+	buf := make([]byte, 10)
+	if n, err := c.ReadAt(buf, 155); n != 5 || err != io.EOF || string(buf[:n]) != "bcdef" {
+		t.Errorf("expected 5 bytes and io.EOF, got: %d, %v", n, err)
+	}
+	if n, err := c.ReadAt(buf, 160); n != 0 || err != io.EOF {
+		t.Errorf("expected io.EOF, got: %d, %v", n, err)
+	}
+	if hits, misses := c.Stats(); hits == 0 || misses != 8 {
+		t.Errorf("unexpected stats: %d hits, %d misses", hits, misses)
+	}
+
+	// Errors aren't cached.
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(f.Name())
+	f.Close()
+	failing := NewCachedReaderAt(f, 16, 4)
+	for i := 0; i < 2; i++ {
+		if _, err := failing.ReadAt(buf, 0); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("expected the reader's error, got: %v", err)
+		}
+	}
+}
+
+func TestCachedReaderAtLookups(t *testing.T) {
+	src := &countingReaderAt{r: bytes.NewReader(newDBBytes(records))}
+	db := New(NewCachedReaderAt(src, 4096, 16))
+	checkRecords(t, db, records)
+	before := src.reads
+	checkRecords(t, db, records)
+	if src.reads != before {
+		t.Errorf("expected repeated lookups to be cached, got %d more reads", src.reads-before)
+	}
+}
+
+func TestCachedReaderAtDefaults(t *testing.T) {
+	src := &countingReaderAt{r: bytes.NewReader(newDBBytes(records))}
+	db := New(NewCachedReaderAt(src, 0, -1))
+	checkRecords(t, db, records)
+	before := src.reads
+	checkRecords(t, db, records)
+	if src.reads != before {
+		t.Errorf("expected the default sizes to cache lookups, got %d more reads", src.reads-before)
+	}
+}
@@ -0,0 +1,197 @@
+package cdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Role is the part a member database plays in a catalog.
+type Role string
+
+const (
+	RoleBase  Role = "base"  // The bottom layer of a stack.
+	RoleDelta Role = "delta" // A layer overriding the layers before it.
+	RoleShard Role = "shard" // One of a set of shards.
+)
+
+// CatalogMember is a database listed in a catalog.
+type CatalogMember struct {
+	// Name is the member's file name, relative to the catalog's directory.
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// Fingerprint is the member's Fingerprint when the catalog was written.
+	Fingerprint string `json:"fingerprint"`
+	// Shard is the member's shard number, for RoleShard.
+	Shard int `json:"shard,omitempty"`
+}
+
+// Catalog lists the databases of a multi-file deployment. It is stored as a
+// cdb with a "version" record holding the catalog version as a decimal
+// number, and a "member" record holding each member as JSON, in order.
+//
+// A catalog either stacks a base and its deltas, in the order listed, or
+// shards keys over members 0 to n-1.
+type Catalog struct {
+	// Version increases every time the deployment changes. Readers reloading
+	// a catalog should ignore one with a lower version than the one they have,
+	// so they never go back to an older set of files.
+	Version uint64
+	Members []CatalogMember
+}
+
+// ErrBadCatalog is returned for catalogs that can't be read, or whose members
+// don't make a valid Stack or Sharded.
+var ErrBadCatalog = errors.New("bad catalog")
+
+// WriteCatalog writes cat to w. It doesn't close w.
+func WriteCatalog(w *Writer, cat *Catalog) error {
+	if err := w.Write([]byte("version"), []byte(strconv.FormatUint(cat.Version, 10))); err != nil {
+		return err
+	}
+	for _, m := range cat.Members {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if err := w.Write([]byte("member"), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadCatalog reads the catalog stored in c.
+func ReadCatalog(c *Cdb) (*Catalog, error) {
+	cat := new(Catalog)
+	v, err := c.Bytes([]byte("version"))
+	if err == ErrNotFound {
+		return nil, fmt.Errorf("%w: no version", ErrBadCatalog)
+	} else if err != nil {
+		return nil, err
+	}
+	if cat.Version, err = strconv.ParseUint(string(v), 10, 64); err != nil {
+		return nil, fmt.Errorf("%w: version %q", ErrBadCatalog, v)
+	}
+	iter := c.Iterate([]byte("member"))
+	for {
+		b, err := iter.NextBytes()
+		if err == ErrNotFound {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		var m CatalogMember
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadCatalog, err)
+		}
+		cat.Members = append(cat.Members, m)
+	}
+	return cat, nil
+}
+
+// Fingerprint identifies the contents of a database by the SHA-256 of its
+// header and hash tables. That covers every key's hash and every record's
+// position and lengths, so it catches a missing, stale or swapped file
+// without reading the data, but not a value rewritten in place with the same
+// length.
+//
+// Threadsafe.
+func Fingerprint(c *Cdb) (string, error) {
+	lay, err := c.layout()
+	if err != nil {
+		return "", err
+	}
+	eod, _, err := lay.readEntry(c.r, make([]byte, 16), 0)
+	if err != nil {
+		return "", err
+	}
+	end, err := tablesEnd(c.r)
+	if err != nil {
+		return "", err
+	}
+	if end < eod {
+		end = eod
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(c.r, 0, int64(lay.headerSize()))); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, io.NewSectionReader(c.r, int64(eod), int64(end-eod))); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// OpenCatalog opens the catalog in the named file, opens its members with
+// opts, checks their fingerprints, and returns a reader combining them: a
+// Stack of the base and deltas, or a Sharded of the shards. A catalog with a
+// single base member returns that member's Cdb.
+func OpenCatalog(name string, opts ...Option) (Getter, *Catalog, error) {
+	c, err := Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	cat, err := ReadCatalog(c)
+	c.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var layers, shards []Getter
+	var opened []Getter
+	fail := func(err error) (Getter, *Catalog, error) {
+		closeAll(opened)
+		return nil, nil, err
+	}
+	members := append([]CatalogMember(nil), cat.Members...)
+	sort.SliceStable(members, func(i, j int) bool { return members[i].Shard < members[j].Shard })
+	for i, m := range members {
+		db, err := Open(filepath.Join(filepath.Dir(name), m.Name), opts...)
+		if err != nil {
+			return fail(err)
+		}
+		opened = append(opened, db)
+		fp, err := Fingerprint(db)
+		if err != nil {
+			return fail(err)
+		}
+		if fp != m.Fingerprint {
+			return fail(fmt.Errorf("%w: %s has fingerprint %s, expected %s", ErrBadCatalog, m.Name, fp, m.Fingerprint))
+		}
+		switch m.Role {
+		case RoleBase:
+			if len(layers) > 0 {
+				return fail(fmt.Errorf("%w: base %s isn't the first layer", ErrBadCatalog, m.Name))
+			}
+			layers = append(layers, db)
+		case RoleDelta:
+			layers = append(layers, db)
+		case RoleShard:
+			if m.Shard != i {
+				return fail(fmt.Errorf("%w: shard %d is missing", ErrBadCatalog, i))
+			}
+			shards = append(shards, db)
+		default:
+			return fail(fmt.Errorf("%w: %s has unknown role %q", ErrBadCatalog, m.Name, m.Role))
+		}
+	}
+
+	switch {
+	case len(layers) > 0 && len(shards) > 0:
+		return fail(fmt.Errorf("%w: mixes layers and shards", ErrBadCatalog))
+	case len(shards) > 0:
+		return NewSharded(shards...), cat, nil
+	case len(layers) == 1:
+		return layers[0], cat, nil
+	case len(layers) > 1:
+		return NewStack(layers...), cat, nil
+	}
+	return fail(fmt.Errorf("%w: no members", ErrBadCatalog))
+}
@@ -0,0 +1,122 @@
+package cdb
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeDB writes recs to the named file and returns its fingerprint.
+func writeDB(t *testing.T, name string, recs []rec) string {
+	if err := ioutil.WriteFile(name, newDBBytes(recs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	fp, err := Fingerprint(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+func writeCatalog(t *testing.T, name string, cat *Catalog) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := NewWriter(f)
+	if err := WriteCatalog(w, cat); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenCatalogStack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cat := &Catalog{Version: 7, Members: []CatalogMember{
+		{Name: "base.cdb", Role: RoleBase, Fingerprint: writeDB(t, filepath.Join(dir, "base.cdb"), records)},
+		{Name: "delta.cdb", Role: RoleDelta, Fingerprint: writeDB(t, filepath.Join(dir, "delta.cdb"), []rec{
+			{"two", []string{"two!"}},
+			{"four", []string{"4"}},
+		})},
+	}}
+	name := filepath.Join(dir, "catalog.cdb")
+	writeCatalog(t, name, cat)
+
+	g, got, err := OpenCatalog(name)
+	if err != nil {
+		t.Fatalf("OpenCatalog error: %v", err)
+	}
+	defer g.Close()
+	if !reflect.DeepEqual(got, cat) {
+		t.Errorf("expected catalog %+v, got: %+v", cat, got)
+	}
+	if _, ok := g.(*Stack); !ok {
+		t.Fatalf("expected a Stack, got: %T", g)
+	}
+	for key, expected := range map[string]string{"one": "1", "two": "two!", "four": "4"} {
+		if b, err := g.Bytes([]byte(key)); err != nil || string(b) != expected {
+			t.Errorf("%s: expected %s, got: %q, %v", key, expected, b, err)
+		}
+	}
+	if _, err := g.Bytes([]byte("five")); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for a missing key, got: %v", err)
+	}
+
+	// A member that changed after the catalog was written is refused.
+	writeDB(t, filepath.Join(dir, "delta.cdb"), records)
+	if _, _, err := OpenCatalog(name); !errors.Is(err, ErrBadCatalog) {
+		t.Errorf("expected ErrBadCatalog for a stale member, got: %v", err)
+	}
+}
+
+func TestOpenCatalogSharded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shards := make([][]rec, 3)
+	for _, r := range records {
+		i := ShardFor([]byte(r.key), len(shards))
+		shards[i] = append(shards[i], r)
+	}
+	cat := &Catalog{Version: 1}
+	for i, recs := range shards {
+		name := "shard" + string(rune('0'+i)) + ".cdb"
+		fp := writeDB(t, filepath.Join(dir, name), recs)
+		cat.Members = append(cat.Members, CatalogMember{Name: name, Role: RoleShard, Fingerprint: fp, Shard: i})
+	}
+	name := filepath.Join(dir, "catalog.cdb")
+	writeCatalog(t, name, cat)
+
+	g, _, err := OpenCatalog(name)
+	if err != nil {
+		t.Fatalf("OpenCatalog error: %v", err)
+	}
+	defer g.Close()
+	if _, ok := g.(*Sharded); !ok {
+		t.Fatalf("expected a Sharded, got: %T", g)
+	}
+	for _, r := range records {
+		if b, err := g.Bytes([]byte(r.key)); err != nil || string(b) != r.values[0] {
+			t.Errorf("%s: expected %s, got: %q, %v", r.key, r.values[0], b, err)
+		}
+	}
+}
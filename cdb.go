@@ -6,10 +6,16 @@ package cdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -19,10 +25,58 @@ const (
 type Cdb struct {
 	r      io.ReaderAt
 	closer io.Closer
+	// transform is applied to values before they are returned. May be nil.
+	transform func(key, val []byte) ([]byte, error)
+	// readerTransform is the streaming form of transform, if it was set with
+	// ValueReaderTransform. May be nil.
+	readerTransform func(key []byte, val *io.SectionReader) (*io.SectionReader, error)
+	// ext holds the extension sections, which are read on first use.
+	ext extensions
+	// negCache holds hashes of keys with no values. May be nil.
+	negCache *negCache
+	// latency records lookup latency. May be nil.
+	latency *latencyHistogram
+	// wrappers are applied to the reader, in order, by setReader.
+	wrappers []func(io.ReaderAt) io.ReaderAt
+	// access is the access pattern hint used when opening the file.
+	access AccessPattern
+	// probes tracks how many slots lookups in each hash table read.
+	probes [256]tableProbes
+	// bufPools hold value buffers for BytesPooled, by size class.
+	bufPools [numBufClasses]sync.Pool
+	// lay is the layout of the database, classic or 64 bit.
+	lay layoutState
+	// closed is set to 1 by Close.
+	closed uint32
+	// hash hashes keys for lookups. May be nil, for the cdb hash.
+	hash func(key []byte) uint32
+	// maxValueSize is the largest value lookups return, or 0 for no limit.
+	maxValueSize int64
+	// mmap is true if Open should map the file into memory, and hugePages
+	// and lockTables are the WithHugePages and WithLockedTables options for
+	// the mapping.
+	mmap       bool
+	hugePages  bool
+	lockTables bool
+	// counts holds the number of records and keys, once counted.
+	counts countState
+	// tombstones is true if empty values are tombstones.
+	tombstones bool
+	// detectChanges is true if reads check the database for changes at most
+	// every changeInterval, with a changeGuard.
+	detectChanges  bool
+	changeInterval time.Duration
+	// eager holds the work the Eager options moved into Open.
+	eager []func(*Cdb) error
+	// lazy is the file of a Cdb made by LazyOpen, opened on first use. May
+	// be nil.
+	lazy *lazyFile
 }
 
 type CdbIterator struct {
 	db *Cdb
+	// r is the reader of db, bound to the iterator's context if it has one.
+	r io.ReaderAt
 	// initErr is non-nil if an error happened when the iterator was created.
 	initErr error
 	// If it is modified the iterator will stop working properly.
@@ -31,37 +85,107 @@ type CdbIterator struct {
 	loop uint32
 	// khash is the hash of the key.
 	khash uint32
+	// lay is the layout of the database.
+	lay layout
 	// kpos is the next file position in the hash to check for the key.
-	kpos uint32
+	kpos uint64
 	// hpos is the file position of the hash table that this key is in.
-	hpos uint32
+	hpos uint64
 	// hslots is the number of slots in the hash table.
 	hslots uint32
+	// hend is the file position of the end of the hash table.
+	hend uint64
 	// dpos is the file position of the data. Only valid if the last call to next
 	// returned nil.
-	dpos uint32
+	dpos uint64
 	// dlen is the length of the data. Only valid if the last call to next
 	// returned nil.
 	dlen uint32
+	// sawHash is true if a slot with the key's hash has been seen.
+	sawHash bool
+	// slots holds slots read ahead of kpos, from the si'th to the sn'th.
+	slots  [maxProbeSpan * 16]byte
+	si, sn uint32
+	// probes is the number of slots read by the current call to next.
+	probes uint32
+	// ctx is the iterator's context, and probeTimeout the limit on each call
+	// to next, if it isn't 0.
+	ctx          context.Context
+	probeTimeout time.Duration
 	// buf is used as scratch space for io.
 	buf [64]byte
 }
 
 // Open opens the named file read-only and returns a new Cdb object.  The file
-// should exist and be a cdb-format database file.
-func Open(name string) (*Cdb, error) {
-	f, err := os.Open(name)
+// should exist and be a cdb-format database file. Nothing is read from it
+// until it is used: its layout, header and extensions are read on first use,
+// and it is only verified or warmed when asked, so opening takes microseconds
+// whatever the size of the file.
+//
+// The Eager options move that work into Open, for programs that would rather
+// pay for it, or fail, at startup than on their first lookups. They apply to
+// Open, OpenMmap and NewFromFd, and to LazyOpen when it opens the file. Other
+// constructors ignore them.
+func Open(name string, opts ...Option) (*Cdb, error) {
+	return newCdb(opts).open(name)
+}
+
+// open opens the named file as the database of c.
+func (c *Cdb) open(name string) (*Cdb, error) {
+	f, err := openFile(name, c.access)
 	if err != nil {
 		return nil, err
 	}
-	c := New(f)
-	c.closer = f
+	return c.fromFile(f)
+}
+
+// fromFile makes f the database of c, mapping it into memory if WithMmap was
+// given, and does the work of the Eager options. It closes f if that fails.
+func (c *Cdb) fromFile(f *os.File) (*Cdb, error) {
+	file, err := c.mapped(f)
+	if err != nil {
+		return nil, err
+	}
+	c.setReader(file)
+	c.closer = file
+	if err := c.runEager(); err != nil {
+		file.Close()
+		return nil, err
+	}
 	runtime.SetFinalizer(c, (*Cdb).Close)
 	return c, nil
 }
 
-// Close closes the cdb for any further reads.
+// statFile is a File that can be stat'ed, like *os.File and *mmapFile.
+type statFile interface {
+	File
+	Stat() (os.FileInfo, error)
+}
+
+// mapped returns f mapped into memory if WithMmap was given and the platform
+// supports it, and f itself otherwise. It closes f if mapping fails.
+func (c *Cdb) mapped(f *os.File) (statFile, error) {
+	if !c.mmap {
+		return f, nil
+	}
+	m, err := mapFile(f)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return f, nil
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := c.adviseMapping(m); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close closes the cdb for any further reads, which return ErrClosed.
 func (c *Cdb) Close() (err error) {
+	atomic.StoreUint32(&c.closed, 1)
 	if c.closer != nil {
 		err = c.closer.Close()
 		c.closer = nil
@@ -71,16 +195,59 @@ func (c *Cdb) Close() (err error) {
 }
 
 // New creates a new Cdb from the given ReaderAt, which should be a cdb format database.
-func New(r io.ReaderAt) *Cdb {
-	return &Cdb{r: r}
+func New(r io.ReaderAt, opts ...Option) *Cdb {
+	c := newCdb(opts)
+	c.setReader(r)
+	return c
+}
+
+// newCdb returns a Cdb configured with opts, without a reader.
+func newCdb(opts []Option) *Cdb {
+	c := new(Cdb)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// setReader sets the reader of the Cdb, wrapped by the wrappers added by its
+// options. The changeGuard of DetectChanges goes first, so it sees r itself.
+func (c *Cdb) setReader(r io.ReaderAt) {
+	if c.detectChanges {
+		r = &changeGuard{r: r, interval: c.changeInterval}
+	}
+	for _, wrap := range c.wrappers {
+		r = wrap(r)
+	}
+	c.r = r
+}
+
+// iterPool holds iterators for the single value lookups, which don't hand the
+// iterator to the caller.
+var iterPool = sync.Pool{New: func() interface{} { return new(CdbIterator) }}
+
+// getIter returns a pooled iterator for key. It must be returned with putIter.
+func (c *Cdb) getIter(key []byte) *CdbIterator {
+	iter := iterPool.Get().(*CdbIterator)
+	iter.reset(c, key)
+	return iter
+}
+
+func putIter(iter *CdbIterator) {
+	// Don't keep the db or key alive.
+	iter.db, iter.r, iter.key = nil, nil, nil
+	iterPool.Put(iter)
 }
 
 // Exists returns true if there are any values for this key.
 //
 // Threadsafe.
 func (c *Cdb) Exists(key []byte) (bool, error) {
-	err := c.Iterate(key).next()
-	if err == io.EOF {
+	defer c.observeSince(c.now())
+	iter := c.getIter(key)
+	err := iter.nextLive()
+	putIter(iter)
+	if err == ErrNotFound {
 		return false, nil
 	}
 	if err != nil {
@@ -89,20 +256,49 @@ func (c *Cdb) Exists(key []byte) (bool, error) {
 	return true, nil
 }
 
-// Bytes returns the first value for this key as a []byte. Returns EOF when
-// there is no value.
+// Bytes returns the first value for this key as a []byte. Returns ErrNotFound
+// when there is no value.
 //
 // Threadsafe.
 func (c *Cdb) Bytes(key []byte) ([]byte, error) {
-	return c.Iterate(key).NextBytes()
+	defer c.observeSince(c.now())
+	iter := c.getIter(key)
+	b, err := iter.NextBytes()
+	putIter(iter)
+	return b, err
+}
+
+// AllValues returns every value for this key, in the order they were written.
+// Returns no values and a nil error when there are none.
+//
+// Threadsafe.
+func (c *Cdb) AllValues(key []byte) ([][]byte, error) {
+	defer c.observeSince(c.now())
+	iter := c.getIter(key)
+	defer putIter(iter)
+	var vals [][]byte
+	for {
+		val, err := iter.NextBytes()
+		if err == ErrNotFound {
+			return vals, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
 }
 
 // Reader returns the first value for this key as an io.SectionReader. Returns
-// EOF when there is no value.
+// ErrNotFound when there is no value.
 //
 // Threadsafe.
 func (c *Cdb) Reader(key []byte) (*io.SectionReader, error) {
-	return c.Iterate(key).NextReader()
+	defer c.observeSince(c.now())
+	iter := c.getIter(key)
+	r, err := iter.NextReader()
+	putIter(iter)
+	return r, err
 }
 
 // Iterate returns an iterator that can be used to access all of the values for
@@ -114,107 +310,216 @@ func (c *Cdb) Reader(key []byte) (*io.SectionReader, error) {
 // Threadsafe.
 func (c *Cdb) Iterate(key []byte) *CdbIterator {
 	iter := new(CdbIterator)
-	iter.db = c
-	iter.key = key
-	// Calculate the hash of the key.
-	iter.khash = checksum(key)
+	iter.reset(c, key)
+	return iter
+}
+
+// reset prepares the iterator to look up key in c.
+func (iter *CdbIterator) reset(c *Cdb, key []byte) {
+	iter.resetContext(context.Background(), c, key)
+}
+
+// resetContext prepares the iterator to look up key in c, with reads that
+// fail once ctx is done.
+func (iter *CdbIterator) resetContext(ctx context.Context, c *Cdb, key []byte) {
+	iter.resetHashed(ctx, c, key, c.checksum(key))
+}
+
+// resetHashed is resetContext for a key whose hash in c is h.
+func (iter *CdbIterator) resetHashed(ctx context.Context, c *Cdb, key []byte, h uint32) {
+	*iter = CdbIterator{db: c, r: contextReader(ctx, c.r), key: key, ctx: ctx, khash: h}
+	if iter.lay, iter.initErr = c.layoutAt(iter.r); iter.initErr != nil {
+		return
+	}
+	if c.negCache != nil && c.negCache.contains(iter.khash) {
+		iter.initErr = ErrNotFound
+		return
+	}
 	// Read in the position and size of the hash table for this key.
-	iter.hpos, iter.hslots, iter.initErr = readNums(iter.db.r, iter.buf[:], iter.khash%256*8)
-	if iter.initErr != nil {
-		return iter
+	hpos, hslots, err := iter.lay.readEntry(iter.r, iter.buf[:], uint64(iter.khash%256)*iter.lay.entrySize())
+	if err != nil {
+		iter.initErr = err
+		return
 	}
+	iter.setTable(hpos, hslots)
+}
+
+// setTable sets the position and number of slots of the key's hash table.
+func (iter *CdbIterator) setTable(hpos, hslots uint64) {
+	iter.hpos, iter.hslots = hpos, uint32(hslots)
 	// If the hash table has no slots, there are no values.
 	if iter.hslots == 0 {
-		iter.initErr = io.EOF
-		return iter
+		iter.initErr = iter.miss()
+		return
 	}
+	size := iter.lay.entrySize()
+	iter.hend = iter.hpos + hslots*size
 	// Calculate first possible file position of key.
 	hashslot := iter.khash / 256 % iter.hslots
-	iter.kpos = iter.hpos + hashslot*8
-	return iter
+	iter.kpos = iter.hpos + uint64(hashslot)*size
 }
 
-// NextBytes returns the next value for this iterator as a []byte. Returns EOF
-// when there are no values left.
+// NextBytes returns the next value for this iterator as a []byte. Returns
+// ErrNotFound when there are no values left.
 //
 // Not threadsafe.
 func (iter *CdbIterator) NextBytes() ([]byte, error) {
-	if err := iter.next(); err != nil {
+	if err := iter.nextValue(); err != nil {
 		return nil, err
 	}
 	data := make([]byte, iter.dlen)
-	if _, err := iter.db.r.ReadAt(data, int64(iter.dpos)); err != nil {
-		if err == io.EOF {
-			err = io.ErrUnexpectedEOF
-		}
+	if err := readFull(iter.r, data, int64(iter.dpos)); err != nil {
 		return nil, err
 	}
+	if iter.db.transform != nil {
+		return iter.db.transform(iter.key, data)
+	}
 	return data, nil
 }
 
 // NextReader returns the next value for this iterator as an io.SectionReader.
-// Returns EOF when there are no values left.
+// Returns ErrNotFound when there are no values left.
 //
 // Not threadsafe.
 func (iter *CdbIterator) NextReader() (*io.SectionReader, error) {
-	if err := iter.next(); err != nil {
+	if err := iter.nextValue(); err != nil {
 		return nil, err
 	}
-	return io.NewSectionReader(iter.db.r, int64(iter.dpos), int64(iter.dlen)), nil
+	valReader := io.NewSectionReader(iter.r, int64(iter.dpos), int64(iter.dlen))
+	if iter.db.transform != nil {
+		return iter.db.transformReader(iter.key, valReader)
+	}
+	return valReader, nil
+}
+
+// nextValue is like next, but fails with ErrValueTooLarge if the value found
+// is bigger than the limit set by WithMaxValueSize.
+func (iter *CdbIterator) nextValue() error {
+	if err := iter.nextLive(); err != nil {
+		return err
+	}
+	return iter.db.checkValueSize(iter.dlen)
+}
+
+// nextLive is like next, but skips empty values in databases opened with
+// TombstoneEmptyValues, since they are tombstones.
+func (iter *CdbIterator) nextLive() error {
+	for {
+		if err := iter.next(); err != nil {
+			return err
+		}
+		if iter.dlen > 0 || !iter.db.tombstones {
+			return nil
+		}
+	}
 }
 
 // next iterates through the hash table until it finds the next match. If no
-// matches are found, returns EOF.
+// matches are found, returns ErrNotFound.
 //
 // When a match is found dpos and dlen can be used to retreive the data.
 func (iter *CdbIterator) next() error {
 	if iter.initErr != nil {
 		return iter.initErr
 	}
-	var err error
-	var khash, recPos uint32
+	if iter.probeTimeout > 0 {
+		return iter.nextWithin(iter.probeTimeout)
+	}
+	return iter.probe()
+}
+
+// probe is next, without the probe timeout.
+func (iter *CdbIterator) probe() error {
+	r, buf := iter.r, iter.buf[:]
+	klen := uint32(len(iter.key))
+	iter.probes = 0
 	// Iterate through all of the hash slots until we find our key.
-	for {
-		// If we have seen every hash slot, we are done.
-		if iter.loop >= iter.hslots {
-			return io.EOF
-		}
-		khash, recPos, err = readNums(iter.db.r, iter.buf[:], iter.kpos)
-		if err != nil {
-			return err
+	for ; iter.loop < iter.hslots; iter.loop++ {
+		if iter.si == iter.sn {
+			if err := iter.readSlots(); err != nil {
+				return err
+			}
 		}
+		h, recPos := iter.lay.entry(iter.slots[uint64(iter.si)*iter.lay.entrySize():])
+		khash := uint32(h)
+		iter.si++
+		iter.probes++
 		if recPos == 0 {
-			return io.EOF
+			return iter.miss()
 		}
-		// Move the iterator to the next position.
-		iter.loop++
-		iter.kpos += 8
-		// If the kpos goes past the end of the hash table, wrap around to the start.
-		if iter.kpos == iter.hpos+(iter.hslots*8) {
+		// Move the iterator to the next position, wrapping around to the start
+		// at the end of the hash table.
+		iter.kpos += iter.lay.entrySize()
+		if iter.kpos == iter.hend {
 			iter.kpos = iter.hpos
 		}
 		// If the key hash doesn't match, this hash slot isn't for our key. Keep iterating.
 		if khash != iter.khash {
 			continue
 		}
-		keyLen, dataLen, err := readNums(iter.db.r, iter.buf[:], recPos)
-		if err != nil {
+		iter.sawHash = true
+		// Read the record header, and the key too if it fits in buf, in one go.
+		n := 8 + klen
+		if n > uint32(len(buf)) {
+			n = 8
+		}
+		if err := readFull(r, buf[:n], int64(recPos)); err != nil {
 			return err
 		}
+		keyLen := binary.LittleEndian.Uint32(buf)
+		dataLen := binary.LittleEndian.Uint32(buf[4:])
 		// Check that the keys actually match in case of a hash collision.
-		if keyLen != uint32(len(iter.key)) {
+		if keyLen != klen {
 			continue
 		}
-		if isMatch, err := match(iter.db.r, iter.buf[:], iter.key, recPos+8); err != nil {
+		if n > 8 {
+			if !bytes.Equal(buf[8:n], iter.key) {
+				continue
+			}
+		} else if isMatch, err := match(r, buf, iter.key, int64(recPos)+8); err != nil {
 			return err
-		} else if isMatch == false {
+		} else if !isMatch {
 			continue
 		}
-		iter.dpos = recPos + 8 + keyLen
+		iter.loop++
+		iter.dpos = recPos + 8 + uint64(keyLen)
 		iter.dlen = dataLen
+		iter.db.probes[iter.khash%256].observe(iter.probes)
 		return nil
 	}
-	panic("unreached")
+	// We have seen every hash slot.
+	return iter.miss()
+}
+
+// readSlots reads the next slots from kpos into iter.slots, as many as the
+// key's table usually needs, without going past the end of the table or
+// rereading slots already checked.
+func (iter *CdbIterator) readSlots() error {
+	size := iter.lay.entrySize()
+	n := iter.db.probes[iter.khash%256].span()
+	if left := uint32((iter.hend - iter.kpos) / size); n > left {
+		n = left
+	}
+	if left := iter.hslots - iter.loop; n > left {
+		n = left
+	}
+	if err := readFull(iter.r, iter.slots[:uint64(n)*size], int64(iter.kpos)); err != nil {
+		return err
+	}
+	iter.si, iter.sn = 0, n
+	return nil
+}
+
+// miss returns ErrNotFound, after adding the key's hash to the negative cache if no
+// slot had the hash.
+func (iter *CdbIterator) miss() error {
+	if iter.probes > 0 {
+		iter.db.probes[iter.khash%256].observe(iter.probes)
+	}
+	if !iter.sawHash && iter.db.negCache != nil {
+		iter.db.negCache.add(iter.khash)
+	}
+	return ErrNotFound
 }
 
 // ForEachReader calls onRecordFn for every key-val pair in the database.
@@ -224,28 +529,57 @@ func (iter *CdbIterator) next() error {
 //
 // Threadsafe.
 func (c *Cdb) ForEachReader(onRecordFn func(keyReader, valReader *io.SectionReader) error) error {
-	buf := make([]byte, 8)
-	// The start is the first record after the header.
-	pos := headerSize
+	return c.forEachRecord(c.r, c.transformed(onRecordFn))
+}
+
+// forEachRecord calls onRecordFn with the raw key and value of every record in
+// the data section, read from r.
+func (c *Cdb) forEachRecord(r io.ReaderAt, onRecordFn func(keyReader, valReader *io.SectionReader) error) error {
+	return c.scanRecordsAt(r, 0, func(pos uint64, klen, dlen uint32) error {
+		// Create readers that point directly to sections of the underlying reader.
+		if err := c.checkValueSize(dlen); err != nil {
+			return err
+		}
+		keyReader := io.NewSectionReader(r, int64(pos+8), int64(klen))
+		dataReader := io.NewSectionReader(r, int64(pos+8+uint64(klen)), int64(dlen))
+		// Send them to the callback.
+		return onRecordFn(keyReader, dataReader)
+	})
+}
+
+// scanRecords calls onRecordFn with the position and key and data lengths of
+// every record in the data section, starting with the record at start, or
+// with the first record if start is 0.
+func (c *Cdb) scanRecords(start uint64, onRecordFn func(pos uint64, klen, dlen uint32) error) error {
+	return c.scanRecordsAt(c.r, start, onRecordFn)
+}
+
+// scanRecordsAt is scanRecords, reading from r.
+func (c *Cdb) scanRecordsAt(r io.ReaderAt, start uint64, onRecordFn func(pos uint64, klen, dlen uint32) error) error {
+	lay, err := c.layoutAt(r)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 16)
+	pos := start
+	if pos == 0 {
+		pos = lay.headerSize()
+	}
 	// The end is the start of the first hash table.
-	end, _, err := readNums(c.r, buf, 0)
+	end, _, err := lay.readEntry(r, buf, 0)
 	if err != nil {
 		return err
 	}
 	for pos < end {
-		klen, dlen, err := readNums(c.r, buf, pos)
+		klen, dlen, err := readNums(r, buf, int64(pos))
 		if err != nil {
 			return err
 		}
-		// Create readers that point directly to sections of the underlying reader.
-		keyReader := io.NewSectionReader(c.r, int64(pos+8), int64(klen))
-		dataReader := io.NewSectionReader(c.r, int64(pos+8+klen), int64(dlen))
-		// Send them to the callback.
-		if err := onRecordFn(keyReader, dataReader); err != nil {
+		if err := onRecordFn(pos, klen, dlen); err != nil {
 			return err
 		}
 		// Move to the next record.
-		pos += 8 + klen + dlen
+		pos += 8 + uint64(klen) + uint64(dlen)
 	}
 	return nil
 }
@@ -259,8 +593,13 @@ func (c *Cdb) ForEachReader(onRecordFn func(keyReader, valReader *io.SectionRead
 //
 // Threadsafe.
 func (c *Cdb) ForEachBytes(onRecordFn func(key, val []byte) error) error {
+	return c.forEachBytes(c.r, onRecordFn)
+}
+
+// forEachBytes is ForEachBytes, reading from r.
+func (c *Cdb) forEachBytes(r io.ReaderAt, onRecordFn func(key, val []byte) error) error {
 	var kbuf, dbuf []byte
-	return c.ForEachReader(func(keyReader, valReader *io.SectionReader) error {
+	return c.forEachRecord(r, func(keyReader, valReader *io.SectionReader) error {
 		// Correctly size the buffers.
 		klen, dlen := keyReader.Size(), valReader.Size()
 		if int64(cap(kbuf)) < klen {
@@ -277,43 +616,217 @@ func (c *Cdb) ForEachBytes(onRecordFn func(key, val []byte) error) error {
 		if _, err := io.ReadFull(valReader, dbuf); err != nil {
 			return err
 		}
+		val := dbuf
+		if c.transform != nil {
+			var err error
+			if val, err = c.transform(kbuf, dbuf); err != nil {
+				return err
+			}
+		}
 		// Send them to the callback.
-		if err := onRecordFn(kbuf, dbuf); err != nil {
+		if err := onRecordFn(kbuf, val); err != nil {
 			return err
 		}
 		return nil
 	})
 }
 
+// ForEachKey calls onKeyFn with the key of every record in the database,
+// without reading any values. A key with several values is passed once for
+// each of them.
+//
+// The byte slice is only valid for the length of a call to onKeyFn.
+//
+// If onKeyFn returns an error, iteration will stop and the error will be
+// returned.
+//
+// Threadsafe.
+func (c *Cdb) ForEachKey(onKeyFn func(key []byte) error) error {
+	var key []byte
+	return c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		if uint32(cap(key)) < klen {
+			key = make([]byte, klen)
+		}
+		key = key[:klen]
+		if err := readFull(c.r, key, int64(pos)+8); err != nil {
+			return err
+		}
+		return onKeyFn(key)
+	})
+}
+
+// ForEachKeyReader is like ForEachKey, but passes each key as an
+// io.SectionReader, so keys don't have to fit in memory.
+//
+// Threadsafe.
+func (c *Cdb) ForEachKeyReader(onKeyFn func(keyReader *io.SectionReader) error) error {
+	return c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		return onKeyFn(io.NewSectionReader(c.r, int64(pos)+8, int64(klen)))
+	})
+}
+
+// transformed wraps onRecordFn so that it is called with transformed values,
+// if the Cdb has a transform.
+func (c *Cdb) transformed(onRecordFn func(keyReader, valReader *io.SectionReader) error) func(keyReader, valReader *io.SectionReader) error {
+	if c.transform == nil {
+		return onRecordFn
+	}
+	return func(keyReader, valReader *io.SectionReader) error {
+		key := make([]byte, keyReader.Size())
+		if _, err := io.ReadFull(keyReader, key); err != nil {
+			return err
+		}
+		valReader, err := c.transformReader(key, valReader)
+		if err != nil {
+			return err
+		}
+		return onRecordFn(io.NewSectionReader(bytes.NewReader(key), 0, int64(len(key))), valReader)
+	}
+}
+
+// transformReader reads the value from valReader and returns a reader for the
+// transformed value.
+func (c *Cdb) transformReader(key []byte, valReader *io.SectionReader) (*io.SectionReader, error) {
+	if c.readerTransform != nil {
+		return c.readerTransform(key, valReader)
+	}
+	val := make([]byte, valReader.Size())
+	if _, err := io.ReadFull(valReader, val); err != nil {
+		return nil, err
+	}
+	val, err := c.transform(key, val)
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(bytes.NewReader(val), 0, int64(len(val))), nil
+}
+
+// ForEachReaderHashOrder calls onRecordFn for every record reachable from the
+// hash tables, walking the tables in order and following each slot's pointer
+// instead of reading the data section sequentially. Every slot is checked
+// against the record it points to, so a successful walk also verifies that
+// the tables and records are consistent.
+//
+// If onRecordFn returns an error, iteration will stop and the error will be
+// returned.
+//
+// Threadsafe.
+func (c *Cdb) ForEachReaderHashOrder(onRecordFn func(keyReader, valReader *io.SectionReader) error) error {
+	for i := 0; i < 256; i++ {
+		if err := c.ForEachReaderInTable(i, onRecordFn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForEachReaderInTable is like ForEachReaderHashOrder, but only walks hash
+// table i, where 0 <= i < 256. It can be used to verify a database
+// incrementally, one table at a time.
+//
+// Threadsafe.
+func (c *Cdb) ForEachReaderInTable(i int, onRecordFn func(keyReader, valReader *io.SectionReader) error) error {
+	if i < 0 || i >= 256 {
+		return fmt.Errorf("cdb: table index %d out of range", i)
+	}
+	return c.walkTable(i, c.transformed(onRecordFn))
+}
+
+// walkTable checks the slots of hash table i and calls onRecordFn with the raw
+// record each one points to.
+func (c *Cdb) walkTable(i int, onRecordFn func(keyReader, valReader *io.SectionReader) error) error {
+	return c.walkSlots(i, func(recPos uint64, klen, dlen uint32) error {
+		keyReader := io.NewSectionReader(c.r, int64(recPos+8), int64(klen))
+		dataReader := io.NewSectionReader(c.r, int64(recPos+8+uint64(klen)), int64(dlen))
+		return onRecordFn(keyReader, dataReader)
+	})
+}
+
+// walkSlots checks the slots of hash table i and calls onSlotFn with the
+// position and key and data lengths of the record each one points to.
+func (c *Cdb) walkSlots(i int, onSlotFn func(recPos uint64, klen, dlen uint32) error) error {
+	lay, err := c.layout()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 16)
+	end, _, err := lay.readEntry(c.r, buf, 0)
+	if err != nil {
+		return err
+	}
+	size := lay.entrySize()
+	hpos, hslots, err := lay.readEntry(c.r, buf, uint64(i)*size)
+	if err != nil {
+		return err
+	}
+	var key []byte
+	for n := uint64(0); n < hslots; n++ {
+		spos := hpos + n*size
+		khash, recPos, err := lay.readEntry(c.r, buf, spos)
+		if err != nil {
+			return err
+		}
+		if recPos == 0 {
+			continue
+		}
+		if khash%256 != uint64(i) {
+			return corruptf(spos, "slot hash %#x belongs in table %d, found in table %d", khash, khash%256, i)
+		}
+		if recPos < lay.headerSize() || recPos >= end {
+			return corruptf(spos, "slot points to %d, outside of the data section", recPos)
+		}
+		klen, dlen, err := readNums(c.r, buf, int64(recPos))
+		if err != nil {
+			return err
+		}
+		if recPos+8+uint64(klen)+uint64(dlen) > end {
+			return corruptf(recPos, "record runs past the end of the data section")
+		}
+		// Check that the slot hash is the hash of the key it points to.
+		if uint32(cap(key)) < klen {
+			key = make([]byte, klen)
+		}
+		key = key[:klen]
+		if err := readFull(c.r, key, int64(recPos)+8); err != nil {
+			return err
+		}
+		if h := c.checksum(key); uint64(h) != khash {
+			return corruptf(spos, "slot hash %#x doesn't match the hash %#x of the record at %d", khash, h, recPos)
+		}
+		if err := onSlotFn(recPos, klen, dlen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // match returns true if the data at file position pos matches key.
-func match(r io.ReaderAt, buf []byte, key []byte, pos uint32) (bool, error) {
+func match(r io.ReaderAt, buf []byte, key []byte, pos int64) (bool, error) {
 	klen := len(key)
 	for n := 0; n < klen; n += len(buf) {
 		nleft := klen - n
 		if len(buf) > nleft {
 			buf = buf[:nleft]
 		}
-		if _, err := r.ReadAt(buf, int64(pos)); err != nil {
+		if err := readFull(r, buf, pos); err != nil {
 			return false, err
 		}
 		if !bytes.Equal(buf, key[n:n+len(buf)]) {
 			return false, nil
 		}
-		pos += uint32(len(buf))
+		pos += int64(len(buf))
 	}
 	return true, nil
 }
 
-func readNums(r io.ReaderAt, buf []byte, pos uint32) (uint32, uint32, error) {
-	n, err := r.ReadAt(buf[:8], int64(pos))
-	// Ignore EOFs when we have read the full 8 bytes.
-	if err == io.EOF && n == 8 {
-		err = nil
-	}
-	if err == io.EOF {
-		err = io.ErrUnexpectedEOF
-	}
-	if err != nil {
+// readFull reads len(buf) bytes at file position pos.
+func readFull(r io.ReaderAt, buf []byte, pos int64) error {
+	n, err := r.ReadAt(buf, pos)
+	return readErr(pos, n, len(buf), err)
+}
+
+func readNums(r io.ReaderAt, buf []byte, pos int64) (uint32, uint32, error) {
+	if err := readFull(r, buf[:8], pos); err != nil {
 		return 0, 0, err
 	}
 	return binary.LittleEndian.Uint32(buf[:4]), binary.LittleEndian.Uint32(buf[4:8]), nil
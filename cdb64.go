@@ -0,0 +1,211 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Databases are either classic cdb files, with 32 bit positions, or use the
+// 64 bit layout of cdb64 and mcdb, for databases bigger than 4GB. The 64 bit
+// layout is the same except that every position and slot count in the header
+// and every hash slot is a pair of 64 bit little endian numbers, so the header
+// is 4096 bytes and slots are 16. Record headers hold 32 bit key and data
+// lengths in both.
+type layout struct {
+	wide bool
+}
+
+// headerSize returns the size of the header, which is also the position of
+// the first record.
+func (l layout) headerSize() uint64 {
+	return 256 * l.entrySize()
+}
+
+// entrySize returns the size of a header entry or hash slot.
+func (l layout) entrySize() uint64 {
+	if l.wide {
+		return 16
+	}
+	return 8
+}
+
+// maxPos returns the largest position the layout can hold.
+func (l layout) maxPos() uint64 {
+	if l.wide {
+		return 1<<64 - 1
+	}
+	return 1<<32 - 1
+}
+
+// entry decodes the header entry or hash slot at the start of buf.
+func (l layout) entry(buf []byte) (uint64, uint64) {
+	if l.wide {
+		return binary.LittleEndian.Uint64(buf), binary.LittleEndian.Uint64(buf[8:])
+	}
+	return uint64(binary.LittleEndian.Uint32(buf)), uint64(binary.LittleEndian.Uint32(buf[4:]))
+}
+
+// putEntry encodes a header entry or hash slot at the start of buf.
+func (l layout) putEntry(buf []byte, x, y uint64) {
+	if l.wide {
+		binary.LittleEndian.PutUint64(buf, x)
+		binary.LittleEndian.PutUint64(buf[8:], y)
+		return
+	}
+	binary.LittleEndian.PutUint32(buf, uint32(x))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(y))
+}
+
+// readEntry reads the header entry or hash slot at pos.
+func (l layout) readEntry(r io.ReaderAt, buf []byte, pos uint64) (uint64, uint64, error) {
+	n := l.entrySize()
+	if err := readFull(r, buf[:n], int64(pos)); err != nil {
+		return 0, 0, err
+	}
+	x, y := l.entry(buf)
+	return x, y, nil
+}
+
+// slotChunk is the number of hash slots forEachSlot reads at once, so the
+// memory it uses doesn't depend on the slot counts in the header, which a
+// damaged database can set to anything.
+const slotChunk = 512
+
+// readTable reads the position and slot count of hash table i from the
+// header.
+func (l layout) readTable(r io.ReaderAt, buf []byte, i uint64) (uint64, uint64, error) {
+	size := l.entrySize()
+	hpos, hslots, err := l.readEntry(r, buf, i*size)
+	if err != nil {
+		return 0, 0, err
+	}
+	if hslots > (1<<64-1-hpos)/size {
+		return 0, 0, corruptf(i*size, "hash table %d has too many slots", i)
+	}
+	return hpos, hslots, nil
+}
+
+// forEachSlot calls fn with the index, hash and record position of each of
+// the hslots slots of the hash table at hpos. The slots are read slotChunk at
+// a time, so a slot count that runs past the end of the file is reported as
+// a CorruptError once the reads reach it.
+func (l layout) forEachSlot(r io.ReaderAt, hpos, hslots uint64, fn func(n, h, pos uint64) error) error {
+	size := l.entrySize()
+	buf := make([]byte, min(hslots, slotChunk)*size)
+	for n := uint64(0); n < hslots; {
+		chunk := buf[:min(hslots-n, slotChunk)*size]
+		if err := readFull(r, chunk, int64(hpos+n*size)); err != nil {
+			return err
+		}
+		for off := uint64(0); off < uint64(len(chunk)); off += size {
+			h, pos := l.entry(chunk[off:])
+			if err := fn(n, h, pos); err != nil {
+				return err
+			}
+			n++
+		}
+	}
+	return nil
+}
+
+// Cdb64 returns a WriterOption that writes the database in the 64 bit layout
+// used by cdb64 and mcdb, which has no 4GB size limit. Open and New detect
+// the layout automatically, but other cdb implementations can't read it.
+func Cdb64() WriterOption {
+	return func(w *Writer) {
+		w.wide = true
+	}
+}
+
+// layoutState holds the layout of a Cdb's database, which is detected on
+// first use. A failed detection isn't kept, so a lazily opened file that
+// doesn't exist yet is looked at again.
+type layoutState struct {
+	mu sync.Mutex
+	// known is set to 1, after l, once the layout is detected.
+	known uint32
+	l     layout
+}
+
+// layout returns the layout of the database. Every read of the database
+// starts with it, so it also returns ErrClosed once the Cdb is closed.
+//
+// Threadsafe.
+func (c *Cdb) layout() (layout, error) {
+	return c.layoutAt(c.r)
+}
+
+// layoutAt is layout, detecting the layout by reading from r if it isn't
+// known yet.
+func (c *Cdb) layoutAt(r io.ReaderAt) (layout, error) {
+	if atomic.LoadUint32(&c.closed) == 1 {
+		return layout{}, ErrClosed
+	}
+	if atomic.LoadUint32(&c.lay.known) == 1 {
+		return c.lay.l, nil
+	}
+	// A lazily opened file is opened first, since the Eager options work
+	// out the layout themselves. Errors opening it are reported like the
+	// failed read of the header they stand for.
+	if c.lazy != nil {
+		if _, err := c.lazy.file(); err != nil {
+			return layout{}, readErr(0, 0, 1, err)
+		}
+	}
+	c.lay.mu.Lock()
+	defer c.lay.mu.Unlock()
+	if c.lay.known == 0 {
+		l, err := detectLayout(r)
+		if err != nil {
+			return layout{}, err
+		}
+		c.lay.l = l
+		atomic.StoreUint32(&c.lay.known, 1)
+	}
+	return c.lay.l, nil
+}
+
+// detectLayout works out the layout of the database in r. Builders write the
+// hash tables one after another in table order, so in the right layout each
+// table starts where the one before it ends. Reading the header in the wrong
+// layout mixes up positions and slot counts, which breaks that.
+func detectLayout(r io.ReaderAt) (layout, error) {
+	header := make([]byte, 256*16)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return layout{}, readErr(0, n, len(header), err)
+	}
+	if n < 256*8 {
+		return layout{}, fmt.Errorf("%w: %d bytes is too short for a header: %w", ErrInvalidDatabase, n, io.ErrUnexpectedEOF)
+	}
+	classic := layout{}
+	if n < len(header) || contiguous(classic, header) {
+		return classic, nil
+	}
+	if wide := (layout{wide: true}); contiguous(wide, header) {
+		return wide, nil
+	}
+	// Not written by a known builder. Assume the classic layout, which
+	// other builders use.
+	return classic, nil
+}
+
+// contiguous returns true if the hash tables in header, read in layout l,
+// follow on from each other after the records.
+func contiguous(l layout, header []byte) bool {
+	pos, nslots := l.entry(header)
+	if pos < l.headerSize() {
+		return false
+	}
+	for i := uint64(1); i < 256; i++ {
+		next, n := l.entry(header[i*l.entrySize():])
+		if next != pos+nslots*l.entrySize() {
+			return false
+		}
+		pos, nslots = next, n
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func newDB64Bytes(recs []rec) []byte {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp, Cdb64())
+	for _, record := range recs {
+		for _, val := range record.values {
+			if err := w.Write([]byte(record.key), []byte(val)); err != nil {
+				panic(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestCdb64(t *testing.T) {
+	b := newDB64Bytes(records)
+	expected := uint64(4096)
+	for _, rec := range records {
+		for _, val := range rec.values {
+			expected += 8 + uint64(len(rec.key)) + uint64(len(val))
+		}
+	}
+	if eod := binary.LittleEndian.Uint64(b); eod != expected {
+		t.Fatalf("expected the data to end at %d, got: %d", expected, eod)
+	}
+	db := New(bytes.NewReader(b))
+	if lay, err := db.layout(); err != nil || !lay.wide {
+		t.Fatalf("expected the 64 bit layout to be detected, got: %+v, %v", lay, err)
+	}
+	checkRecords(t, db, records)
+	checkRecords(t, New(bytes.NewReader(newDB64Bytes(adversarialRecords))), adversarialRecords)
+	if _, err := db.Bytes([]byte("missing")); err == nil {
+		t.Errorf("expected an error for a missing key")
+	}
+
+	n := 0
+	if err := db.ForEachBytes(func(key, val []byte) error {
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("expected 6 records, got: %d", n)
+	}
+	if err := db.Verify(); err != nil {
+		t.Errorf("Verify error: %v", err)
+	}
+
+	var dump bytes.Buffer
+	if err := Dump(&dump, bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump.Bytes(), data) {
+		t.Errorf("expected dump %q, got: %q", data, dump.Bytes())
+	}
+}
+
+func TestDetectLayoutClassic(t *testing.T) {
+	for _, recs := range [][]rec{nil, records, adversarialRecords} {
+		lay, err := detectLayout(bytes.NewReader(newDBBytes(recs)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lay.wide {
+			t.Errorf("expected the classic layout for %d records", len(recs))
+		}
+	}
+	lay, err := detectLayout(bytes.NewReader(newDB64Bytes(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lay.wide {
+		t.Errorf("expected the 64 bit layout for an empty database")
+	}
+}
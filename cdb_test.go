@@ -6,6 +6,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -71,10 +73,10 @@ func TestCdb(t *testing.T) {
 				t.Fatal("value mismatch")
 			}
 		}
-		// Read all values, so should get EOF
+		// Read all values, so should get ErrNotFound
 		_, err = iter.NextReader()
-		if err != io.EOF {
-			t.Fatalf("Expected EOF, got %s", err)
+		if err != ErrNotFound {
+			t.Fatalf("Expected ErrNotFound, got %s", err)
 		}
 	}
 
@@ -97,8 +99,8 @@ func TestCdb(t *testing.T) {
 func TestNotFound(t *testing.T) {
 	db := newDB(records)
 	b, err := db.Bytes([]byte("asdf"))
-	if err != io.EOF {
-		t.Errorf("err: expected EOF, got: %v", err)
+	if err != ErrNotFound {
+		t.Errorf("err: expected ErrNotFound, got: %v", err)
 	}
 	if b != nil {
 		t.Errorf("b: expected nil, got: %s", b)
@@ -116,6 +118,27 @@ func TestBytes(t *testing.T) {
 	}
 }
 
+func TestAllValues(t *testing.T) {
+	db := newDB(records)
+	for _, rec := range records {
+		vals, err := db.AllValues([]byte(rec.key))
+		if err != nil {
+			t.Fatalf("AllValues error: %v", err)
+		}
+		if len(vals) != len(rec.values) {
+			t.Fatalf("key %q: expected %d values, got: %d", rec.key, len(rec.values), len(vals))
+		}
+		for i, val := range rec.values {
+			if string(vals[i]) != val {
+				t.Errorf("key %q: expected %s, got: %s", rec.key, val, vals[i])
+			}
+		}
+	}
+	if vals, err := db.AllValues([]byte("asdf")); vals != nil || err != nil {
+		t.Errorf("expected no values, got: %q, %v", vals, err)
+	}
+}
+
 func TestReader(t *testing.T) {
 	db := newDB(records)
 	r, err := db.Reader([]byte("one"))
@@ -208,3 +231,77 @@ func init() {
 	b.WriteByte('\n')
 	data = b.Bytes()
 }
+
+func TestForEachKey(t *testing.T) {
+	big := strings.Repeat("x", 1<<20)
+	r := &offsetsReaderAt{r: bytes.NewReader(newDBBytes([]rec{{"a", []string{big, "1"}}, {"b", []string{"2"}}}))}
+	db := New(r)
+	var keys []string
+	if err := db.ForEachKey(func(key []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a", "a", "b"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got: %v", expected, keys)
+	}
+	valStart := int64(headerSize) + 8 + 1
+	for _, off := range r.offs {
+		if off >= valStart && off < valStart+int64(len(big)) {
+			t.Errorf("expected no reads of the value, got a read at %d", off)
+		}
+	}
+
+	keys = nil
+	if err := db.ForEachKeyReader(func(keyReader *io.SectionReader) error {
+		key, err := ioutil.ReadAll(keyReader)
+		keys = append(keys, string(key))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a", "a", "b"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got: %v", expected, keys)
+	}
+}
+
+func TestForEachHashOrder(t *testing.T) {
+	db := newDB(records)
+	got := make(map[string][]string)
+	n := 0
+	err := db.ForEachReaderHashOrder(func(keyReader, valReader *io.SectionReader) error {
+		key, err := ioutil.ReadAll(keyReader)
+		if err != nil {
+			return err
+		}
+		val, err := ioutil.ReadAll(valReader)
+		if err != nil {
+			return err
+		}
+		got[string(key)] = append(got[string(key)], string(val))
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachReaderHashOrder error: %v", err)
+	}
+	if n != 6 {
+		t.Errorf("expected 6 records, got: %v", n)
+	}
+	for _, rec := range records {
+		if len(got[rec.key]) != len(rec.values) {
+			t.Errorf("key %s: expected %v, got: %v", rec.key, rec.values, got[rec.key])
+		}
+	}
+
+	// Changing a key breaks the link between its slot and record.
+	b := newDBBytes(records)
+	b[headerSize+8] ^= 0xff
+	err = New(bytes.NewReader(b)).ForEachReaderHashOrder(func(keyReader, valReader *io.SectionReader) error {
+		return nil
+	})
+	if err == nil {
+		t.Errorf("expected an error for a corrupt key")
+	}
+}
@@ -0,0 +1,17 @@
+// Package cdbafero opens cdb databases stored in afero filesystems.
+package cdbafero
+
+import (
+	"github.com/spf13/afero"
+	"github.com/torbit/cdb"
+)
+
+// Open opens the named file in fs and returns a new Cdb object. Close closes
+// the file.
+func Open(fs afero.Fs, name string, opts ...cdb.Option) (*cdb.Cdb, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return cdb.NewFile(f, opts...), nil
+}
@@ -0,0 +1,33 @@
+package cdbafero
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/torbit/cdb"
+)
+
+func TestOpen(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	f, err := fs.Create("test.cdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cdb.Make(f, bytes.NewBufferString("+3,1:one->1\n\n")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	db, err := Open(fs, "test.cdb")
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer db.Close()
+	if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected 1, got: %q, %v", b, err)
+	}
+	if _, err := Open(fs, "missing.cdb"); err == nil {
+		t.Errorf("expected an error opening a missing file")
+	}
+}
@@ -0,0 +1,17 @@
+// Package cdbbilly opens cdb databases stored in go-billy filesystems.
+package cdbbilly
+
+import (
+	"github.com/go-git/go-billy/v5"
+	"github.com/torbit/cdb"
+)
+
+// Open opens the named file in fs and returns a new Cdb object. Close closes
+// the file.
+func Open(fs billy.Basic, name string, opts ...cdb.Option) (*cdb.Cdb, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return cdb.NewFile(f, opts...), nil
+}
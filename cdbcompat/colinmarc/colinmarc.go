@@ -0,0 +1,195 @@
+// Package cdb exposes this module's reader and writer with the API of
+// github.com/colinmarc/cdb, so programs using it can switch by changing
+// their import path, and the two can be benchmarked with the same code.
+package cdb
+
+import (
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/torbit/cdb"
+)
+
+// CDB is a database opened for reading.
+type CDB struct {
+	db     *cdb.Cdb
+	closer io.Closer
+}
+
+// Open opens the database at path.
+func Open(path string) (*CDB, error) {
+	db, err := cdb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &CDB{db: db, closer: db}, nil
+}
+
+// New opens the database in reader. A nil hasher uses the cdb hash.
+func New(reader io.ReaderAt, hasher func() hash.Hash32) (*CDB, error) {
+	var opts []cdb.Option
+	if hasher != nil {
+		opts = append(opts, cdb.WithHash(hashFunc(hasher)))
+	}
+	return &CDB{db: cdb.New(reader, opts...)}, nil
+}
+
+// Get returns the first value for key, or nil if there is none.
+func (c *CDB) Get(key []byte) ([]byte, error) {
+	val, err := c.db.Bytes(key)
+	if errors.Is(err, cdb.ErrNotFound) {
+		return nil, nil
+	}
+	return val, err
+}
+
+// Size returns the size of the database in bytes, or 0 if it can't be told.
+func (c *CDB) Size() int {
+	f, ok := c.closer.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return 0
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return 0
+	}
+	return int(fi.Size())
+}
+
+// Close closes the database, if it was opened by Open.
+func (c *CDB) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+// Iter returns an Iterator over the records of the database.
+func (c *CDB) Iter() *Iterator {
+	return &Iterator{db: c.db}
+}
+
+// Iterator visits the records of a database. The values of a key are visited
+// together, in the order the keys were first written.
+type Iterator struct {
+	db    *cdb.Cdb
+	token []byte
+	keys  [][]byte
+	vals  [][]byte
+	key   []byte
+	val   []byte
+	done  bool
+	err   error
+}
+
+// iterPage is the number of keys an Iterator lists at a time.
+const iterPage = 64
+
+// Next moves to the next record, and returns false once there are none left
+// or an error happened.
+func (it *Iterator) Next() bool {
+	for len(it.vals) == 0 {
+		if it.err != nil {
+			return false
+		}
+		if len(it.keys) == 0 {
+			if it.done {
+				return false
+			}
+			it.keys, it.token, it.err = it.db.ListKeys(it.token, iterPage)
+			it.done = it.token == nil
+			continue
+		}
+		it.key, it.keys = it.keys[0], it.keys[1:]
+		it.vals, it.err = it.db.AllValues(it.key)
+	}
+	it.val, it.vals = it.vals[0], it.vals[1:]
+	return true
+}
+
+// Key returns the key of the current record.
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current record.
+func (it *Iterator) Value() []byte {
+	return it.val
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Writer builds a database.
+type Writer struct {
+	w      *cdb.Writer
+	ws     io.WriteSeeker
+	hasher func() hash.Hash32
+}
+
+// Create creates a database at path, replacing any file there.
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(f, nil)
+}
+
+// NewWriter builds a database in writer. A nil hasher uses the cdb hash.
+func NewWriter(writer io.WriteSeeker, hasher func() hash.Hash32) (*Writer, error) {
+	var opts []cdb.WriterOption
+	if hasher != nil {
+		opts = append(opts, cdb.Hash(hashFunc(hasher)))
+	}
+	return &Writer{w: cdb.NewWriter(writer, opts...), ws: writer, hasher: hasher}, nil
+}
+
+// Put adds a record.
+func (w *Writer) Put(key, value []byte) error {
+	return w.w.Put(key, value)
+}
+
+// Close finishes the database, and closes the file if it can be closed.
+func (w *Writer) Close() error {
+	err := w.w.Close()
+	if c, ok := w.ws.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Freeze finishes the database and returns it opened for reading. The writer
+// must be an io.ReaderAt, like an *os.File, and is closed by the CDB's Close.
+func (w *Writer) Freeze() (*CDB, error) {
+	r, ok := w.ws.(io.ReaderAt)
+	if !ok {
+		return nil, errors.New("cdb: writer can't be read back")
+	}
+	if err := w.w.Close(); err != nil {
+		return nil, err
+	}
+	c, _ := New(r, w.hasher)
+	c.closer, _ = w.ws.(io.Closer)
+	return c, nil
+}
+
+// hashFunc adapts hasher to the key hash functions of this module, which must
+// be threadsafe.
+func hashFunc(hasher func() hash.Hash32) func(key []byte) uint32 {
+	pool := sync.Pool{New: func() interface{} { return hasher() }}
+	return func(key []byte) uint32 {
+		h := pool.Get().(hash.Hash32)
+		defer pool.Put(h)
+		h.Reset()
+		h.Write(key)
+		return h.Sum32()
+	}
+}
@@ -0,0 +1,84 @@
+package cdb
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	for _, hasher := range []func() hash.Hash32{nil, fnv.New32a} {
+		path := filepath.Join(t.TempDir(), "test.cdb")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWriter(f, hasher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, kv := range [][2]string{{"one", "1"}, {"two", "2"}, {"three", "3"}, {"two", "22"}} {
+			if err := w.Put([]byte(kv[0]), []byte(kv[1])); err != nil {
+				t.Fatal(err)
+			}
+		}
+		db, err := w.Freeze()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := db.Get([]byte("two")); err != nil || string(v) != "2" {
+			t.Errorf("expected 2, got: %q, %v", v, err)
+		}
+		if v, err := db.Get([]byte("four")); err != nil || v != nil {
+			t.Errorf("expected nil, nil for a missing key, got: %q, %v", v, err)
+		}
+		if db.Size() == 0 {
+			t.Error("expected the size of the file")
+		}
+
+		var got []string
+		it := db.Iter()
+		for it.Next() {
+			got = append(got, fmt.Sprintf("%s=%s", it.Key(), it.Value()))
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		if want := "[one=1 two=2 two=22 three=3]"; fmt.Sprint(got) != want {
+			t.Errorf("expected %s, got: %v", want, got)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "bench.cdb")
+	w, err := Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		k := []byte(fmt.Sprint(i))
+		w.Put(k, k)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	db, err := Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	key := []byte("500")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.Get(key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
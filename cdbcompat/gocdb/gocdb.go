@@ -0,0 +1,88 @@
+// Package cdb exposes this module's reader with the API of
+// github.com/jbarham/go-cdb, which it was forked from, so programs using it
+// can switch by changing their import path, and the two can be benchmarked
+// with the same code.
+package cdb
+
+import (
+	"errors"
+	"io"
+
+	"github.com/torbit/cdb"
+)
+
+// Cdb is a database opened for reading. A Cdb holds the state of a FindNext
+// search, so it is not threadsafe.
+type Cdb struct {
+	db   *cdb.Cdb
+	iter *cdb.CdbIterator
+	// key is the key of the search iter is for.
+	key string
+}
+
+// Open opens the named file read-only.
+func Open(name string) (*Cdb, error) {
+	db, err := cdb.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Cdb{db: db}, nil
+}
+
+// New returns a Cdb reading from r.
+func New(r io.ReaderAt) *Cdb {
+	return &Cdb{db: cdb.New(r)}
+}
+
+// Close closes the database, if it was opened by Open.
+func (c *Cdb) Close() error {
+	return c.db.Close()
+}
+
+// Data returns the first value for key, or io.EOF if there is none.
+func (c *Cdb) Data(key []byte) ([]byte, error) {
+	b, err := c.db.Bytes(key)
+	return b, eof(err)
+}
+
+// FindStart resets the search, so the next FindNext returns the first value.
+func (c *Cdb) FindStart() {
+	c.iter = nil
+}
+
+// FindNext returns the next value for key, or io.EOF once there are none
+// left. Searching for another key starts again from its first value.
+func (c *Cdb) FindNext(key []byte) (*io.SectionReader, error) {
+	if c.iter == nil || c.key != string(key) {
+		c.key = string(key)
+		c.iter = c.db.Iterate([]byte(c.key))
+	}
+	r, err := c.iter.NextReader()
+	return r, eof(err)
+}
+
+// Find returns the first value for key, or io.EOF if there is none.
+func (c *Cdb) Find(key []byte) (*io.SectionReader, error) {
+	c.FindStart()
+	return c.FindNext(key)
+}
+
+// eof returns io.EOF itself for a missing key, since go-cdb callers compare
+// errors with it.
+func eof(err error) error {
+	if errors.Is(err, cdb.ErrNotFound) {
+		return io.EOF
+	}
+	return err
+}
+
+// Make reads records in the cdbmake format from r and writes a database to w.
+func Make(w io.WriteSeeker, r io.Reader) error {
+	return cdb.Make(w, r)
+}
+
+// Dump writes the records of the database read from r to w in the cdbmake
+// format.
+func Dump(w io.Writer, r io.Reader) error {
+	return cdb.Dump(w, r)
+}
@@ -0,0 +1,86 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const records = "+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n\n"
+
+func newDB(t testing.TB) string {
+	path := filepath.Join(t.TempDir(), "test.cdb")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := Make(f, strings.NewReader(records)); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFind(t *testing.T) {
+	c, err := Open(newDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if b, err := c.Data([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected 1, got: %q, %v", b, err)
+	}
+	if _, err := c.Data([]byte("three")); err != io.EOF {
+		t.Errorf("expected io.EOF, got: %v", err)
+	}
+
+	var vals []string
+	c.FindStart()
+	for {
+		r, err := c.FindNext([]byte("two"))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(r)
+		vals = append(vals, string(b))
+	}
+	if strings.Join(vals, ",") != "2,22" {
+		t.Errorf("expected 2,22, got: %v", vals)
+	}
+	if r, err := c.Find([]byte("two")); err != nil || r.Size() != 1 {
+		t.Errorf("expected Find to start again, got: %v", err)
+	}
+
+	f, err := os.Open(newDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if err := Dump(&buf, f); err != nil || buf.String() != records {
+		t.Errorf("expected %q, got: %q, %v", records, buf.String(), err)
+	}
+}
+
+func BenchmarkData(b *testing.B) {
+	c, err := Open(newDB(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	key := []byte("two")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Data(key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
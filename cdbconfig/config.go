@@ -0,0 +1,226 @@
+// Package cdbconfig serves configuration from cdb files, so services can
+// distribute their configuration as a cdb built once and pushed everywhere.
+//
+// A Source satisfies the koanf Provider and Watcher interfaces without
+// depending on koanf, so it can be passed straight to koanf.Load. For viper,
+// pass the map returned by Read to viper.MergeConfigMap.
+package cdbconfig
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+// DefaultInterval is how often Watch checks the file for changes if the
+// Source's Interval isn't set.
+const DefaultInterval = time.Second
+
+// Source is configuration read from a cdb file, whose keys are the
+// configuration keys and whose values are the settings. The file is reopened
+// by Reload, or by Watch when it sees the file replaced, so deploys should
+// rename a new file over the old one.
+//
+// Threadsafe.
+type Source struct {
+	// Interval is how often Watch checks the file. Set it before calling
+	// Watch.
+	Interval time.Duration
+
+	name  string
+	delim string
+	opts  []cdb.Option
+
+	mu   sync.RWMutex
+	db   *cdb.Cdb
+	info os.FileInfo
+	// stop is closed to stop Watch, and is nil when not watching.
+	stop   chan struct{}
+	closed bool
+
+	// reloadMu is held by Reload, so changes are worked out between
+	// consecutive databases.
+	reloadMu sync.Mutex
+
+	subMu      sync.Mutex
+	subs       map[<-chan Change]*subscription
+	closedSubs bool
+}
+
+// Open opens the named cdb file as a Source. Keys are split into nested
+// sections at delim by Read, so with a delim of "." the key "db.host" is
+// the setting host in the section db. An empty delim leaves keys flat. The
+// options are used every time the file is opened.
+func Open(name, delim string, opts ...cdb.Option) (*Source, error) {
+	s := &Source{name: name, delim: delim, opts: opts}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the first value of key. It returns cdb.ErrNotFound if there is
+// no such key.
+func (s *Source) Get(key string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return "", cdb.ErrClosed
+	}
+	b, err := s.db.Bytes([]byte(key))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Read returns all of the settings, nested into sections at the delimiter
+// given to Open. Keys with several values have a []interface{} of them.
+func (s *Source) Read() (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return nil, cdb.ErrClosed
+	}
+	out := make(map[string]interface{})
+	err := s.db.ForEachBytes(func(key, val []byte) error {
+		path := []string{string(key)}
+		if s.delim != "" {
+			path = strings.Split(string(key), s.delim)
+		}
+		m := out
+		for _, section := range path[:len(path)-1] {
+			sub, ok := m[section].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				m[section] = sub
+			}
+			m = sub
+		}
+		name := path[len(path)-1]
+		switch old := m[name].(type) {
+		case nil:
+			m[name] = string(val)
+		case []interface{}:
+			m[name] = append(old, string(val))
+		case string:
+			m[name] = []interface{}{old, string(val)}
+		default:
+			// A section with the same name as a setting wins.
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReadBytes isn't supported, since the settings have no serialized form. It
+// is there to satisfy the koanf Provider interface, which uses Read when no
+// parser is given.
+func (s *Source) ReadBytes() ([]byte, error) {
+	return nil, errors.New("cdbconfig: ReadBytes is not supported, use Read")
+}
+
+// Reload reopens the file if it has been replaced or modified since it was
+// last opened, and returns true if it was. The old database is closed once
+// the reads using it are done, and changes to subscribed keys have been sent.
+func (s *Source) Reload() (bool, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	info, err := os.Stat(s.name)
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	same := s.info != nil && os.SameFile(s.info, info) &&
+		s.info.ModTime().Equal(info.ModTime()) && s.info.Size() == info.Size()
+	s.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+	db, err := cdb.Open(s.name, s.opts...)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		db.Close()
+		return false, cdb.ErrClosed
+	}
+	old := s.db
+	s.db, s.info = db, info
+	s.mu.Unlock()
+	if old != nil {
+		s.notify(old, db)
+		old.Close()
+	}
+	return true, nil
+}
+
+// Watch checks the file for changes every Interval, and calls cb with a nil
+// event after reloading it, or with the error if reloading failed. It returns
+// an error if the Source is already being watched. Stop watching with
+// Unwatch.
+func (s *Source) Watch(cb func(event interface{}, err error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return errors.New("cdbconfig: already watching")
+	}
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+			if changed, err := s.Reload(); err != nil {
+				cb(nil, err)
+			} else if changed {
+				cb(nil, nil)
+			}
+		}
+	}()
+	return nil
+}
+
+// Unwatch stops Watch.
+func (s *Source) Unwatch() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	return nil
+}
+
+// Close stops watching, ends all subscriptions and closes the database.
+func (s *Source) Close() error {
+	s.Unwatch()
+	s.unsubscribeAll()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
@@ -0,0 +1,103 @@
+package cdbconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+// writeDB writes a database of the key value pairs to name, replacing it by
+// renaming a new file over it like a deploy would.
+func writeDB(t *testing.T, name string, kvs ...string) {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := cdb.NewWriter(tmp)
+	for i := 0; i < len(kvs); i += 2 {
+		if err := w.Write([]byte(kvs[i]), []byte(kvs[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "config.cdb")
+	writeDB(t, name, "db.host", "localhost", "db.port", "5432", "name", "svc", "peers", "a", "peers", "b")
+
+	s, err := Open(name, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	m, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"db":    map[string]interface{}{"host": "localhost", "port": "5432"},
+		"name":  "svc",
+		"peers": []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %v, got: %v", expected, m)
+	}
+	if v, err := s.Get("db.host"); err != nil || v != "localhost" {
+		t.Errorf("expected localhost, got: %q, %v", v, err)
+	}
+	if _, err := s.Get("missing"); err != cdb.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if changed, err := s.Reload(); changed || err != nil {
+		t.Errorf("expected no reload of an unchanged file, got: %v, %v", changed, err)
+	}
+
+	s.Interval = time.Millisecond
+	reloaded := make(chan error, 1)
+	if err := s.Watch(func(event interface{}, err error) {
+		select {
+		case reloaded <- err:
+		default:
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Watch(func(interface{}, error) {}); err == nil {
+		t.Errorf("expected an error watching twice")
+	}
+	writeDB(t, name, "db.host", "db1")
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a reload")
+	}
+	if v, err := s.Get("db.host"); err != nil || v != "db1" {
+		t.Errorf("expected db1 after the reload, got: %q, %v", v, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("db.host"); err != cdb.ErrClosed {
+		t.Errorf("expected ErrClosed, got: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package cdbconfig
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/torbit/cdb"
+)
+
+// Change is a change to the values of a watched key, seen when the file was
+// reloaded.
+type Change struct {
+	Key []byte
+	// Old and New are all of the key's values before and after the reload,
+	// in order. They are nil if the key wasn't there.
+	Old, New [][]byte
+	// Err is set if the values couldn't be read, in which case Old and New
+	// may be incomplete.
+	Err error
+}
+
+// subscription is a channel of changes to some keys.
+type subscription struct {
+	keys [][]byte
+	ch   chan Change
+	// stop is closed to abandon sends when the subscription is ended.
+	stop chan struct{}
+	// mu is held while sending, so ch isn't closed during a send.
+	mu     sync.Mutex
+	closed bool
+}
+
+// Subscribe returns a channel that receives a Change for each of keys whose
+// values differ after a reload. The changes of a reload are sent before
+// Reload returns, so a receiver that falls behind holds up reloading. The
+// channel is closed by Unsubscribe or Close.
+func (s *Source) Subscribe(keys [][]byte) <-chan Change {
+	sub := &subscription{
+		ch:   make(chan Change, len(keys)),
+		stop: make(chan struct{}),
+	}
+	for _, key := range keys {
+		sub.keys = append(sub.keys, append([]byte(nil), key...))
+	}
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[<-chan Change]*subscription)
+	}
+	if s.closedSubs {
+		sub.end()
+	} else {
+		s.subs[sub.ch] = sub
+	}
+	return sub.ch
+}
+
+// Unsubscribe stops sending changes to ch, which was returned by Subscribe,
+// and closes it.
+func (s *Source) Unsubscribe(ch <-chan Change) {
+	s.subMu.Lock()
+	sub := s.subs[ch]
+	delete(s.subs, ch)
+	s.subMu.Unlock()
+	if sub != nil {
+		sub.end()
+	}
+}
+
+// unsubscribeAll ends every subscription, for Close.
+func (s *Source) unsubscribeAll() {
+	s.subMu.Lock()
+	subs := s.subs
+	s.subs, s.closedSubs = nil, true
+	s.subMu.Unlock()
+	for _, sub := range subs {
+		sub.end()
+	}
+}
+
+// end closes the subscription's channel, abandoning any send in progress.
+func (sub *subscription) end() {
+	close(sub.stop)
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.closed = true
+	close(sub.ch)
+}
+
+// notify sends the changes to the subscribed keys between the old and new
+// databases.
+func (s *Source) notify(old, db *cdb.Cdb) {
+	s.subMu.Lock()
+	subs := make([]*subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.subMu.Unlock()
+	for _, sub := range subs {
+		for _, key := range sub.keys {
+			c, changed := diffKey(old, db, key)
+			if changed && !sub.send(c) {
+				break
+			}
+		}
+	}
+}
+
+// send sends c, and returns false if the subscription has ended.
+func (sub *subscription) send(c Change) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return false
+	}
+	select {
+	case sub.ch <- c:
+		return true
+	case <-sub.stop:
+		return false
+	}
+}
+
+// diffKey returns the change to the values of key between old and db, and
+// whether there is one.
+func diffKey(old, db *cdb.Cdb, key []byte) (Change, bool) {
+	c := Change{Key: key}
+	c.Old, c.Err = old.AllValues(key)
+	if c.Err != nil {
+		return c, true
+	}
+	c.New, c.Err = db.AllValues(key)
+	if c.Err != nil {
+		return c, true
+	}
+	if len(c.Old) != len(c.New) {
+		return c, true
+	}
+	for i := range c.Old {
+		if !bytes.Equal(c.Old[i], c.New[i]) {
+			return c, true
+		}
+	}
+	return c, false
+}
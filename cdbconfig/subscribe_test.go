@@ -0,0 +1,52 @@
+package cdbconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "config.cdb")
+	writeDB(t, name, "db.host", "localhost", "name", "svc", "peers", "a")
+
+	s, err := Open(name, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ch := s.Subscribe([][]byte{[]byte("db.host"), []byte("name"), []byte("peers"), []byte("new")})
+	other := s.Subscribe([][]byte{[]byte("name")})
+
+	writeDB(t, name, "db.host", "db1", "name", "svc", "peers", "a", "peers", "b", "new", "1")
+	if changed, err := s.Reload(); !changed || err != nil {
+		t.Fatalf("expected a reload, got: %v, %v", changed, err)
+	}
+	s.Unsubscribe(ch)
+	var got []Change
+	for c := range ch {
+		got = append(got, c)
+	}
+	expected := []Change{
+		{Key: []byte("db.host"), Old: [][]byte{[]byte("localhost")}, New: [][]byte{[]byte("db1")}},
+		{Key: []byte("peers"), Old: [][]byte{[]byte("a")}, New: [][]byte{[]byte("a"), []byte("b")}},
+		{Key: []byte("new"), New: [][]byte{[]byte("1")}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected changes %q, got: %q", expected, got)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := <-other; ok {
+		t.Errorf("expected no changes to an unchanged key, got: %q", c)
+	}
+}
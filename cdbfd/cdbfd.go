@@ -0,0 +1,63 @@
+//go:build unix
+
+package cdbfd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/torbit/cdb"
+)
+
+// Send sends f's descriptor over conn. The descriptor is duplicated into the
+// receiving process, so f can be closed once Send returns.
+func Send(conn *net.UnixConn, f *os.File) error {
+	// Stream sockets need at least one byte of data to carry the descriptor.
+	_, _, err := conn.WriteMsgUnix([]byte{0}, syscall.UnixRights(int(f.Fd())), nil)
+	return err
+}
+
+// ReceiveFile receives a descriptor sent by Send over conn, and returns it as
+// a file with the given name.
+func ReceiveFile(conn *net.UnixConn, name string) (*os.File, error) {
+	buf := make([]byte, 1)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, os.NewSyscallError("parse socket control message", err)
+	}
+	var fds []int
+	for i := range msgs {
+		got, err := syscall.ParseUnixRights(&msgs[i])
+		if err != nil {
+			return nil, os.NewSyscallError("parse unix rights", err)
+		}
+		fds = append(fds, got...)
+	}
+	if len(fds) != 1 {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+		return nil, errors.New("cdbfd: expected one file descriptor")
+	}
+	syscall.CloseOnExec(fds[0])
+	return os.NewFile(uintptr(fds[0]), name), nil
+}
+
+// Receive receives a descriptor sent by Send over conn and opens it as a
+// database with opts. Closing the database closes the descriptor.
+func Receive(conn *net.UnixConn, opts ...cdb.Option) (*cdb.Cdb, error) {
+	f, err := ReceiveFile(conn, "received")
+	if err != nil {
+		return nil, err
+	}
+	// NewFromFd duplicates the descriptor.
+	defer f.Close()
+	return cdb.NewFromFd(f.Fd(), opts...)
+}
@@ -0,0 +1,62 @@
+//go:build unix
+
+package cdbfd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+// socketPair returns the two ends of a connected unix socket.
+func socketPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conns [2]*net.UnixConn
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socket")
+		c, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = c.(*net.UnixConn)
+		t.Cleanup(func() { c.Close() })
+	}
+	return conns[0], conns[1]
+}
+
+func TestSendReceive(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := cdb.NewWriter(tmp)
+	if err := w.Put([]byte("one"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sender, receiver := socketPair(t)
+	if err := Send(sender, tmp); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	db, err := Receive(receiver, cdb.WithMmap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected 1, got: %q, %v", b, err)
+	}
+}
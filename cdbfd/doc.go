@@ -0,0 +1,13 @@
+// Package cdbfd passes open databases between processes over unix domain
+// sockets, so a privileged process that builds or fetches databases can hand
+// them to sandboxed readers that can't open the files themselves. The sender
+// passes file descriptors with SCM_RIGHTS, and the receiver reads them with
+// cdb.NewFromFd.
+//
+// On Linux, Seal copies a database into a memfd sealed against changes, and
+// OpenSealed opens one after checking its seals, so processes sharing a
+// database in memory can rely on it never changing under them. Descriptors
+// opened with O_PATH also keep readers from changing the databases.
+//
+// The package is only available on unix systems.
+package cdbfd
@@ -0,0 +1,96 @@
+package cdbfd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/torbit/cdb"
+)
+
+// The syscall package doesn't define memfd_create on every architecture, or
+// the sealing constants.
+var sysMemfdCreate = map[string]uintptr{
+	"386":      356,
+	"amd64":    319,
+	"arm":      385,
+	"arm64":    279,
+	"loong64":  279,
+	"mips":     4354,
+	"mipsle":   4354,
+	"mips64":   5314,
+	"mips64le": 5314,
+	"ppc64":    360,
+	"ppc64le":  360,
+	"riscv64":  279,
+	"s390x":    350,
+}
+
+const (
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+
+	fAddSeals = 1033
+	fGetSeals = 1034
+
+	sealSeal   = 0x1
+	sealShrink = 0x2
+	sealGrow   = 0x4
+	sealWrite  = 0x8
+
+	// sealed are the seals that make a memfd immutable.
+	sealed = sealSeal | sealShrink | sealGrow | sealWrite
+)
+
+// ErrNotSealed is returned by OpenSealed for files that aren't sealed memfds.
+var ErrNotSealed = errors.New("cdbfd: not a sealed memfd")
+
+// Seal copies the database read from r into a new memfd with the given name,
+// which is only for debugging, and seals it so that neither this process nor
+// any it is sent to can change it. Send the file to readers with Send, and
+// open it with OpenSealed.
+func Seal(name string, r io.Reader) (*os.File, error) {
+	nr, ok := sysMemfdCreate[runtime.GOARCH]
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	fd, _, errno := syscall.Syscall(nr, uintptr(unsafe.Pointer(p)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("memfd_create", errno)
+	}
+	f := os.NewFile(fd, "memfd:"+name)
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, sealed); errno != 0 {
+		f.Close()
+		return nil, os.NewSyscallError("fcntl", errno)
+	}
+	return f, nil
+}
+
+// OpenSealed opens the database in a memfd sealed by Seal, checking that it
+// can't be changed, so readers can trust it stays as it was checked. Closing
+// the database doesn't close f.
+func OpenSealed(f *os.File, opts ...cdb.Option) (*cdb.Cdb, error) {
+	seals, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), fGetSeals, 0)
+	if errno == syscall.EINVAL {
+		// Not a memfd.
+		return nil, fmt.Errorf("%w: %s", ErrNotSealed, f.Name())
+	} else if errno != 0 {
+		return nil, os.NewSyscallError("fcntl", errno)
+	}
+	if seals&sealed != sealed {
+		return nil, fmt.Errorf("%w: %s has seals %#x", ErrNotSealed, f.Name(), seals)
+	}
+	return cdb.NewFromFd(f.Fd(), opts...)
+}
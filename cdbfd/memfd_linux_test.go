@@ -0,0 +1,63 @@
+package cdbfd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+func TestSeal(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := cdb.NewWriter(tmp)
+	if err := w.Put([]byte("one"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Seal("test", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt([]byte("x"), 0); err == nil {
+		t.Error("expected writing a sealed memfd to fail")
+	}
+
+	// Send it to another "process", which checks the seals.
+	sender, receiver := socketPair(t)
+	if err := Send(sender, f); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReceiveFile(receiver, "memfd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Close()
+	db, err := OpenSealed(got, cdb.WithMmap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if v, err := db.Bytes([]byte("one")); err != nil || string(v) != "1" {
+		t.Errorf("expected 1, got: %q, %v", v, err)
+	}
+
+	if _, err := OpenSealed(tmp); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("expected ErrNotSealed for a regular file, got: %v", err)
+	}
+}
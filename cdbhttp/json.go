@@ -0,0 +1,141 @@
+// Package cdbhttp serves cdb databases over HTTP.
+package cdbhttp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/torbit/cdb"
+)
+
+// JSONHandler returns a handler that serves values of db that hold JSON
+// objects. A GET of /key returns the first value for key. The fields query
+// parameter, a comma separated list like ?fields=a,b, projects the object down
+// to just those top level fields. Values are streamed from the database rather
+// than buffered in full, and only projected fields are held in memory one at a
+// time. Responses are gzipped if the client accepts it.
+//
+// Use http.StripPrefix to serve it below the root.
+func JSONHandler(db *cdb.Cdb) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		val, err := db.Reader([]byte(key))
+		if errors.Is(err, cdb.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var fields map[string]bool
+		if f := r.URL.Query().Get("fields"); f != "" {
+			fields = make(map[string]bool)
+			for _, name := range strings.Split(f, ",") {
+				fields[name] = true
+			}
+		}
+		dec := json.NewDecoder(bufio.NewReader(val))
+		if fields != nil {
+			// Check that the value is an object before committing to a status.
+			if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+				http.Error(w, "value is not a JSON object", http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Vary", "Accept-Encoding")
+		var out io.Writer = w
+		if acceptsGzip(r) {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			out = gz
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		if fields == nil {
+			io.Copy(out, val)
+			return
+		}
+		project(out, dec, fields)
+	})
+}
+
+// project writes the fields of the object being decoded by dec, whose opening
+// brace has been read, to w. Other fields are skipped without being buffered.
+// Errors can't be reported once the response has started, so it just stops.
+func project(w io.Writer, dec *json.Decoder, fields map[string]bool) {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	bw.WriteByte('{')
+	first := true
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		name, _ := tok.(string)
+		if !fields[name] {
+			if skipValue(dec) != nil {
+				return
+			}
+			continue
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		nameJSON, _ := json.Marshal(name)
+		bw.Write(nameJSON)
+		bw.WriteByte(':')
+		bw.Write(raw)
+	}
+	bw.WriteByte('}')
+}
+
+// skipValue reads past the next value in dec a token at a time.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(enc, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
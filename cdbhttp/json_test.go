@@ -0,0 +1,74 @@
+package cdbhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+func newDB(t *testing.T, kvs ...string) *cdb.Cdb {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := cdb.NewWriter(tmp)
+	for i := 0; i < len(kvs); i += 2 {
+		if err := w.Write([]byte(kvs[i]), []byte(kvs[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cdb.New(bytes.NewReader(b))
+}
+
+func TestJSONHandler(t *testing.T) {
+	h := JSONHandler(newDB(t,
+		"user", `{"name":"ann","big":{"a":[1,2,{"b":"}"}]},"age":3}`,
+		"list", `[1,2]`,
+	))
+	for _, tc := range []struct {
+		url, expected string
+		code          int
+	}{
+		{"/user", `{"name":"ann","big":{"a":[1,2,{"b":"}"}]},"age":3}`, 200},
+		{"/user?fields=age,name", `{"name":"ann","age":3}`, 200},
+		{"/user?fields=missing", `{}`, 200},
+		{"/list?fields=a", "value is not a JSON object\n", http.StatusUnprocessableEntity},
+		{"/nope", "404 page not found\n", 404},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tc.url, nil))
+		if rec.Code != tc.code || rec.Body.String() != tc.expected {
+			t.Errorf("%s: expected %v %s, got: %v %s", tc.url, tc.code, tc.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/user?fields=name", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzipped response")
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != `{"name":"ann"}` {
+		t.Errorf("expected projected body, got: %s", b)
+	}
+}
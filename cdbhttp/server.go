@@ -0,0 +1,163 @@
+package cdbhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+// Server is a handler that serves the values of a database, turning it into a
+// read-only key-value service. A GET of /key returns the first value for key,
+// and the skip query parameter, like ?skip=1, skips that many values of a key
+// with several. Missing keys get a 404. Responses have a Content-Length and
+// an ETag derived from a SHA-256 of the database's records, so clients can
+// revalidate cached values cheaply, and Range requests are supported. The
+// hash is worked out on the first request for each database, which reads all
+// of it.
+//
+// The database can be replaced with Swap while the Server is serving.
+//
+// Use http.StripPrefix to serve it below the root.
+type Server struct {
+	contentType func(key []byte) string
+
+	// mu guards db, which Swap changes.
+	mu sync.RWMutex
+	db *served
+}
+
+// served is a database being served, with its ETag once worked out.
+type served struct {
+	db   *cdb.Cdb
+	once sync.Once
+	etag string
+	// requests counts the requests using db, which Swap waits for.
+	requests sync.WaitGroup
+}
+
+// etagValue returns the ETag of the database's values, or "" if it can't be
+// read, in which case responses go without one. The hash covers every key
+// and value, so a database rebuilt with values of the same lengths gets a
+// new ETag, which cdb.Fingerprint wouldn't give it.
+func (s *served) etagValue() string {
+	s.once.Do(func() {
+		h := sha256.New()
+		if _, err := s.db.WriteTo(h); err == nil {
+			s.etag = `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+		}
+	})
+	return s.etag
+}
+
+// HandlerOption configures a Server.
+type HandlerOption func(*Server)
+
+// ContentType returns a HandlerOption that sets the Content-Type of each
+// value to the one fn returns for its key. TypeByExtension is one such fn.
+// The default is application/octet-stream.
+func ContentType(fn func(key []byte) string) HandlerOption {
+	return func(s *Server) {
+		s.contentType = fn
+	}
+}
+
+// TypeByExtension returns the MIME type of the key's file extension, like
+// text/html for "index.html", or application/octet-stream if it has none.
+func TypeByExtension(key []byte) string {
+	if t := mime.TypeByExtension(path.Ext(string(key))); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// Handler returns a handler serving the values of db. See Server.
+func Handler(db *cdb.Cdb, opts ...HandlerOption) http.Handler {
+	return NewServer(db, opts...)
+}
+
+// NewServer returns a Server for db.
+func NewServer(db *cdb.Cdb, opts ...HandlerOption) *Server {
+	s := &Server{db: &served{db: db}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Swap replaces the database being served, and returns the one it replaced,
+// which the caller should close. New requests use db straight away, and Swap
+// returns once the requests still using the old database have finished.
+func (s *Server) Swap(db *cdb.Cdb) *cdb.Cdb {
+	s.mu.Lock()
+	old := s.db
+	s.db = &served{db: db}
+	s.mu.Unlock()
+	old.requests.Wait()
+	return old.db
+}
+
+// acquire returns the database being served, which the caller has to
+// release with requests.Done once it is finished with it.
+func (s *Server) acquire() *served {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.db.requests.Add(1)
+	return s.db
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	skip := 0
+	if v := r.URL.Query().Get("skip"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "bad skip parameter", http.StatusBadRequest)
+			return
+		}
+		skip = n
+	}
+
+	sv := s.acquire()
+	defer sv.requests.Done()
+	key := []byte(strings.TrimPrefix(r.URL.Path, "/"))
+	iter := sv.db.Iterate(key)
+	for i := 0; ; i++ {
+		val, err := iter.NextReader()
+		if errors.Is(err, cdb.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if i < skip {
+			continue
+		}
+		// Keys aren't file names, so ServeContent mustn't guess the type
+		// from them.
+		contentType := "application/octet-stream"
+		if s.contentType != nil {
+			contentType = s.contentType(key)
+		}
+		w.Header().Set("Content-Type", contentType)
+		if etag := sv.etagValue(); etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		http.ServeContent(w, r, "", time.Time{}, val)
+		return
+	}
+}
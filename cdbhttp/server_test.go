@@ -0,0 +1,151 @@
+package cdbhttp
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer(newDB(t, "one", "1", "two", "2", "two", "22"))
+	get := func(url string) (int, string) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		return rec.Code, rec.Body.String()
+	}
+	for _, tc := range []struct {
+		url, expected string
+		code          int
+	}{
+		{"/one", "1", 200},
+		{"/two", "2", 200},
+		{"/two?skip=1", "22", 200},
+		{"/two?skip=2", "404 page not found\n", 404},
+		{"/two?skip=x", "bad skip parameter\n", 400},
+		{"/three", "404 page not found\n", 404},
+	} {
+		if code, body := get(tc.url); code != tc.code || body != tc.expected {
+			t.Errorf("%s: expected %v %q, got: %v %q", tc.url, tc.code, tc.expected, code, body)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/two?skip=1", nil)
+	req.Header.Set("Range", "bytes=1-")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != 206 || rec.Body.String() != "2" {
+		t.Errorf("expected a partial response, got: %v %q", rec.Code, rec.Body.String())
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	req = httptest.NewRequest("GET", "/one", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != 304 {
+		t.Errorf("expected 304 for a matching ETag, got: %v", rec.Code)
+	}
+
+	old := s.Swap(newDB(t, "one", "new"))
+	old.Close()
+	if code, body := get("/one"); code != 200 || body != "new" {
+		t.Errorf("expected the swapped database to be served, got: %v %q", code, body)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/one", nil))
+	if rec.Header().Get("ETag") == etag {
+		t.Errorf("expected the ETag to change with the database")
+	}
+}
+
+func TestServerETagSameLengths(t *testing.T) {
+	// A rebuild with the same keys and value lengths has the same
+	// fingerprint, but not the same values.
+	etag := func(db *cdb.Cdb) string {
+		rec := httptest.NewRecorder()
+		NewServer(db).ServeHTTP(rec, httptest.NewRequest("GET", "/one", nil))
+		return rec.Header().Get("ETag")
+	}
+	before, after := etag(newDB(t, "one", "old")), etag(newDB(t, "one", "new"))
+	if before == "" || before == after {
+		t.Errorf("expected different ETags for different values, got: %s and %s", before, after)
+	}
+}
+
+func TestHandlerContentType(t *testing.T) {
+	h := Handler(newDB(t, "index.html", "<h1>hi</h1>", "blob", "x"), ContentType(TypeByExtension))
+	for key, want := range map[string]string{
+		"/index.html": "text/html; charset=utf-8",
+		"/blob":       "application/octet-stream",
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", key, nil))
+		if got := rec.Header().Get("Content-Type"); got != want {
+			t.Errorf("%s: expected %s, got: %s", key, want, got)
+		}
+		if rec.Header().Get("Content-Length") == "" {
+			t.Errorf("%s: expected a Content-Length", key)
+		}
+	}
+}
+
+// slowWriter is a response writer for a client that doesn't read until
+// unblock is closed.
+type slowWriter struct {
+	*httptest.ResponseRecorder
+	writing chan struct{}
+	unblock chan struct{}
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	close(w.writing)
+	<-w.unblock
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestServerSwapSlowClient(t *testing.T) {
+	s := NewServer(newDB(t, "one", "1"))
+	slow := &slowWriter{httptest.NewRecorder(), make(chan struct{}), make(chan struct{})}
+	served := make(chan struct{})
+	go func() {
+		s.ServeHTTP(slow, httptest.NewRequest("GET", "/one", nil))
+		close(served)
+	}()
+	<-slow.writing
+
+	swapped := make(chan struct{})
+	go func() {
+		s.Swap(newDB(t, "one", "new")).Close()
+		close(swapped)
+	}()
+	// New requests aren't held up by the slow one, but the old database
+	// isn't given back until it's done with.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/one", nil))
+		if rec.Body.String() == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the swapped database was never served")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-swapped:
+		t.Fatal("Swap returned while a request was using the old database")
+	default:
+	}
+	close(slow.unblock)
+	<-served
+	<-swapped
+	if slow.Body.String() != "1" {
+		t.Errorf("expected the slow client to get the old value, got: %q", slow.Body.String())
+	}
+}
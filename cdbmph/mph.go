@@ -0,0 +1,314 @@
+// Package cdbmph builds a minimal perfect hash index of the keys in a cdb, so
+// lookups need a single ReadAt.
+//
+// A lookup in a cdb reads the hash table pointer, then one or more hash slots,
+// then the record. An Index is kept in memory instead, using about 8 bytes per
+// key for the record locations plus around 6 bits per key for the hash
+// function, and maps each key straight to its first record, which is read and
+// checked in one go. This matters most when reads are expensive, like on spinning
+// disks or remote storage.
+package cdbmph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/torbit/cdb"
+)
+
+// keysPerBucket is the average number of keys hashed to each bucket. Fewer
+// keys per bucket make building faster but the index bigger.
+const keysPerBucket = 5
+
+// directSlot marks a bucket's seed as holding the slot of its only key
+// directly, rather than a seed for the slot hash.
+const directSlot = 1 << 31
+
+const magic = "cdbmph01"
+
+// ErrBadIndex is returned when reading an index that isn't valid.
+var ErrBadIndex = errors.New("bad mph index")
+
+// ErrHashCollision is returned by Build when two keys have the same 64 bit
+// hash, so no seed can tell them apart.
+var ErrHashCollision = errors.New("cdbmph: two keys have the same hash")
+
+// ErrWideDatabase is returned by Build for databases in the 64 bit layout,
+// whose record positions don't fit in an index.
+var ErrWideDatabase = errors.New("cdbmph: 64 bit databases can't be indexed")
+
+// Index maps each distinct key of a cdb to the position of its first record.
+//
+// Threadsafe.
+type Index struct {
+	// seeds holds the seed for the slot hash of each bucket.
+	seeds []uint32
+	// pos and dlen hold the record position and data length for each slot.
+	pos, dlen []uint32
+}
+
+// Build returns an index of db. Only the first value of each key is indexed.
+// All keys are held in memory while building. Db shouldn't have a
+// ValueTransform, since the index points at the raw records. The index holds
+// 32 bit record positions, so databases in the 64 bit layout of the Cdb64
+// WriterOption fail with ErrWideDatabase.
+func Build(db *cdb.Cdb) (*Index, error) {
+	format, err := db.Format()
+	if err != nil {
+		return nil, err
+	}
+	if format.Wide {
+		return nil, ErrWideDatabase
+	}
+	var entries []entry
+	err = db.ForEachReader(func(keyReader, valReader *io.SectionReader) error {
+		key := make([]byte, keyReader.Size())
+		if _, err := io.ReadFull(keyReader, key); err != nil {
+			return err
+		}
+		_, off, _ := keyReader.Outer()
+		pos := uint32(off - 8) // Classic databases are under 4GB.
+		// Only index the first record of each key.
+		iter := db.Iterate(key)
+		if _, err := iter.NextReader(); err != nil {
+			return err
+		}
+		if iter.RecordPos() != uint64(pos) {
+			return nil
+		}
+		entries = append(entries, entry{hashKey(key), pos, uint32(valReader.Size())})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newIndex(entries)
+}
+
+// entry is a key to be indexed, by its hash, with the position and data
+// length of its first record.
+type entry struct {
+	h         uint64
+	pos, dlen uint32
+}
+
+// newIndex returns an index of the entries.
+func newIndex(entries []entry) (*Index, error) {
+	n := len(entries)
+	nbuckets := (n + keysPerBucket - 1) / keysPerBucket
+	if nbuckets == 0 {
+		nbuckets = 1
+	}
+	x := &Index{
+		seeds: make([]uint32, nbuckets),
+		pos:   make([]uint32, n),
+		dlen:  make([]uint32, n),
+	}
+	buckets := make([][]int, nbuckets)
+	for i, e := range entries {
+		b := bucket(e.h, nbuckets)
+		buckets[b] = append(buckets[b], i)
+	}
+	// Place the biggest buckets first, while there are lots of free slots.
+	order := make([]int, nbuckets)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(buckets[order[i]]) > len(buckets[order[j]]) })
+
+	used := make([]bool, n)
+	nextFree := 0
+	slots := make([]int, 0, keysPerBucket*2)
+	for _, b := range order {
+		keys := buckets[b]
+		if len(keys) == 0 {
+			break
+		}
+		if len(keys) == 1 {
+			// Any free slot will do, so store it directly.
+			for used[nextFree] {
+				nextFree++
+			}
+			used[nextFree] = true
+			x.seeds[b] = directSlot | uint32(nextFree)
+			x.place(nextFree, entries[keys[0]].pos, entries[keys[0]].dlen)
+			continue
+		}
+		// Keys with the same hash land in the same slot whatever the seed.
+		for i, k := range keys {
+			for _, other := range keys[:i] {
+				if entries[k].h == entries[other].h {
+					return nil, ErrHashCollision
+				}
+			}
+		}
+		for seed := uint32(1); ; seed++ {
+			if seed == directSlot {
+				return nil, fmt.Errorf("cdbmph: can't place bucket of %d keys", len(keys))
+			}
+			slots = slots[:0]
+			for _, k := range keys {
+				s := slot(entries[k].h, seed, n)
+				if used[s] || contains(slots, s) {
+					break
+				}
+				slots = append(slots, s)
+			}
+			if len(slots) < len(keys) {
+				continue
+			}
+			for i, s := range slots {
+				used[s] = true
+				x.place(s, entries[keys[i]].pos, entries[keys[i]].dlen)
+			}
+			x.seeds[b] = seed
+			break
+		}
+	}
+	return x, nil
+}
+
+func (x *Index) place(s int, pos, dlen uint32) {
+	x.pos[s], x.dlen[s] = pos, dlen
+}
+
+// Len returns the number of keys in the index.
+func (x *Index) Len() int {
+	return len(x.pos)
+}
+
+// Lookup returns the first value for key, reading it from r, which must read
+// the database the index was built from. Returns cdb.ErrNotFound if there is
+// no value.
+func (x *Index) Lookup(r io.ReaderAt, key []byte) ([]byte, error) {
+	n := len(x.pos)
+	if n == 0 {
+		return nil, cdb.ErrNotFound
+	}
+	h := hashKey(key)
+	var s int
+	if seed := x.seeds[bucket(h, len(x.seeds))]; seed&directSlot != 0 {
+		s = int(seed &^ directSlot)
+	} else {
+		s = slot(h, seed, n)
+	}
+	// The slot always holds some record, which is only ours if the key
+	// matches.
+	klen, dlen := uint32(len(key)), x.dlen[s]
+	buf := make([]byte, 8+int64(klen)+int64(dlen))
+	if _, err := r.ReadAt(buf, int64(x.pos[s])); err != nil && err != io.EOF {
+		return nil, err
+	} else if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if binary.LittleEndian.Uint32(buf) != klen || binary.LittleEndian.Uint32(buf[4:]) != dlen || !bytes.Equal(buf[8:8+klen], key) {
+		return nil, cdb.ErrNotFound
+	}
+	return buf[8+klen:], nil
+}
+
+// WriteTo writes the index to w, to be read back with ReadIndex.
+func (x *Index) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(magic)
+	var buf [4]byte
+	put := func(v uint32) {
+		binary.LittleEndian.PutUint32(buf[:], v)
+		bw.Write(buf[:])
+	}
+	put(uint32(len(x.seeds)))
+	put(uint32(len(x.pos)))
+	for _, v := range x.seeds {
+		put(v)
+	}
+	for s := range x.pos {
+		put(x.pos[s])
+		put(x.dlen[s])
+	}
+	size := int64(len(magic) + 8 + 4*len(x.seeds) + 8*len(x.pos))
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// ReadIndex reads an index written by Index.WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, len(magic)+8)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, err
+	}
+	if string(head[:len(magic)]) != magic {
+		return nil, ErrBadIndex
+	}
+	nbuckets := binary.LittleEndian.Uint32(head[len(magic):])
+	n := binary.LittleEndian.Uint32(head[len(magic)+4:])
+	if nbuckets == 0 || uint64(nbuckets) > uint64(n)+1 {
+		return nil, ErrBadIndex
+	}
+	body := make([]byte, 4*int64(nbuckets)+8*int64(n))
+	if _, err := io.ReadFull(br, body); err != nil {
+		return nil, err
+	}
+	x := &Index{
+		seeds: make([]uint32, nbuckets),
+		pos:   make([]uint32, n),
+		dlen:  make([]uint32, n),
+	}
+	for i := range x.seeds {
+		x.seeds[i] = binary.LittleEndian.Uint32(body[4*i:])
+		if x.seeds[i]&directSlot != 0 && x.seeds[i]&^directSlot >= n {
+			return nil, ErrBadIndex
+		}
+	}
+	body = body[4*nbuckets:]
+	for s := range x.pos {
+		x.pos[s] = binary.LittleEndian.Uint32(body[8*s:])
+		x.dlen[s] = binary.LittleEndian.Uint32(body[8*s+4:])
+	}
+	return x, nil
+}
+
+// hashKey returns the 64 bit FNV-1a hash of key.
+func hashKey(key []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range key {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+// mix is the splitmix64 finalizer, used to derive independent hashes from a
+// key's hash and a seed.
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+func bucket(h uint64, nbuckets int) int {
+	return int(mix(h) % uint64(nbuckets))
+}
+
+func slot(h uint64, seed uint32, n int) int {
+	return int(mix(h^uint64(seed)*0x9e3779b97f4a7c15) % uint64(n))
+}
+
+func contains(slots []int, s int) bool {
+	for _, t := range slots {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
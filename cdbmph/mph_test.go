@@ -0,0 +1,83 @@
+package cdbmph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+func TestIndex(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := cdb.NewWriter(tmp)
+	const n = 5000
+	for i := 0; i < n; i++ {
+		w.Write([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("val%d", i)))
+	}
+	// Only the first value of a key is indexed.
+	w.Write([]byte("key7"), []byte("second"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(b)
+
+	x, err := Build(cdb.New(r))
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	if x.Len() != n {
+		t.Errorf("expected %v keys, got: %v", n, x.Len())
+	}
+	var buf bytes.Buffer
+	if _, err := x.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if x, err = ReadIndex(&buf); err != nil {
+		t.Fatalf("ReadIndex error: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		val, err := x.Lookup(r, []byte(fmt.Sprintf("key%d", i)))
+		if err != nil {
+			t.Fatalf("key%d: Lookup error: %v", i, err)
+		}
+		if expected := fmt.Sprintf("val%d", i); string(val) != expected {
+			t.Errorf("key%d: expected %s, got: %s", i, expected, val)
+		}
+	}
+	if _, err := x.Lookup(r, []byte("missing")); err != cdb.ErrNotFound {
+		t.Errorf("expected ErrNotFound for a missing key, got: %v", err)
+	}
+}
+
+func TestBuildWide(t *testing.T) {
+	w := cdb.NewBufferWriter(cdb.Cdb64())
+	w.Write([]byte("key"), []byte("val"))
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Build(cdb.New(bytes.NewReader(b))); !errors.Is(err, ErrWideDatabase) {
+		t.Errorf("expected ErrWideDatabase, got: %v", err)
+	}
+}
+
+func TestBuildHashCollision(t *testing.T) {
+	// Two keys with the same hash in a bucket with others fail straight
+	// away, rather than after trying every seed.
+	entries := []entry{{h: 1, pos: 2048}, {h: 2, pos: 2060}, {h: 1, pos: 2072}}
+	if _, err := newIndex(entries); err != ErrHashCollision {
+		t.Errorf("expected ErrHashCollision, got: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+// Package cdbprom exports cdb metrics to Prometheus.
+package cdbprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/torbit/cdb"
+)
+
+// Collector is a prometheus.Collector for the lookup latency of a Cdb opened
+// with cdb.RecordLatency.
+type Collector struct {
+	db   *cdb.Cdb
+	desc *prometheus.Desc
+}
+
+// NewCollector returns a Collector for db. The labels distinguish it from
+// the collectors of other databases.
+func NewCollector(db *cdb.Cdb, labels prometheus.Labels) *Collector {
+	return &Collector{
+		db: db,
+		desc: prometheus.NewDesc("cdb_lookup_duration_seconds",
+			"Latency of cdb lookups.", nil, labels),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.LatencyStats()
+	buckets := make(map[float64]uint64, len(stats.Buckets))
+	var cumulative uint64
+	for i, b := range stats.Buckets {
+		cumulative += stats.Counts[i]
+		buckets[b.Seconds()] = cumulative
+	}
+	ch <- prometheus.MustNewConstHistogram(c.desc, stats.Count, stats.Sum.Seconds(), buckets)
+}
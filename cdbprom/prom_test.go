@@ -0,0 +1,32 @@
+package cdbprom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/torbit/cdb"
+)
+
+func TestCollector(t *testing.T) {
+	// An empty database, which has no keys.
+	db := cdb.New(bytes.NewReader(make([]byte, 2048)), cdb.RecordLatency())
+	for i := 0; i < 3; i++ {
+		db.Exists([]byte("key"))
+	}
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(NewCollector(db, prometheus.Labels{"db": "test"})); err != nil {
+		t.Fatal(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather error: %v", err)
+	}
+	if len(families) != 1 || families[0].GetName() != "cdb_lookup_duration_seconds" {
+		t.Fatalf("expected one cdb_lookup_duration_seconds family, got: %v", families)
+	}
+	h := families[0].GetMetric()[0].GetHistogram()
+	if h.GetSampleCount() != 3 || len(h.GetBucket()) != len(cdb.DefaultLatencyBuckets) {
+		t.Errorf("expected 3 samples in %d buckets, got: %v", len(cdb.DefaultLatencyBuckets), h)
+	}
+}
@@ -0,0 +1,257 @@
+// Package cdbremote reads databases over HTTP with Range requests, so a
+// database stored on a CDN or object store can be queried without downloading
+// the whole file.
+//
+// Open keeps the database's header in memory, so once it is loaded each
+// lookup costs two or three requests: one for the key's hash slots, one for
+// the record's header and key, and one for its value.
+package cdbremote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+// ErrNoRanges is returned when the server answers a Range request with the
+// whole file.
+var ErrNoRanges = errors.New("cdbremote: server doesn't support range requests")
+
+// StatusError is returned for responses with an unexpected status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("cdbremote: %s: %s", e.URL, e.Status)
+}
+
+// temporary returns true for statuses worth retrying.
+func (e *StatusError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// ReaderAt is an io.ReaderAt that reads a file over HTTP with Range GETs.
+//
+// Threadsafe.
+type ReaderAt struct {
+	url       string
+	client    *http.Client
+	header    http.Header
+	chunkSize int
+	retries   int
+	backoff   time.Duration
+	// size is the size of the file plus one, or 0 until it is known.
+	size int64
+}
+
+// Option configures a ReaderAt.
+type Option func(*ReaderAt)
+
+// Client returns an Option that sends requests with c instead of
+// http.DefaultClient.
+func Client(c *http.Client) Option {
+	return func(r *ReaderAt) {
+		r.client = c
+	}
+}
+
+// Header returns an Option that adds a header to every request, such as one
+// carrying credentials.
+func Header(key, value string) Option {
+	return func(r *ReaderAt) {
+		r.header.Add(key, value)
+	}
+}
+
+// ChunkSize returns an Option that splits reads bigger than n bytes into
+// requests of at most n bytes, each retried on its own. The default is 1MB.
+func ChunkSize(n int) Option {
+	return func(r *ReaderAt) {
+		r.chunkSize = n
+	}
+}
+
+// Retries returns an Option that retries failed requests up to n times,
+// waiting backoff before the first retry and twice as long before each one
+// after. Network errors, 429s and 5xx statuses are retried. The default is 3
+// retries starting at 100ms.
+func Retries(n int, backoff time.Duration) Option {
+	return func(r *ReaderAt) {
+		r.retries = n
+		r.backoff = backoff
+	}
+}
+
+// NewReaderAt returns a ReaderAt reading the file at url.
+func NewReaderAt(url string, opts ...Option) *ReaderAt {
+	r := &ReaderAt{
+		url:       url,
+		client:    http.DefaultClient,
+		header:    make(http.Header),
+		chunkSize: 1 << 20,
+		retries:   3,
+		backoff:   100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Open returns a database reading the file at url, keeping its header in
+// memory. It checks that the file exists first. Use NewReaderAt with cdb.New
+// to pass other cdb Options.
+func Open(url string, opts ...Option) (*cdb.Cdb, error) {
+	r := NewReaderAt(url, opts...)
+	if _, err := r.Size(); err != nil {
+		return nil, err
+	}
+	return cdb.New(r, cdb.WithPreloadedHeader()), nil
+}
+
+// Size returns the size of the file, asking the server for it with a HEAD
+// request if no response has given it yet.
+func (r *ReaderAt) Size() (int64, error) {
+	if size := atomic.LoadInt64(&r.size); size > 0 {
+		return size - 1, nil
+	}
+	err := r.retry(func() error {
+		resp, err := r.do(http.MethodHead, "")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return r.statusError(resp)
+		}
+		if resp.ContentLength < 0 {
+			return fmt.Errorf("cdbremote: %s: no Content-Length", r.url)
+		}
+		r.setSize(resp.ContentLength)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return atomic.LoadInt64(&r.size) - 1, nil
+}
+
+func (r *ReaderAt) setSize(size int64) {
+	atomic.StoreInt64(&r.size, size+1)
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("cdbremote: negative offset")
+	}
+	n := 0
+	for n < len(p) {
+		chunk := p[n:]
+		if len(chunk) > r.chunkSize {
+			chunk = chunk[:r.chunkSize]
+		}
+		var m int
+		err := r.retry(func() (err error) {
+			m, err = r.fetch(chunk, off+int64(n))
+			return err
+		})
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if m < len(chunk) {
+			return n, io.EOF
+		}
+	}
+	return n, nil
+}
+
+// fetch reads the range of the file starting at off into buf, in one request.
+// Reading less than len(buf) means the file ends.
+func (r *ReaderAt) fetch(buf []byte, off int64) (int, error) {
+	resp, err := r.do(http.MethodGet, fmt.Sprintf("bytes=%d-%d", off, off+int64(len(buf))-1))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		if size, ok := contentRangeSize(resp.Header.Get("Content-Range")); ok {
+			r.setSize(size)
+		}
+		return 0, nil
+	case http.StatusOK:
+		return 0, ErrNoRanges
+	default:
+		return 0, r.statusError(resp)
+	}
+	if size, ok := contentRangeSize(resp.Header.Get("Content-Range")); ok {
+		r.setSize(size)
+	}
+	want := len(buf)
+	if resp.ContentLength >= 0 && resp.ContentLength < int64(want) {
+		// The range was cut short by the end of the file.
+		want = int(resp.ContentLength)
+	}
+	n, err := io.ReadFull(resp.Body, buf[:want])
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// do sends a request for the file, with a Range header if rng isn't empty.
+func (r *ReaderAt) do(method, rng string) (*http.Response, error) {
+	req, err := http.NewRequest(method, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	return r.client.Do(req)
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't worth
+// retrying, or runs out of retries.
+func (r *ReaderAt) retry(fn func() error) error {
+	wait := r.backoff
+	for i := 0; ; i++ {
+		err := fn()
+		var se *StatusError
+		if err == nil || err == ErrNoRanges || (errors.As(err, &se) && !se.temporary()) || i == r.retries {
+			return err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (r *ReaderAt) statusError(resp *http.Response) error {
+	return &StatusError{URL: r.url, StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// contentRangeSize returns the size of the file from a Content-Range header,
+// like "bytes 0-99/1234" or "bytes */1234".
+func contentRangeSize(s string) (int64, bool) {
+	i := strings.LastIndexByte(s, '/')
+	if i < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(s[i+1:], 10, 64)
+	return size, err == nil
+}
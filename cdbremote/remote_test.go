@@ -0,0 +1,121 @@
+package cdbremote
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+// serve serves data with support for Range requests, failing the first fail
+// requests with a 503, and counting the requests in *count.
+func serve(t *testing.T, data []byte, fail int32, count *int32) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(count, 1) <= fail {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "db.cdb", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestReaderAt(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789", 100))
+	var count int32
+	s := serve(t, data, 2, &count)
+	r := NewReaderAt(s.URL, ChunkSize(64), Retries(2, time.Millisecond))
+
+	buf := make([]byte, 200)
+	if n, err := r.ReadAt(buf, 105); n != len(buf) || err != nil {
+		t.Fatalf("expected %d bytes, got: %d, %v", len(buf), n, err)
+	}
+	if !bytes.Equal(buf, data[105:305]) {
+		t.Errorf("expected %q, got: %q", data[105:305], buf)
+	}
+	// Two failures, then one request per 64 byte chunk.
+	if count != 2+4 {
+		t.Errorf("expected 6 requests, got %d", count)
+	}
+	if size, err := r.Size(); size != int64(len(data)) || err != nil {
+		t.Errorf("expected size %d, got: %d, %v", len(data), size, err)
+	}
+
+	n, err := r.ReadAt(buf, 900)
+	if n != 100 || err != io.EOF || !bytes.Equal(buf[:n], data[900:]) {
+		t.Errorf("expected the last 100 bytes and io.EOF, got: %d, %v", n, err)
+	}
+	if n, err := r.ReadAt(buf, 1000); n != 0 || err != io.EOF {
+		t.Errorf("expected io.EOF, got: %d, %v", n, err)
+	}
+}
+
+func TestReaderAtErrors(t *testing.T) {
+	var count int32
+	s := serve(t, []byte("data"), 100, &count)
+	r := NewReaderAt(s.URL, Retries(2, time.Millisecond))
+	var se *StatusError
+	if _, err := r.ReadAt(make([]byte, 2), 0); !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected a 503, got: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 tries, got %d", count)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	if _, err := Open(missing.URL); !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404, got: %v", err)
+	}
+
+	whole := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data"))
+	}))
+	defer whole.Close()
+	if _, err := NewReaderAt(whole.URL).ReadAt(make([]byte, 2), 0); err != ErrNoRanges {
+		t.Errorf("expected ErrNoRanges, got: %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	w := cdb.NewBufferWriter()
+	for _, key := range []string{"one", "two", "three"} {
+		if err := w.Put([]byte(key), []byte(strings.ToUpper(key))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int32
+	s := serve(t, data, 0, &count)
+	db, err := Open(s.URL, Header("Authorization", "Bearer token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, err := db.Bytes([]byte("two")); string(val) != "TWO" || err != nil {
+		t.Fatalf("expected TWO, got: %q, %v", val, err)
+	}
+
+	for _, key := range []string{"one", "three"} {
+		before := atomic.LoadInt32(&count)
+		if val, err := db.Bytes([]byte(key)); string(val) != strings.ToUpper(key) || err != nil {
+			t.Errorf("expected %s, got: %q, %v", strings.ToUpper(key), val, err)
+		}
+		if n := atomic.LoadInt32(&count) - before; n > 3 {
+			t.Errorf("%s: expected at most 3 requests, got %d", key, n)
+		}
+	}
+	if _, err := db.Bytes([]byte("four")); err != cdb.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package cdbsql is a read-only database/sql driver for cdb databases, so
+// tools that speak SQL can query them. It registers as "cdb", with the file
+// name as the data source name:
+//
+//	db, err := sql.Open("cdb", "/path/to/db.cdb")
+//
+// A database is a table with key and value columns, both bytes, under any
+// name. Only lookups and full scans are supported:
+//
+//	SELECT value FROM t WHERE key = ?
+//	SELECT key, value FROM t
+//
+// The columns can be key, value or *, in any order. A key with several values
+// gives a row for each. Scans return the values of each key together, in the
+// order the keys were first written.
+package cdbsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/torbit/cdb"
+)
+
+func init() {
+	sql.Register("cdb", &Driver{})
+}
+
+// ErrReadOnly is returned for statements that would change the database.
+var ErrReadOnly = errors.New("cdbsql: databases are read-only")
+
+// Driver opens cdb databases for database/sql.
+type Driver struct{}
+
+// Open opens the database in the named file.
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	db, err := cdb.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{db: db}, nil
+}
+
+type conn struct {
+	db *cdb.Cdb
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	q, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{db: c.db, q: q}, nil
+}
+
+func (c *conn) Close() error {
+	return c.db.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, ErrReadOnly
+}
+
+// query is a parsed SELECT statement.
+type query struct {
+	// columns are the names of the columns selected, in order.
+	columns []string
+	// where is true for lookups of one key, which is key, or the statement's
+	// argument if placeholder is true.
+	where       bool
+	key         []byte
+	placeholder bool
+}
+
+var selectRE = regexp.MustCompile(`(?is)^\s*SELECT\s+(.+?)\s+FROM\s+("?)\w+("?)(?:\s+WHERE\s+key\s*=\s*(\?|'(?:[^']|'')*'))?\s*;?\s*$`)
+
+// parse parses the statements the driver supports.
+func parse(s string) (*query, error) {
+	m := selectRE.FindStringSubmatch(s)
+	if m == nil || m[2] != m[3] {
+		if fields := strings.Fields(s); len(fields) > 0 && !strings.EqualFold(fields[0], "select") {
+			return nil, ErrReadOnly
+		}
+		return nil, fmt.Errorf("cdbsql: unsupported query %q", s)
+	}
+	q := new(query)
+	for _, col := range strings.Split(m[1], ",") {
+		switch col = strings.ToLower(strings.TrimSpace(col)); col {
+		case "*":
+			q.columns = append(q.columns, "key", "value")
+		case "key", "value":
+			q.columns = append(q.columns, col)
+		default:
+			return nil, fmt.Errorf("cdbsql: unknown column %q", col)
+		}
+	}
+	switch lit := m[4]; {
+	case lit == "?":
+		q.where, q.placeholder = true, true
+	case lit != "":
+		q.where = true
+		q.key = []byte(strings.ReplaceAll(lit[1:len(lit)-1], "''", "'"))
+	}
+	return q, nil
+}
+
+type stmt struct {
+	db *cdb.Cdb
+	q  *query
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	if s.q.placeholder {
+		return 1
+	}
+	return 0
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, ErrReadOnly
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	r := &rows{db: s.db, columns: s.q.columns}
+	if !s.q.where {
+		r.scan = true
+		return r, nil
+	}
+	key := s.q.key
+	if s.q.placeholder {
+		switch arg := args[0].(type) {
+		case []byte:
+			key = arg
+		case string:
+			key = []byte(arg)
+		default:
+			return nil, fmt.Errorf("cdbsql: key must be a string or []byte, not %T", arg)
+		}
+	}
+	r.keys = [][]byte{key}
+	return r, nil
+}
+
+// rows returns the records of the keys in keys, and of the rest of the
+// database too if scan is true, listing them a page at a time.
+type rows struct {
+	db      *cdb.Cdb
+	columns []string
+	scan    bool
+	token   []byte
+	keys    [][]byte
+	key     []byte
+	vals    [][]byte
+}
+
+// scanPage is the number of keys a scan lists at a time.
+const scanPage = 64
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	r.scan, r.keys, r.vals = false, nil, nil
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	for len(r.vals) == 0 {
+		if len(r.keys) == 0 {
+			if !r.scan {
+				return io.EOF
+			}
+			var err error
+			if r.keys, r.token, err = r.db.ListKeys(r.token, scanPage); err != nil {
+				return err
+			}
+			r.scan = r.token != nil
+			continue
+		}
+		r.key, r.keys = r.keys[0], r.keys[1:]
+		vals, err := r.db.AllValues(r.key)
+		if err != nil {
+			return err
+		}
+		r.vals = vals
+	}
+	for i, col := range r.columns {
+		if col == "key" {
+			dest[i] = r.key
+		} else {
+			dest[i] = r.vals[0]
+		}
+	}
+	r.vals = r.vals[1:]
+	return nil
+}
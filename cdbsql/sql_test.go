@@ -0,0 +1,96 @@
+package cdbsql
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+func newDB(t *testing.T) *sql.DB {
+	name := filepath.Join(t.TempDir(), "test.cdb")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := cdb.Make(f, strings.NewReader("+3,1:one->1\n+3,1:two->2\n+5,1:three->3\n+3,2:two->22\n\n")); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("cdb", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLookup(t *testing.T) {
+	db := newDB(t)
+	var val string
+	if err := db.QueryRow("SELECT value FROM t WHERE key = ?", "one").Scan(&val); err != nil || val != "1" {
+		t.Errorf("expected 1, got: %q, %v", val, err)
+	}
+	if err := db.QueryRow("select value from t where key = 'three';").Scan(&val); err != nil || val != "3" {
+		t.Errorf("expected 3, got: %q, %v", val, err)
+	}
+	if err := db.QueryRow("SELECT value FROM t WHERE key = ?", "four").Scan(&val); err != sql.ErrNoRows {
+		t.Errorf("expected no rows, got: %v", err)
+	}
+
+	rows, err := db.Query("SELECT value, key FROM t WHERE key = ?", []byte("two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for rows.Next() {
+		var key, val string
+		if err := rows.Scan(&val, &key); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, key+"="+val)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "two=2 two=22" {
+		t.Errorf("expected two=2 two=22, got: %v", got)
+	}
+}
+
+func TestScan(t *testing.T) {
+	rows, err := newDB(t).Query("SELECT * FROM records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var key, val []byte
+		if err := rows.Scan(&key, &val); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(key)+"="+string(val))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "one=1 two=2 two=22 three=3" {
+		t.Errorf("expected every record, got: %v", got)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	db := newDB(t)
+	if _, err := db.Exec("DELETE FROM t"); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly, got: %v", err)
+	}
+	for _, q := range []string{"SELECT size FROM t", "SELECT value FROM t WHERE value = ?"} {
+		if _, err := db.Query(q, "x"); err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cdbtest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/torbit/cdb"
+)
+
+// CompatMismatch is a lookup that gave different values in the two databases
+// compared by CompatCheck.
+type CompatMismatch struct {
+	// Key is the key looked up.
+	Key []byte
+	// File is "external" for a lookup in the externally built database, and
+	// "built" for one in the database built by this package.
+	File string
+	// Want holds the values of the key in the other database, and Got the
+	// values the lookup found.
+	Want, Got [][]byte
+}
+
+// CompatCheck checks that this package and another cdb implementation agree,
+// to find interop bugs such as a different key hash. It builds a database
+// from records, in the cdbmake format read by cdb.Make, and looks up every key
+// of it in external, a database built from the same records by the other
+// implementation. Then it does the reverse, looking up every key of external
+// in the database it built. It returns the lookups that disagreed, in the
+// order of the records.
+func CompatCheck(records io.Reader, external io.ReaderAt) ([]CompatMismatch, error) {
+	tmp, err := ioutil.TempFile("", "cdbcompat")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := cdb.Make(tmp, records); err != nil {
+		return nil, err
+	}
+	built, ext := cdb.New(tmp), cdb.New(external)
+	mismatches, err := compareLookups(built, ext, "external")
+	if err != nil {
+		return nil, err
+	}
+	reverse, err := compareLookups(ext, built, "built")
+	if err != nil {
+		return nil, err
+	}
+	return append(mismatches, reverse...), nil
+}
+
+// compareLookups looks up every key of from in to, and returns the keys
+// whose values differ.
+func compareLookups(from, to *cdb.Cdb, file string) ([]CompatMismatch, error) {
+	var mismatches []CompatMismatch
+	seen := make(map[string]bool)
+	err := from.ForEachBytes(func(key, val []byte) error {
+		if seen[string(key)] {
+			return nil
+		}
+		seen[string(key)] = true
+		want, err := from.AllValues(key)
+		if err != nil {
+			return err
+		}
+		got, err := to.AllValues(key)
+		if err != nil {
+			return err
+		}
+		if !equalValues(want, got) {
+			mismatches = append(mismatches, CompatMismatch{append([]byte(nil), key...), file, want, got})
+		}
+		return nil
+	})
+	return mismatches, err
+}
+
+func equalValues(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
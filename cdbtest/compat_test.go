@@ -0,0 +1,64 @@
+package cdbtest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+const compatRecords = "+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n\n"
+
+func buildExternal(t *testing.T, opts ...cdb.WriterOption) []byte {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := cdb.NewWriter(tmp, opts...)
+	for _, kv := range [][2]string{{"one", "1"}, {"two", "2"}, {"two", "22"}} {
+		if err := w.Write([]byte(kv[0]), []byte(kv[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCompatCheck(t *testing.T) {
+	ext := buildExternal(t)
+	mismatches, err := CompatCheck(strings.NewReader(compatRecords), bytes.NewReader(ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 0 {
+		t.Errorf("expected no mismatches, got: %v", mismatches)
+	}
+
+	// An implementation with a different hash can't find any keys, and its
+	// records aren't found either.
+	ext = buildExternal(t, cdb.Hash(func(key []byte) uint32 { return uint32(len(key)) * 7919 }))
+	mismatches, err = CompatCheck(strings.NewReader(compatRecords), bytes.NewReader(ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 4 {
+		t.Fatalf("expected 4 mismatches, got: %v", mismatches)
+	}
+	if m := mismatches[1]; string(m.Key) != "two" || m.File != "external" || len(m.Want) != 2 || len(m.Got) != 0 {
+		t.Errorf("expected two to be missing from the external database, got: %+v", m)
+	}
+	if m := mismatches[2]; string(m.Key) != "one" || m.File != "built" {
+		t.Errorf("expected one to be checked in the built database, got: %+v", m)
+	}
+}
@@ -0,0 +1,92 @@
+package cdbtest
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CorruptionKind is a class of database corruption made by Corrupt.
+type CorruptionKind int
+
+const (
+	// TruncatedTable cuts the database off in the middle of its last hash
+	// table.
+	TruncatedTable CorruptionKind = iota
+	// BadSlotPointer points the first used hash slot past the end of the
+	// database.
+	BadSlotPointer
+	// BadLengths makes the first record's data length run past the end of
+	// the data section.
+	BadLengths
+	// FlippedValueBit flips the lowest bit of the first byte of the first
+	// non-empty value. Lookups still succeed, but return the wrong value.
+	FlippedValueBit
+)
+
+func (k CorruptionKind) String() string {
+	switch k {
+	case TruncatedTable:
+		return "TruncatedTable"
+	case BadSlotPointer:
+		return "BadSlotPointer"
+	case BadLengths:
+		return "BadLengths"
+	case FlippedValueBit:
+		return "FlippedValueBit"
+	}
+	return fmt.Sprintf("CorruptionKind(%d)", int(k))
+}
+
+const headerSize = 256 * 8
+
+// Corrupt returns a copy of the database in buf with the given kind of
+// corruption. The same input and kind always give the same output, so tests
+// can check for specific errors. Buf is not modified.
+//
+// Corrupt panics if buf has no place for the corruption, for example an empty
+// database has no hash slots to point elsewhere.
+func Corrupt(buf []byte, kind CorruptionKind) []byte {
+	if len(buf) < headerSize {
+		panic("cdbtest: database is smaller than its header")
+	}
+	b := append([]byte(nil), buf...)
+	num := func(pos uint32) uint32 { return binary.LittleEndian.Uint32(b[pos:]) }
+	put := func(pos, x uint32) { binary.LittleEndian.PutUint32(b[pos:], x) }
+	eod := num(0)
+	switch kind {
+	case TruncatedTable:
+		for i := uint32(255); i < 256; i-- {
+			if pos, nslots := num(i*8), num(i*8+4); nslots > 0 {
+				// Cut off the second half of the first slot and everything after it.
+				return b[:pos+4]
+			}
+		}
+	case BadSlotPointer:
+		for i := uint32(0); i < 256; i++ {
+			pos, nslots := num(i*8), num(i*8+4)
+			for s := pos; s < pos+nslots*8; s += 8 {
+				if num(s+4) != 0 {
+					put(s+4, uint32(len(b))+1024)
+					return b
+				}
+			}
+		}
+	case BadLengths:
+		if headerSize < eod {
+			put(headerSize+4, eod)
+			return b
+		}
+	case FlippedValueBit:
+		for pos := uint32(headerSize); pos < eod; {
+			klen, dlen := num(pos), num(pos+4)
+			if dlen > 0 {
+				b[pos+8+klen] ^= 1
+				return b
+			}
+			pos += 8 + klen + dlen
+		}
+	default:
+		panic(fmt.Sprintf("cdbtest: unknown corruption kind %v", kind))
+	}
+	panic(fmt.Sprintf("cdbtest: database has nowhere for %v corruption", kind))
+}
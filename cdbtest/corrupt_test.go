@@ -0,0 +1,39 @@
+package cdbtest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+func TestCorrupt(t *testing.T) {
+	r := newDB(t)
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []CorruptionKind{TruncatedTable, BadSlotPointer, BadLengths} {
+		db := cdb.New(bytes.NewReader(Corrupt(buf, kind)))
+		err := db.ForEachReaderHashOrder(func(keyReader, valReader *io.SectionReader) error {
+			return nil
+		})
+		if err == nil {
+			t.Errorf("%v: expected an error", kind)
+		}
+	}
+
+	b, err := cdb.New(bytes.NewReader(Corrupt(buf, FlippedValueBit))).Bytes([]byte("key"))
+	if err != nil {
+		t.Fatalf("FlippedValueBit: Bytes error: %v", err)
+	}
+	if string(b) != "walue" {
+		t.Errorf("FlippedValueBit: expected walue, got: %s", b)
+	}
+
+	if !bytes.Equal(Corrupt(buf, BadLengths), Corrupt(buf, BadLengths)) {
+		t.Errorf("expected Corrupt to be deterministic")
+	}
+}
@@ -0,0 +1,123 @@
+// Package cdbtest provides helpers for testing code that uses cdb databases.
+package cdbtest
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Fault describes a failure to inject into reads of a FaultyReaderAt.
+type Fault struct {
+	// Off and Len select the byte range of the underlying reader that the
+	// fault applies to. A read is affected if it overlaps the range. A Len of
+	// zero extends the range to the end of the reader.
+	Off, Len int64
+	// Skip is the number of affected reads to let through before the fault
+	// starts triggering.
+	Skip int
+	// Count is the number of times the fault triggers. Zero means it always
+	// triggers.
+	Count int
+	// Delay is how long to sleep before the read.
+	Delay time.Duration
+	// Short makes the read return only the bytes before Off, as if the file
+	// had been truncated there.
+	Short bool
+	// Err is the error returned by the read. If Short is set and Err is nil,
+	// io.EOF is returned.
+	Err error
+
+	seen, triggered int
+}
+
+// FaultyReaderAt is an io.ReaderAt that wraps another one and injects errors,
+// short reads and latency, for testing how callers handle failing storage.
+//
+// Threadsafe.
+type FaultyReaderAt struct {
+	r io.ReaderAt
+
+	mu     sync.Mutex
+	faults []*Fault
+	reads  int
+}
+
+// NewFaultyReaderAt returns a FaultyReaderAt reading from r that injects the
+// given faults. When several faults affect a read, the first one in the list
+// wins.
+func NewFaultyReaderAt(r io.ReaderAt, faults ...Fault) *FaultyReaderAt {
+	f := &FaultyReaderAt{r: r}
+	for i := range faults {
+		f.AddFault(faults[i])
+	}
+	return f
+}
+
+// AddFault adds a fault to be injected into subsequent reads.
+func (f *FaultyReaderAt) AddFault(fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fault.seen, fault.triggered = 0, 0
+	f.faults = append(f.faults, &fault)
+}
+
+// Reset removes all faults.
+func (f *FaultyReaderAt) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+// Reads returns the number of calls to ReadAt so far.
+func (f *FaultyReaderAt) Reads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+func (f *FaultyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	fault := f.match(off, int64(len(p)))
+	if fault == nil {
+		return f.r.ReadAt(p, off)
+	}
+	if fault.Delay > 0 {
+		time.Sleep(fault.Delay)
+	}
+	if fault.Short {
+		n := 0
+		if fault.Off > off {
+			n, _ = f.r.ReadAt(p[:fault.Off-off], off)
+		}
+		if fault.Err != nil {
+			return n, fault.Err
+		}
+		return n, io.EOF
+	}
+	if fault.Err != nil {
+		return 0, fault.Err
+	}
+	return f.r.ReadAt(p, off)
+}
+
+// match returns the fault that should be injected into the read, or nil.
+func (f *FaultyReaderAt) match(off, n int64) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+	for _, fault := range f.faults {
+		if off+n <= fault.Off || (fault.Len > 0 && off >= fault.Off+fault.Len) {
+			continue
+		}
+		fault.seen++
+		if fault.seen <= fault.Skip {
+			continue
+		}
+		if fault.Count > 0 && fault.triggered >= fault.Count {
+			continue
+		}
+		fault.triggered++
+		return fault
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cdbtest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+func newDB(t *testing.T) *bytes.Reader {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := cdb.NewWriter(tmp)
+	if err := w.Write([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(b)
+}
+
+func TestFaultyReaderAtError(t *testing.T) {
+	r := NewFaultyReaderAt(newDB(t), Fault{Err: syscall.EIO, Skip: 1, Count: 1})
+	db := cdb.New(r)
+	// The first read (the header) is skipped, the second fails.
+	if _, err := db.Bytes([]byte("key")); !errors.Is(err, syscall.EIO) {
+		t.Errorf("expected EIO, got: %v", err)
+	}
+	// The fault only triggers once.
+	if b, err := db.Bytes([]byte("key")); err != nil || string(b) != "value" {
+		t.Errorf("expected value, got: %q, %v", b, err)
+	}
+	if r.Reads() == 0 {
+		t.Errorf("expected reads to be counted")
+	}
+}
+
+func TestFaultyReaderAtShort(t *testing.T) {
+	// Truncate the last byte of the value, which follows the 2048 byte header,
+	// the 8 byte record header and the key.
+	r := NewFaultyReaderAt(newDB(t), Fault{Off: 2048 + 8 + 3 + 4, Len: 1, Short: true})
+	_, err := cdb.New(r).Bytes([]byte("key"))
+	var corrupt *cdb.CorruptError
+	if !errors.As(err, &corrupt) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a CorruptError for the truncated value, got: %v", err)
+	}
+	if corrupt.Offset != 2048+8+3+4 {
+		t.Errorf("expected the damage at %d, got: %d", 2048+8+3+4, corrupt.Offset)
+	}
+}
+
+func TestFaultyReaderAtDelay(t *testing.T) {
+	r := NewFaultyReaderAt(newDB(t), Fault{Delay: 10 * time.Millisecond, Count: 1})
+	start := time.Now()
+	if _, err := cdb.New(r).Bytes([]byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Errorf("expected the lookup to be delayed")
+	}
+}
@@ -0,0 +1,239 @@
+package cdbzstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/torbit/cdb"
+)
+
+// Values in the zstd seekable format are a series of independently compressed
+// zstd frames followed by a seek table, stored in a skippable frame, giving
+// the compressed and decompressed size of each frame. Any zstd decoder can
+// decompress them, and readers that understand the seek table can decompress
+// just the frames covering the part of the value they need.
+const (
+	skippableMagic = 0x184D2A5E
+	seekableMagic  = 0x8F92EAB1
+	footerSize     = 9
+	checksumFlag   = 1 << 7
+)
+
+// ErrNotSeekable is returned by NewSeekableReader for values that aren't in
+// the zstd seekable format.
+var ErrNotSeekable = errors.New("not in zstd seekable format")
+
+// SeekableWriter compresses a value into the zstd seekable format.
+//
+// Not threadsafe.
+type SeekableWriter struct {
+	w         io.Writer
+	enc       *zstd.Encoder
+	frameSize int
+	buf       []byte
+	sizes     []uint32 // Compressed and decompressed size of each frame.
+	err       error
+}
+
+// NewSeekableWriter returns a SeekableWriter that compresses every frameSize
+// bytes written to it into a separate frame, and writes the frames to w.
+// Smaller frames make partial reads cheaper, and compression worse. FrameSize
+// must be positive.
+func NewSeekableWriter(w io.Writer, frameSize int) (*SeekableWriter, error) {
+	if frameSize <= 0 {
+		return nil, fmt.Errorf("cdbzstd: frame size %d isn't positive", frameSize)
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &SeekableWriter{w: w, enc: enc, frameSize: frameSize, buf: make([]byte, 0, frameSize)}, nil
+}
+
+func (sw *SeekableWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && sw.err == nil {
+		m := copy(sw.buf[len(sw.buf):sw.frameSize], p)
+		sw.buf = sw.buf[:len(sw.buf)+m]
+		p = p[m:]
+		n += m
+		if len(sw.buf) == sw.frameSize {
+			sw.writeFrame()
+		}
+	}
+	return n, sw.err
+}
+
+func (sw *SeekableWriter) writeFrame() {
+	frame := sw.enc.EncodeAll(sw.buf, nil)
+	if _, sw.err = sw.w.Write(frame); sw.err == nil {
+		sw.sizes = append(sw.sizes, uint32(len(frame)), uint32(len(sw.buf)))
+	}
+	sw.buf = sw.buf[:0]
+}
+
+// Close writes the last frame and the seek table. It doesn't close the
+// underlying writer.
+func (sw *SeekableWriter) Close() error {
+	if len(sw.buf) > 0 && sw.err == nil {
+		sw.writeFrame()
+	}
+	if sw.err != nil {
+		return sw.err
+	}
+	table := make([]byte, 8+len(sw.sizes)*4+footerSize)
+	binary.LittleEndian.PutUint32(table, skippableMagic)
+	binary.LittleEndian.PutUint32(table[4:], uint32(len(table)-8))
+	for i, size := range sw.sizes {
+		binary.LittleEndian.PutUint32(table[8+i*4:], size)
+	}
+	footer := table[len(table)-footerSize:]
+	binary.LittleEndian.PutUint32(footer, uint32(len(sw.sizes)/2))
+	footer[4] = 0 // No checksums.
+	binary.LittleEndian.PutUint32(footer[5:], seekableMagic)
+	_, sw.err = sw.w.Write(table)
+	sw.enc.Close()
+	return sw.err
+}
+
+// frame is the position of a compressed frame and of its decompressed data.
+type frame struct {
+	cpos, dpos   int64
+	csize, dsize int64
+}
+
+// seekableReaderAt decompresses the frames of a seekable value as they are
+// read.
+type seekableReaderAt struct {
+	r      io.ReaderAt
+	frames []frame
+
+	// The most recently decompressed frame is kept for sequential reads.
+	mu      sync.Mutex
+	cached  int
+	content []byte
+}
+
+var (
+	decoderOnce sync.Once
+	decoder     *zstd.Decoder
+	decoderErr  error
+)
+
+// NewSeekableReader returns a reader for the decompressed contents of r, which
+// must be in the zstd seekable format. Reads only decompress the frames they
+// cover. It returns ErrNotSeekable if r doesn't end with a seek table.
+func NewSeekableReader(r *io.SectionReader) (*io.SectionReader, error) {
+	decoderOnce.Do(func() {
+		decoder, decoderErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	if decoderErr != nil {
+		return nil, decoderErr
+	}
+	size := r.Size()
+	if size < 8+footerSize {
+		return nil, ErrNotSeekable
+	}
+	var footer [footerSize]byte
+	if _, err := r.ReadAt(footer[:], size-footerSize); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != seekableMagic || footer[4]&^checksumFlag != 0 {
+		return nil, ErrNotSeekable
+	}
+	nframes := int64(binary.LittleEndian.Uint32(footer[:]))
+	entrySize := int64(8)
+	if footer[4]&checksumFlag != 0 {
+		entrySize = 12
+	}
+	tableSize := 8 + nframes*entrySize + footerSize
+	if tableSize > size {
+		return nil, ErrNotSeekable
+	}
+	table := make([]byte, tableSize)
+	if _, err := r.ReadAt(table, size-tableSize); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(table) != skippableMagic || int64(binary.LittleEndian.Uint32(table[4:])) != tableSize-8 {
+		return nil, ErrNotSeekable
+	}
+
+	s := &seekableReaderAt{r: r, frames: make([]frame, nframes), cached: -1}
+	var cpos, dpos int64
+	for i := range s.frames {
+		entry := table[8+int64(i)*entrySize:]
+		f := frame{cpos: cpos, dpos: dpos}
+		f.csize = int64(binary.LittleEndian.Uint32(entry))
+		f.dsize = int64(binary.LittleEndian.Uint32(entry[4:]))
+		s.frames[i] = f
+		cpos += f.csize
+		dpos += f.dsize
+	}
+	if cpos != size-tableSize {
+		return nil, ErrNotSeekable
+	}
+	return io.NewSectionReader(s, 0, dpos), nil
+}
+
+// frame returns the decompressed contents of frame i.
+func (s *seekableReaderAt) frame(i int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached == i {
+		return s.content, nil
+	}
+	f := s.frames[i]
+	compressed := make([]byte, f.csize)
+	if _, err := s.r.ReadAt(compressed, f.cpos); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	content, err := decoder.DecodeAll(compressed, make([]byte, 0, f.dsize))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) != f.dsize {
+		return nil, errors.New("cdbzstd: frame size doesn't match the seek table")
+	}
+	s.cached, s.content = i, content
+	return content, nil
+}
+
+func (s *seekableReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	i := sort.Search(len(s.frames), func(i int) bool {
+		return s.frames[i].dpos+s.frames[i].dsize > off
+	})
+	n := 0
+	for ; n < len(p) && i < len(s.frames); i++ {
+		content, err := s.frame(i)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], content[off+int64(n)-s.frames[i].dpos:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// SeekableValues returns a cdb.Option that decompresses values stored in the
+// zstd seekable format, so readers returned by lookups support partial reads
+// that only decompress the frames they need. Other values are returned
+// unchanged.
+func SeekableValues() cdb.Option {
+	return cdb.ValueReaderTransform(func(key []byte, val *io.SectionReader) (*io.SectionReader, error) {
+		r, err := NewSeekableReader(val)
+		if err == ErrNotSeekable {
+			return val, nil
+		}
+		return r, err
+	})
+}
@@ -0,0 +1,83 @@
+package cdbzstd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/torbit/cdb"
+)
+
+func TestSeekable(t *testing.T) {
+	content := make([]byte, 10000)
+	for i := range content {
+		content[i] = byte(i / 7)
+	}
+	var value bytes.Buffer
+	sw, err := NewSeekableWriter(&value, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Plain zstd decoders can read the whole value.
+	dec, _ := zstd.NewReader(nil)
+	if b, err := dec.DecodeAll(value.Bytes(), nil); err != nil || !bytes.Equal(b, content) {
+		t.Fatalf("DecodeAll: got %d bytes, %v", len(b), err)
+	}
+
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := cdb.NewWriter(tmp)
+	w.Write([]byte("big"), value.Bytes())
+	w.Write([]byte("plain"), []byte("as is"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db := cdb.New(tmp, SeekableValues())
+	r, err := db.Reader([]byte("big"))
+	if err != nil {
+		t.Fatalf("Reader error: %v", err)
+	}
+	if r.Size() != int64(len(content)) {
+		t.Fatalf("expected size %d, got: %d", len(content), r.Size())
+	}
+	// A read spanning frames.
+	p := make([]byte, 3000)
+	if _, err := r.ReadAt(p, 2000); err != nil {
+		t.Fatalf("ReadAt error: %v", err)
+	}
+	if !bytes.Equal(p, content[2000:5000]) {
+		t.Errorf("ReadAt returned the wrong bytes")
+	}
+	if n, err := r.ReadAt(p, 9000); n != 1000 || err != io.EOF {
+		t.Errorf("expected 1000 bytes and EOF at the end, got: %d, %v", n, err)
+	}
+	if b, err := db.Bytes([]byte("big")); err != nil || !bytes.Equal(b, content) {
+		t.Errorf("Bytes: got %d bytes, %v", len(b), err)
+	}
+	if b, err := db.Bytes([]byte("plain")); err != nil || string(b) != "as is" {
+		t.Errorf("Bytes: expected plain value, got: %q, %v", b, err)
+	}
+}
+
+func TestSeekableWriterFrameSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		if _, err := NewSeekableWriter(ioutil.Discard, size); err == nil {
+			t.Errorf("expected an error for frame size %d", size)
+		}
+	}
+}
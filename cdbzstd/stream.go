@@ -0,0 +1,150 @@
+package cdbzstd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/torbit/cdb"
+)
+
+// ErrNotZstd is returned by NewStreamReader for values that aren't zstd
+// compressed.
+var ErrNotZstd = errors.New("not zstd compressed")
+
+// streamReaderAt decompresses a zstd value as a stream. Reads going forward
+// continue from where the last one stopped, so copying the value out holds
+// no more than the decoder's window in memory. Reads going back start again
+// from the beginning.
+type streamReaderAt struct {
+	r         *io.SectionReader
+	maxWindow uint64
+
+	mu sync.Mutex
+	// dec is at pos in the decompressed value. It is nil before the first
+	// read, and once the end has been reached.
+	dec *zstd.Decoder
+	pos int64
+}
+
+// NewStreamReader returns a reader for the decompressed contents of r, which
+// must be zstd compressed. Reading it in order, as io.Copy does, decompresses
+// the value as a stream, holding at most the compression window in memory, so
+// values of any size can be piped to a client. Reads that go back decompress
+// again from the beginning. Use NewSeekableReader for values that need
+// random access.
+//
+// Values needing a window bigger than maxWindow bytes fail with an error
+// matching zstd.ErrWindowSizeExceeded, so damaged or hostile values can't use
+// up memory. A maxWindow of 0 uses the zstd package's limit. The size of the
+// value is taken from its first frame header. If the header doesn't give it,
+// the value is decompressed once to find it, and the value must be a single
+// frame if it does.
+//
+// It returns ErrNotZstd if r doesn't start with a zstd frame.
+func NewStreamReader(r *io.SectionReader, maxWindow uint64) (*io.SectionReader, error) {
+	buf := make([]byte, zstd.HeaderMaxSize)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var h zstd.Header
+	if err := h.Decode(buf[:n]); err != nil || h.Skippable {
+		return nil, ErrNotZstd
+	}
+	window := h.WindowSize
+	if h.SingleSegment {
+		window = h.FrameContentSize
+	}
+	if maxWindow > 0 && window > maxWindow {
+		return nil, fmt.Errorf("cdbzstd: value needs a %d byte window, over the limit of %d: %w", window, maxWindow, zstd.ErrWindowSizeExceeded)
+	}
+
+	s := &streamReaderAt{r: r, maxWindow: maxWindow}
+	size := int64(h.FrameContentSize)
+	if !h.HasFCS {
+		if size, err = s.size(); err != nil {
+			return nil, err
+		}
+	}
+	return io.NewSectionReader(s, 0, size), nil
+}
+
+// newDecoder returns a decoder reading the value from the beginning.
+func (s *streamReaderAt) newDecoder() (*zstd.Decoder, error) {
+	opts := []zstd.DOption{zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true)}
+	if s.maxWindow > 0 {
+		opts = append(opts, zstd.WithDecoderMaxWindow(s.maxWindow))
+	}
+	return zstd.NewReader(io.NewSectionReader(s.r, 0, s.r.Size()), opts...)
+}
+
+// size decompresses the whole value to find its size.
+func (s *streamReaderAt) size() (int64, error) {
+	dec, err := s.newDecoder()
+	if err != nil {
+		return 0, err
+	}
+	defer dec.Close()
+	return io.Copy(io.Discard, dec)
+}
+
+func (s *streamReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dec == nil || off < s.pos {
+		if s.dec != nil {
+			s.dec.Close()
+		}
+		dec, err := s.newDecoder()
+		if err != nil {
+			return 0, err
+		}
+		s.dec, s.pos = dec, 0
+	}
+	if off > s.pos {
+		n, err := io.CopyN(io.Discard, s.dec, off-s.pos)
+		s.pos += n
+		if err != nil {
+			return 0, s.stop(err)
+		}
+	}
+	n, err := io.ReadFull(s.dec, p)
+	s.pos += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if err != nil {
+		return n, s.stop(err)
+	}
+	return n, nil
+}
+
+// stop closes the decoder after err ended the stream, and returns err.
+func (s *streamReaderAt) stop(err error) error {
+	s.dec.Close()
+	s.dec = nil
+	return err
+}
+
+// StreamValues returns a cdb.Option that decompresses zstd values. Values in
+// the zstd seekable format are read as with SeekableValues, other zstd values
+// are streamed as by NewStreamReader with the given maxWindow, and values
+// that aren't compressed are returned unchanged. Readers returned by lookups,
+// and by CdbIterator.NextReader, decompress as they are read, so huge values
+// can be copied out with constant memory.
+func StreamValues(maxWindow uint64) cdb.Option {
+	return cdb.ValueReaderTransform(func(key []byte, val *io.SectionReader) (*io.SectionReader, error) {
+		r, err := NewSeekableReader(val)
+		if err != ErrNotSeekable {
+			return r, err
+		}
+		r, err = NewStreamReader(val, maxWindow)
+		if err == ErrNotZstd {
+			return val, nil
+		}
+		return r, err
+	})
+}
@@ -0,0 +1,116 @@
+package cdbzstd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/torbit/cdb"
+)
+
+// content returns n bytes that compress well but need a big window to do it.
+func content(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i/7) ^ byte(i>>16)
+	}
+	return b
+}
+
+// compress compresses b as a stream, so the frame doesn't record its size.
+func compress(t *testing.T, b []byte, opts ...zstd.EOption) []byte {
+	var buf bytes.Buffer
+	enc, err := zstd.NewWriter(&buf, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStreamValues(t *testing.T) {
+	// Streamed values bigger than a block don't record their size.
+	small := content(3 << 20)
+	big := content(64 << 20)
+	enc, _ := zstd.NewWriter(nil)
+	sized := enc.EncodeAll(small, nil)
+
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := cdb.NewWriter(tmp)
+	w.Write([]byte("sized"), sized)
+	w.Write([]byte("unsized"), compress(t, small, zstd.WithWindowSize(1<<20)))
+	w.Write([]byte("big"), compress(t, big, zstd.WithWindowSize(1<<20)))
+	w.Write([]byte("wide"), compress(t, content(6<<20), zstd.WithWindowSize(8<<20)))
+	w.Write([]byte("plain"), []byte("as is"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db := cdb.New(tmp, StreamValues(4<<20))
+
+	for _, key := range []string{"sized", "unsized"} {
+		r, err := db.Reader([]byte(key))
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if r.Size() != int64(len(small)) {
+			t.Errorf("%s: expected size %d, got: %d", key, len(small), r.Size())
+		}
+		// Reads going forward, then back.
+		p := make([]byte, 1000)
+		for _, off := range []int{2000, 5000, 1000} {
+			if _, err := r.ReadAt(p, int64(off)); err != nil || !bytes.Equal(p, small[off:off+1000]) {
+				t.Errorf("%s: ReadAt %d returned the wrong bytes, %v", key, off, err)
+			}
+		}
+		if n, err := r.ReadAt(p, int64(len(small)-500)); n != 500 || err != io.EOF {
+			t.Errorf("%s: expected 500 bytes and EOF at the end, got: %d, %v", key, n, err)
+		}
+		if b, err := db.Bytes([]byte(key)); err != nil || !bytes.Equal(b, small) {
+			t.Errorf("%s: Bytes: got %d bytes, %v", key, len(b), err)
+		}
+	}
+
+	// Streaming a big value out holds about a window of it in memory.
+	iter := db.Iterate([]byte("big"))
+	r, err := iter.NextReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	h := sha256.New()
+	if n, err := io.CopyBuffer(h, r, make([]byte, 32<<10)); n != int64(len(big)) || err != nil {
+		t.Fatalf("expected %d bytes, got: %d, %v", len(big), n, err)
+	}
+	runtime.ReadMemStats(&after)
+	if sum := sha256.Sum256(big); !bytes.Equal(h.Sum(nil), sum[:]) {
+		t.Error("streamed the wrong content")
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Errorf("expected streaming to allocate about the window, got %d bytes", alloc)
+	}
+
+	if _, err := db.Reader([]byte("wide")); !errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		t.Errorf("expected ErrWindowSizeExceeded, got: %v", err)
+	}
+	if b, err := db.Bytes([]byte("plain")); err != nil || string(b) != "as is" {
+		t.Errorf("Bytes: expected plain value, got: %q, %v", b, err)
+	}
+}
@@ -0,0 +1,28 @@
+// Package cdbzstd adds zstd support to package cdb.
+//
+// Importing it registers a zstd decompressor, so Make accepts zstd
+// compressed input:
+//
+//	import _ "github.com/torbit/cdb/cdbzstd"
+//
+// It also reads and writes huge values in the zstd seekable format, see
+// SeekableValues, and streams other zstd values with bounded memory, see
+// StreamValues.
+package cdbzstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/torbit/cdb"
+)
+
+func init() {
+	cdb.RegisterDecompressor("zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}, func(r io.Reader) (io.Reader, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	})
+}
@@ -0,0 +1,38 @@
+package cdbzstd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/torbit/cdb"
+)
+
+func TestMakeZstd(t *testing.T) {
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write([]byte("+3,5:key->value\n\n"))
+	zw.Close()
+
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := cdb.Make(tmp, &buf); err != nil {
+		t.Fatalf("Make failed: %v", err)
+	}
+	b, err := cdb.New(tmp).Bytes([]byte("key"))
+	if err != nil {
+		t.Fatalf("Bytes error: %v", err)
+	}
+	if string(b) != "value" {
+		t.Errorf("expected value, got: %s", b)
+	}
+}
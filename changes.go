@@ -0,0 +1,128 @@
+package cdb
+
+import (
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrDatabaseChanged is returned by reads from a database that was modified in
+// place after it was opened, when DetectChanges is used.
+var ErrDatabaseChanged = errors.New("database changed")
+
+// DetectChanges returns an Option that guards against the database being
+// overwritten in place while it is open, which would make reads return
+// garbage. The file's size, modification time and a checksum of its header
+// are recorded on the first read, and compared again whenever a read fails
+// and at most every interval otherwise. Once a change is seen, all reads
+// return ErrDatabaseChanged.
+//
+// The check reads the database directly, whatever order the options are
+// given in, so it isn't hidden by options like WithPreloadedHeader that serve
+// reads from memory. Size and modification time are checked for files opened
+// by Open, OpenMmap, LazyOpen, NewFromFd and OpenFS. Readers passed to New and
+// NewFile are only checked that way if they have a Stat method, like
+// *os.File, and otherwise only by the checksum of their header.
+func DetectChanges(interval time.Duration) Option {
+	return func(c *Cdb) {
+		c.detectChanges, c.changeInterval = true, interval
+	}
+}
+
+type fingerprint struct {
+	size    int64
+	modTime time.Time
+	sum     uint32
+}
+
+// equal returns true if fp and other describe the same file contents.
+// Modification times are compared as instants, ignoring monotonic clock
+// readings and locations.
+func (fp *fingerprint) equal(other *fingerprint) bool {
+	return fp.size == other.size && fp.modTime.Equal(other.modTime) && fp.sum == other.sum
+}
+
+// changeGuard is an io.ReaderAt that checks the reader it wraps for changes.
+type changeGuard struct {
+	r        io.ReaderAt
+	interval time.Duration
+
+	mu          sync.Mutex
+	fp          *fingerprint
+	lastChecked time.Time
+	changed     bool
+}
+
+func (g *changeGuard) ReadAt(p []byte, off int64) (int, error) {
+	g.mu.Lock()
+	if g.fp == nil {
+		fp, err := g.fingerprint()
+		if err != nil {
+			g.mu.Unlock()
+			return 0, err
+		}
+		g.fp, g.lastChecked = fp, time.Now()
+	}
+	due := time.Since(g.lastChecked) >= g.interval
+	g.mu.Unlock()
+	if due {
+		if err := g.check(true); err != nil {
+			return 0, err
+		}
+	}
+	n, err := g.r.ReadAt(p, off)
+	if err != nil {
+		// The failure may be because the file was truncated or rewritten.
+		if err := g.check(false); err != nil {
+			return 0, err
+		}
+	}
+	return n, err
+}
+
+// check returns ErrDatabaseChanged if the reader's fingerprint changed. A
+// periodic check is skipped if another read checked since the interval
+// passed, so readers that all find the check due only make one.
+func (g *changeGuard) check(periodic bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.changed {
+		return ErrDatabaseChanged
+	}
+	if periodic && time.Since(g.lastChecked) < g.interval {
+		return nil
+	}
+	fp, err := g.fingerprint()
+	if err != nil {
+		return err
+	}
+	g.lastChecked = time.Now()
+	if !fp.equal(g.fp) {
+		g.changed = true
+		return ErrDatabaseChanged
+	}
+	return nil
+}
+
+func (g *changeGuard) fingerprint() (*fingerprint, error) {
+	fp := new(fingerprint)
+	if s, ok := g.r.(interface {
+		Stat() (os.FileInfo, error)
+	}); ok {
+		fi, err := s.Stat()
+		if err != nil {
+			return nil, err
+		}
+		fp.size, fp.modTime = fi.Size(), fi.ModTime()
+	}
+	header := make([]byte, headerSize)
+	n, err := g.r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	fp.sum = crc32.ChecksumIEEE(header[:n])
+	return fp, nil
+}
@@ -0,0 +1,163 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDetectChanges(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(newDBBytes(records)); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+
+	db, err := Open(tmp.Name(), DetectChanges(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Fatalf("expected 1, got: %s, %v", b, err)
+	}
+
+	// Overwrite the file in place with a different database.
+	if err := ioutil.WriteFile(tmp.Name(), newDBBytes(records[:1]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Bytes([]byte("one")); err != ErrDatabaseChanged {
+		t.Errorf("expected ErrDatabaseChanged, got: %v", err)
+	}
+	if _, err := db.Bytes([]byte("two")); err != ErrDatabaseChanged {
+		t.Errorf("expected ErrDatabaseChanged to stick, got: %v", err)
+	}
+}
+
+func TestDetectChangesInterval(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(newDBBytes(records))
+	tmp.Close()
+
+	db, err := Open(tmp.Name(), DetectChanges(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Bytes([]byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	// Reads that fail are checked even before the interval is up.
+	if err := os.Truncate(tmp.Name(), 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Bytes([]byte("one")); err != ErrDatabaseChanged {
+		t.Errorf("expected ErrDatabaseChanged, got: %v", err)
+	}
+}
+
+func TestDetectChangesOptionOrder(t *testing.T) {
+	// The database must be bigger than the header WithPreloadedHeader keeps
+	// in memory, so lookups read the file.
+	recs := append([]rec{{"big", []string{strings.Repeat("x", 8192)}}}, records...)
+	name := filepath.Join(t.TempDir(), "test.cdb")
+	if err := os.WriteFile(name, newDBBytes(recs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range [][]Option{
+		{WithPreloadedHeader(), DetectChanges(0)},
+		{DetectChanges(0), WithPreloadedHeader()},
+	} {
+		db, err := Open(name, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Bytes([]byte("one")); err != nil {
+			t.Fatal(err)
+		}
+		// Rewrite the file with the same header, which is in memory.
+		if err := os.WriteFile(name, newDBBytes(recs), 0644); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Hour)
+		if err := os.Chtimes(name, later, later); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Bytes([]byte("one")); err != ErrDatabaseChanged {
+			t.Errorf("expected ErrDatabaseChanged, got: %v", err)
+		}
+		db.Close()
+	}
+}
+
+// statCounter counts the calls to Stat of the file it wraps.
+type statCounter struct {
+	*os.File
+	stats atomic.Int32
+}
+
+func (s *statCounter) Stat() (os.FileInfo, error) {
+	s.stats.Add(1)
+	return s.File.Stat()
+}
+
+func TestDetectChangesConcurrentCheck(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.cdb")
+	if err := os.WriteFile(name, newDBBytes(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := &statCounter{File: f}
+	g := &changeGuard{r: sc, interval: time.Hour}
+	buf := make([]byte, 8)
+	if _, err := g.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers that all find the check due make one between them.
+	g.lastChecked = g.lastChecked.Add(-2 * time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := g.ReadAt(make([]byte, 8), 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := sc.stats.Load(); n != 2 {
+		t.Errorf("expected 2 fingerprints, got: %d", n)
+	}
+}
+
+func TestFingerprintEqual(t *testing.T) {
+	now := time.Now()
+	a := &fingerprint{size: 1, modTime: now, sum: 2}
+	// The same instant without a monotonic reading, in another location.
+	b := &fingerprint{size: 1, modTime: now.Round(0).In(time.FixedZone("x", 3600)), sum: 2}
+	if !a.equal(b) {
+		t.Error("expected the same instant to be equal")
+	}
+	if b.modTime = now.Add(time.Second); a.equal(b) {
+		t.Error("expected different times to differ")
+	}
+}
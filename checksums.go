@@ -0,0 +1,61 @@
+package cdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// Checksums returns a WriterOption that records a checksum of every record in
+// the database, so damage to the data can be found with
+// Cdb.VerifyChecksums. They are stored in an extension section, so other cdb
+// implementations ignore them. The section holds the CRC-32 (IEEE) of each
+// record, header, key and value, in data order, as 32 bit little endian
+// numbers.
+func Checksums() WriterOption {
+	return func(w *Writer) {
+		w.crc = crc32.NewIEEE()
+	}
+}
+
+// VerifyChecksums reads every record and checks it against the checksum
+// recorded with the Checksums WriterOption. It returns a CorruptError for the
+// first record that doesn't match, and ErrNoChecksums if the database has no
+// checksums.
+//
+// Threadsafe.
+func (c *Cdb) VerifyChecksums() error {
+	section, err := c.extension(extChecksums)
+	if err != nil {
+		return err
+	}
+	if section == nil {
+		return ErrNoChecksums
+	}
+	sums := bufio.NewReader(section)
+	crc := crc32.NewIEEE()
+	buf := make([]byte, 4)
+	var end uint64
+	err = c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		end = pos + 8 + uint64(klen) + uint64(dlen)
+		if _, err := io.ReadFull(sums, buf); err != nil {
+			return corruptf(pos, "record has no checksum")
+		}
+		crc.Reset()
+		if _, err := io.Copy(crc, io.NewSectionReader(c.r, int64(pos), int64(end-pos))); err != nil {
+			return readErr(int64(pos), 0, 1, err)
+		}
+		if crc.Sum32() != binary.LittleEndian.Uint32(buf) {
+			return corruptf(pos, "record checksum mismatch")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := sums.ReadByte(); err != io.EOF {
+		return corruptf(end, "more checksums than records")
+	}
+	return nil
+}
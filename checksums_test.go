@@ -0,0 +1,115 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp, Checksums())
+	for _, rec := range records {
+		for _, val := range rec.values {
+			if err := w.Write([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(b))
+	checkRecords(t, db, records)
+	if err := db.VerifyChecksums(); err != nil {
+		t.Fatalf("VerifyChecksums: %v", err)
+	}
+
+	// Damage the value of the second record, which the hash tables can't
+	// notice.
+	second := uint64(headerSize) + 8 + uint64(len("one")+len("1"))
+	b[second+8+uint64(len("two"))] ^= 0xff
+	err = New(bytes.NewReader(b)).VerifyChecksums()
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) || corrupt.Offset != second {
+		t.Errorf("expected a CorruptError at %d, got: %v", second, err)
+	}
+
+	if err := newDB(records).VerifyChecksums(); err != ErrNoChecksums {
+		t.Errorf("expected ErrNoChecksums, got: %v", err)
+	}
+}
+
+// flakyReaderAt fails every read at or after from while failing is set.
+type flakyReaderAt struct {
+	r       io.ReaderAt
+	failing bool
+	from    int64
+}
+
+var errFlaky = errors.New("flaky read")
+
+func (f *flakyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if f.failing && off >= f.from {
+		return 0, errFlaky
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func TestExtensionsRetryAfterReadErrors(t *testing.T) {
+	w := NewBufferWriter(Checksums())
+	for _, rec := range records {
+		for _, val := range rec.values {
+			if err := w.Put([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &flakyReaderAt{r: bytes.NewReader(b), failing: true}
+	db := New(r)
+	if err := db.VerifyChecksums(); !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the read error, got: %v", err)
+	}
+	r.failing = false
+	if err := db.VerifyChecksums(); err != nil {
+		t.Errorf("expected the extensions to be read again, got: %v", err)
+	}
+
+	// A failure reading just the start of the extensions isn't taken for a
+	// database without any.
+	end, err := tablesEnd(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = &flakyReaderAt{r: bytes.NewReader(b), failing: true, from: int64(end)}
+	db = New(r)
+	if err := db.VerifyChecksums(); !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the read error, got: %v", err)
+	}
+	r.failing = false
+	if err := db.VerifyChecksums(); err != nil {
+		t.Errorf("expected the extensions to be read again, got: %v", err)
+	}
+
+	// Nor is a failure reading past the end taken for the end of the file.
+	b = newDBBytes(records)
+	r = &flakyReaderAt{r: bytes.NewReader(b), failing: true, from: int64(len(b))}
+	if err := New(r).Verify(); !errors.Is(err, errFlaky) {
+		t.Errorf("expected the read error from Verify, got: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/torbit/cdb/cdbtest"
+)
+
+func init() {
+	commands["compat"] = command{
+		usage: "compat records.txt external.cdb",
+		run:   runCompat,
+	}
+}
+
+// runCompat checks a database built by another cdb implementation against
+// one built by this package from the same cdbmake records.
+func runCompat(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compat", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	records, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb compat: %v\n", err)
+		return exitIO
+	}
+	defer records.Close()
+	external, err := os.Open(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb compat: %v\n", err)
+		return exitIO
+	}
+	defer external.Close()
+	mismatches, err := cdbtest.CompatCheck(records, external)
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb compat: %v\n", err)
+		return exitIO
+	}
+	for _, m := range mismatches {
+		fmt.Fprintf(stdout, "%s: key %q: expected %q, got %q\n", m.File, m.Key, m.Want, m.Got)
+	}
+	if len(mismatches) > 0 {
+		return exitMismatch
+	}
+	fmt.Fprintf(stdout, "%s: compatible\n", fs.Arg(1))
+	return exitOK
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := newDB(t, dir)
+	records := filepath.Join(dir, "records.txt")
+	if err := ioutil.WriteFile(records, []byte("+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	if code := run([]string{"compat", records, name}, &stdout, ioutil.Discard); code != exitOK {
+		t.Errorf("expected the databases to be compatible, got: %d: %s", code, stdout.String())
+	}
+
+	if err := ioutil.WriteFile(records, []byte("+3,1:one->1\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{"compat", records, name}, &stdout, ioutil.Discard); code != exitMismatch {
+		t.Errorf("expected a mismatch, got: %d", code)
+	}
+	if expected := "built: key \"two\": expected [\"2\" \"22\"], got []\n"; stdout.String() != expected {
+		t.Errorf("expected %q, got: %q", expected, stdout.String())
+	}
+}
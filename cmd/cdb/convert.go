@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/torbit/cdb"
+)
+
+func init() {
+	commands["convert"] = command{
+		usage: "convert [-64] [-checksums] [-hash name] src.cdb dst.cdb",
+		run:   runConvert,
+	}
+}
+
+// hashes are the key hashes convert can build databases with, by name. The
+// cdb hash is nil.
+var hashes = map[string]func(key []byte) uint32{
+	"cdb": nil,
+	"fnv1a": func(key []byte) uint32 {
+		h := fnv.New32a()
+		h.Write(key)
+		return h.Sum32()
+	},
+	"crc32c": func(key []byte) uint32 {
+		return crc32.Checksum(key, crc32.MakeTable(crc32.Castagnoli))
+	},
+}
+
+// runConvert rewrites a database in another format. The flags give the whole
+// target format, so a conversion drops anything not asked for, like the
+// checksums of src.
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	wide := fs.Bool("64", false, "write the 64 bit layout instead of a classic cdb file")
+	sums := fs.Bool("checksums", false, "record a checksum of every record")
+	hashName := fs.String("hash", "cdb", "key hash to build with: "+strings.Join(names, ", "))
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	hash, ok := hashes[*hashName]
+	if fs.NArg() != 2 || !ok {
+		fs.Usage()
+		return exitUsage
+	}
+
+	src, err := cdb.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb convert: %v\n", err)
+		return exitIO
+	}
+	defer src.Close()
+	dst, err := os.Create(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb convert: %v\n", err)
+		return exitIO
+	}
+	err = cdb.ConvertFormat(dst, src, cdb.Format{Wide: *wide, Checksums: *sums, Hash: hash})
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fs.Arg(1))
+		fmt.Fprintf(stderr, "cdb convert: %v\n", err)
+		return exitIO
+	}
+	return exitOK
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := newDB(t, dir)
+	wide := filepath.Join(dir, "wide.cdb")
+
+	var stderr bytes.Buffer
+	if code := run([]string{"convert", "-64", "-checksums", "-hash", "fnv1a", name, wide}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	db, err := cdb.Open(wide, cdb.WithHash(hashes["fnv1a"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if f, err := db.Format(); err != nil || !f.Wide || !f.Checksums {
+		t.Errorf("expected a wide database with checksums, got: %+v, %v", f, err)
+	}
+	if b, err := db.Bytes([]byte("two")); err != nil || string(b) != "2" {
+		t.Errorf("expected 2, got: %q, %v", b, err)
+	}
+
+	sums := filepath.Join(dir, "sums.cdb")
+	if code := run([]string{"convert", "-checksums", name, sums}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if code := run([]string{"verify", sums}, ioutil.Discard, ioutil.Discard); code != exitOK {
+		t.Errorf("expected verify to check the checksums, got: %d", code)
+	}
+
+	back := filepath.Join(dir, "back.cdb")
+	if code := run([]string{"convert", wide, back}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	orig, _ := ioutil.ReadFile(name)
+	got, _ := ioutil.ReadFile(back)
+	if !bytes.Equal(orig, got) {
+		t.Errorf("expected converting back to give the original database")
+	}
+
+	if code := run([]string{"convert", "-hash", "nope", name, back}, ioutil.Discard, ioutil.Discard); code != exitUsage {
+		t.Errorf("expected a usage error for an unknown hash, got: %d", code)
+	}
+}
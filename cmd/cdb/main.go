@@ -0,0 +1,82 @@
+// Command cdb inspects and checks cdb databases.
+//
+// Usage:
+//
+//	cdb <command> [flags] [args]
+//
+// Run cdb help for the list of commands.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/torbit/cdb"
+)
+
+// Exit codes shared by the commands.
+const (
+	exitOK       = 0
+	exitCorrupt  = 1 // The database is corrupt.
+	exitUsage    = 2 // Bad flags or arguments.
+	exitMismatch = 3 // The database doesn't match its checksum or signature.
+	exitIO       = 4 // The database couldn't be read.
+	exitNotFound = 5 // The key isn't in the database.
+)
+
+// A command runs with its arguments, writing to stdout and stderr, and returns
+// the exit code.
+type command struct {
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{}
+
+// stdin is the input of the commands that read records, replaced by tests.
+var stdin io.Reader = os.Stdin
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "cdb: unknown command %q\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+// readExitCode returns the exit code for an error reading a database:
+// exitCorrupt if the database is damaged, and exitIO otherwise.
+func readExitCode(err error) int {
+	if errors.Is(err, cdb.ErrCorrupt) || errors.Is(err, cdb.ErrInvalidDatabase) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return exitCorrupt
+	}
+	return exitIO
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: cdb <command> [flags] [args]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+}
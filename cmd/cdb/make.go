@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/torbit/cdb"
+	// Registers the zstd decompressor, so make reads zstd input.
+	_ "github.com/torbit/cdb/cdbzstd"
+)
+
+func init() {
+	commands["make"] = command{
+		usage: "make [-json | -tsv] out.cdb < records",
+		run:   runMake,
+	}
+	commands["dump"] = command{
+		usage: "dump [-json] db.cdb",
+		run:   runDump,
+	}
+	commands["get"] = command{
+		usage: "get [-skip n] db.cdb key",
+		run:   runGet,
+	}
+}
+
+// runMake builds a database from cdbmake records on stdin, like cdbmake, from
+// JSON Lines with -json, or from tab separated keys and values with -tsv. The
+// input may be compressed with gzip or zstd.
+// The database is built with cdb.CreateAtomic, so readers of out.cdb never
+// see a partial database.
+func runMake(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("make", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonIn := fs.Bool("json", false, "read JSON Lines instead of cdbmake records")
+	tsvIn := fs.Bool("tsv", false, "read a key and a value per line, separated by a tab")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 || *jsonIn && *tsvIn {
+		fs.Usage()
+		return exitUsage
+	}
+
+	err := cdb.CreateAtomic(fs.Arg(0), func(w *cdb.Writer) error {
+		switch {
+		case *jsonIn:
+			return w.PutJSONLines(stdin)
+		case *tsvIn:
+			return w.PutTSV(stdin)
+		}
+		return w.PutRecords(stdin)
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb make: %v\n", err)
+		return exitIO
+	}
+	return exitOK
+}
+
+// runDump writes the records of a database to stdout as cdbmake records, like
+// cdbdump, or as JSON Lines with -json.
+func runDump(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonOut := fs.Bool("json", false, "write JSON Lines instead of cdbmake records")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb dump: %v\n", err)
+		return exitIO
+	}
+	defer f.Close()
+	if *jsonOut {
+		err = cdb.DumpJSON(stdout, cdb.New(f))
+	} else {
+		err = cdb.Dump(stdout, f)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb dump: %v\n", err)
+		return readExitCode(err)
+	}
+	return exitOK
+}
+
+// runGet writes the first value of a key to stdout, like cdbget. With -skip,
+// the first n values are skipped.
+func runGet(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	skip := fs.Int("skip", 0, "number of values to skip")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 || *skip < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	db, err := cdb.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb get: %v\n", err)
+		return exitIO
+	}
+	defer db.Close()
+	iter := db.Iterate([]byte(fs.Arg(1)))
+	for i := 0; ; i++ {
+		val, err := iter.NextReader()
+		if err == cdb.ErrNotFound {
+			return exitNotFound
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "cdb get: %v\n", err)
+			return exitCorrupt
+		}
+		if i < *skip {
+			continue
+		}
+		if _, err := io.Copy(stdout, val); err != nil {
+			fmt.Fprintf(stderr, "cdb get: %v\n", err)
+			return exitIO
+		}
+		return exitOK
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestMakeDumpGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	records := "+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n\n"
+	stdin = strings.NewReader(records)
+	name := filepath.Join(dir, "out.cdb")
+	var stderr bytes.Buffer
+	if code := run([]string{"make", name}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	var stdout bytes.Buffer
+	if code := run([]string{"dump", name}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stdout.String() != records {
+		t.Errorf("expected %q, got: %q", records, stdout.String())
+	}
+
+	jsonl := `{"key":"one","value":"1"}` + "\n" + `{"key":"two","value":"2"}` + "\n" + `{"key":"two","value":"22"}` + "\n"
+	stdout.Reset()
+	if code := run([]string{"dump", "-json", name}, &stdout, &stderr); code != exitOK || stdout.String() != jsonl {
+		t.Errorf("expected %q, got: %d %q", jsonl, code, stdout.String())
+	}
+	stdin = strings.NewReader(jsonl)
+	fromJSON := filepath.Join(dir, "json.cdb")
+	if code := run([]string{"make", "-json", fromJSON}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	stdout.Reset()
+	if run([]string{"dump", fromJSON}, &stdout, &stderr); stdout.String() != records {
+		t.Errorf("expected the JSON Lines to make the same records, got: %q", stdout.String())
+	}
+
+	for _, tc := range []struct {
+		args []string
+		code int
+		out  string
+	}{
+		{[]string{"get", name, "two"}, exitOK, "2"},
+		{[]string{"get", "-skip", "1", name, "two"}, exitOK, "22"},
+		{[]string{"get", "-skip", "2", name, "two"}, exitNotFound, ""},
+		{[]string{"get", name, "three"}, exitNotFound, ""},
+	} {
+		stdout.Reset()
+		if code := run(tc.args, &stdout, ioutil.Discard); code != tc.code || stdout.String() != tc.out {
+			t.Errorf("%v: expected %d, %q, got: %d, %q", tc.args, tc.code, tc.out, code, stdout.String())
+		}
+	}
+}
+
+func TestMakeBadInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	stdin = strings.NewReader("garbage")
+	name := filepath.Join(dir, "out.cdb")
+	if code := run([]string{"make", name}, ioutil.Discard, ioutil.Discard); code == exitOK {
+		t.Fatal("expected make to fail")
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected no files to be left behind, got: %d", len(files))
+	}
+}
+
+func TestMakeZstd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	records := "+3,1:one->1\n\n"
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin = bytes.NewReader(enc.EncodeAll([]byte(records), nil))
+	name := filepath.Join(dir, "out.cdb")
+	var stderr bytes.Buffer
+	if code := run([]string{"make", name}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	var stdout bytes.Buffer
+	if code := run([]string{"dump", name}, &stdout, &stderr); code != exitOK || stdout.String() != records {
+		t.Errorf("expected %q, got: %d %q", records, code, stdout.String())
+	}
+	// Nothing is left behind but the database.
+	if files, err := ioutil.ReadDir(dir); err != nil || len(files) != 1 {
+		t.Errorf("expected only the database in the directory, got: %d files, %v", len(files), err)
+	}
+}
+
+func TestMakeCompressedTSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("one\t1\ntwo\t2\ntwo\t22\nq\tsay \"hi\"\n"))
+	zw.Close()
+	stdin = &gz
+	name := filepath.Join(dir, "out.cdb")
+	var stderr bytes.Buffer
+	if code := run([]string{"make", "-tsv", name}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	var stdout bytes.Buffer
+	records := "+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n+1,8:q->say \"hi\"\n\n"
+	if code := run([]string{"dump", name}, &stdout, &stderr); code != exitOK || stdout.String() != records {
+		t.Errorf("expected %q, got: %d %q", records, code, stdout.String())
+	}
+
+	if code := run([]string{"make", "-json", "-tsv", name}, ioutil.Discard, ioutil.Discard); code != exitUsage {
+		t.Errorf("expected a usage error for -json with -tsv, got: %d", code)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/torbit/cdb"
+)
+
+func init() {
+	commands["rebuild"] = command{
+		usage: "rebuild damaged.cdb dst.cdb",
+		run:   runRebuild,
+	}
+}
+
+// runRebuild writes a copy of a database with its hash tables rebuilt from
+// the records.
+func runRebuild(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rebuild", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	src, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb rebuild: %v\n", err)
+		return exitIO
+	}
+	defer src.Close()
+	dst, err := os.Create(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb rebuild: %v\n", err)
+		return exitIO
+	}
+	err = cdb.RebuildTables(src, dst)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fs.Arg(1))
+		fmt.Fprintf(stderr, "cdb rebuild: %v\n", err)
+		if errors.Is(err, cdb.ErrCorrupt) || errors.Is(err, cdb.ErrInvalidDatabase) {
+			return exitCorrupt
+		}
+		return exitIO
+	}
+	return exitOK
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRebuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := newDB(t, dir)
+	orig, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Overwrite the hash tables, which follow the three records.
+	damaged := append([]byte(nil), orig...)
+	for i := 2048 + 3*8 + len("one1two2two22"); i < len(damaged); i++ {
+		damaged[i] = 0
+	}
+	if err := ioutil.WriteFile(name, damaged, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"verify", name}, ioutil.Discard, ioutil.Discard); code != exitCorrupt {
+		t.Fatalf("expected the damaged database to be corrupt, got: %d", code)
+	}
+
+	fixed := filepath.Join(dir, "fixed.cdb")
+	var stderr bytes.Buffer
+	if code := run([]string{"rebuild", name, fixed}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if got, _ := ioutil.ReadFile(fixed); !bytes.Equal(got, orig) {
+		t.Errorf("expected the rebuilt database to match the original")
+	}
+}
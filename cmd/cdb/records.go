@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/torbit/cdb"
+)
+
+func init() {
+	commands["head"] = command{
+		usage: "head [-n count] [-json] db.cdb",
+		run:   func(args []string, stdout, stderr io.Writer) int { return runRecords("head", args, stdout, stderr) },
+	}
+	commands["tail"] = command{
+		usage: "tail [-n count] [-json] db.cdb",
+		run:   func(args []string, stdout, stderr io.Writer) int { return runRecords("tail", args, stdout, stderr) },
+	}
+	commands["sample"] = command{
+		usage: "sample [-n count] [-seed n] [-json] db.cdb",
+		run:   func(args []string, stdout, stderr io.Writer) int { return runRecords("sample", args, stdout, stderr) },
+	}
+}
+
+// errEnough stops a scan once enough records have been seen.
+var errEnough = errors.New("enough records")
+
+// runRecords writes the first, last or a random sample of records of a
+// database, in data order, as cdbmake records or JSON Lines.
+func runRecords(name string, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	n := fs.Int("n", 10, "number of records")
+	jsonOut := fs.Bool("json", false, "write JSON Lines instead of cdbmake records")
+	seed := fs.Int64("seed", 0, "random seed for sample; 0 picks one from the time")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 || *n < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	db, err := cdb.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb %s: %v\n", name, err)
+		return exitIO
+	}
+	defer db.Close()
+
+	// Only the positions of records are kept, so tail and sample don't hold
+	// values in memory. Once recs is full, tail uses it as a ring of the
+	// last n records, and sample replaces records at random, remembering
+	// their numbers to put them back in data order.
+	type record struct {
+		key, val *io.SectionReader
+		num      int
+	}
+	var recs []record
+	var seen int
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rnd := rand.New(rand.NewSource(*seed))
+	err = db.ForEachReader(func(key, val *io.SectionReader) error {
+		rec := record{key, val, seen}
+		seen++
+		switch {
+		case *n == 0:
+			return errEnough
+		case len(recs) < *n:
+			recs = append(recs, rec)
+		case name == "head":
+			return errEnough
+		case name == "tail":
+			recs[rec.num%*n] = rec
+		case name == "sample":
+			// Reservoir sampling.
+			if i := rnd.Intn(seen); i < *n {
+				recs[i] = rec
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errEnough {
+		fmt.Fprintf(stderr, "cdb %s: %v\n", name, err)
+		return readExitCode(err)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].num < recs[j].num })
+
+	w := bufio.NewWriter(stdout)
+	for _, rec := range recs {
+		key, err := ioutil.ReadAll(rec.key)
+		if err != nil {
+			fmt.Fprintf(stderr, "cdb %s: %v\n", name, err)
+			return exitIO
+		}
+		val, err := ioutil.ReadAll(rec.val)
+		if err != nil {
+			fmt.Fprintf(stderr, "cdb %s: %v\n", name, err)
+			return exitIO
+		}
+		if *jsonOut {
+			cdb.WriteJSONLine(w, key, val)
+		} else {
+			fmt.Fprintf(w, "+%d,%d:%s->%s\n", len(key), len(val), key, val)
+		}
+	}
+	if !*jsonOut {
+		w.WriteString("\n")
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(stderr, "cdb %s: %v\n", name, err)
+		return exitIO
+	}
+	return exitOK
+}
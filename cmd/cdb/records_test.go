@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := newDB(t, dir)
+
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"head", "-n", "2", name}, "+3,1:one->1\n+3,1:two->2\n\n"},
+		{[]string{"tail", "-n", "2", name}, "+3,1:two->2\n+3,2:two->22\n\n"},
+		{[]string{"tail", "-n", "1", name}, "+3,2:two->22\n\n"},
+		{[]string{"head", "-n", "1", "-json", name}, `{"key":"one","value":"1"}` + "\n"},
+		{[]string{"sample", "-n", "5", name}, "+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n\n"},
+		{[]string{"head", "-n", "0", name}, "\n"},
+	} {
+		var stdout bytes.Buffer
+		if code := run(tc.args, &stdout, ioutil.Discard); code != exitOK {
+			t.Errorf("%v: exit code %d", tc.args, code)
+		}
+		if stdout.String() != tc.expected {
+			t.Errorf("%v: expected %q, got: %q", tc.args, tc.expected, stdout.String())
+		}
+	}
+
+	var stdout bytes.Buffer
+	if code := run([]string{"sample", "-n", "2", "-seed", "7", name}, &stdout, ioutil.Discard); code != exitOK {
+		t.Fatalf("sample exit code %d", code)
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 2 sampled records, got: %q", stdout.String())
+	}
+	// The sample is in data order.
+	order := "+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n"
+	if strings.Index(order, lines[0]) >= strings.Index(order, lines[1]) {
+		t.Errorf("expected the sample in data order, got: %q", stdout.String())
+	}
+}
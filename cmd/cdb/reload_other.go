@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import (
+	"io"
+
+	"github.com/torbit/cdb/cdbhttp"
+)
+
+// reloadOnHangup does nothing, as there is no SIGHUP on this platform.
+func reloadOnHangup(s *cdbhttp.Server, name string, stderr io.Writer) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/torbit/cdb/cdbhttp"
+)
+
+// reloadOnHangup reloads the named file into s on every SIGHUP, until the
+// returned func is called.
+func reloadOnHangup(s *cdbhttp.Server, name string, stderr io.Writer) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reload(s, name, stderr)
+		}
+	}()
+	return func() { signal.Stop(hup) }
+}
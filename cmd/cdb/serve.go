@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/torbit/cdb"
+	"github.com/torbit/cdb/cdbhttp"
+)
+
+func init() {
+	commands["serve"] = command{
+		usage: "serve [-listen addr] db.cdb",
+		run:   runServe,
+	}
+}
+
+// runServe serves the values of a database over HTTP until it fails. On Unix,
+// the file is opened again on SIGHUP, so a new version can be renamed over it.
+func runServe(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", ":8080", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	db, err := cdb.Open(name, cdb.EagerHeader())
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb serve: %v\n", err)
+		return exitIO
+	}
+	s := cdbhttp.NewServer(db)
+
+	defer reloadOnHangup(s, name, stderr)()
+
+	err = http.ListenAndServe(*listen, s)
+	fmt.Fprintf(stderr, "cdb serve: %v\n", err)
+	return exitIO
+}
+
+// reload opens the named file again and serves it in place of the old one,
+// which is kept if the new one can't be opened.
+func reload(s *cdbhttp.Server, name string, stderr io.Writer) {
+	db, err := cdb.Open(name, cdb.EagerHeader())
+	if err != nil {
+		fmt.Fprintf(stderr, "cdb serve: reload: %v\n", err)
+		return
+	}
+	s.Swap(db).Close()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/torbit/cdb"
+	"github.com/torbit/cdb/cdbhttp"
+)
+
+func TestServeReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := newDB(t, dir)
+	db, err := cdb.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := cdbhttp.NewServer(db)
+
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader("+3,3:one->new\n\n")
+	if code := run([]string{"make", filepath.Join(dir, "test.cdb")}, ioutil.Discard, ioutil.Discard); code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	reload(s, name, ioutil.Discard)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/one", nil))
+	if rec.Body.String() != "new" {
+		t.Errorf("expected the reloaded database to be served, got: %q", rec.Body.String())
+	}
+
+	// A file that can't be opened keeps the old database.
+	os.Remove(name)
+	reload(s, name, ioutil.Discard)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/one", nil))
+	if rec.Body.String() != "new" {
+		t.Errorf("expected the old database to be kept, got: %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/torbit/cdb"
+)
+
+func init() {
+	commands["verify"] = command{
+		usage: "verify [-json] [-sha256 hex] [-pubkey file -sig file] db.cdb",
+		run:   runVerify,
+	}
+}
+
+// verifyResult is the JSON output of verify.
+type verifyResult struct {
+	File    string `json:"file"`
+	OK      bool   `json:"ok"`
+	Status  string `json:"status"`
+	Tables  int    `json:"tables"`
+	Records int64  `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	jsonOut := fs.Bool("json", false, "write the result as JSON")
+	sum := fs.String("sha256", "", "expected hex SHA-256 of the whole file")
+	pubkey := fs.String("pubkey", "", "file holding the raw Ed25519 public key to check -sig with")
+	sig := fs.String("sig", "", "file holding the raw Ed25519ph signature of the whole file")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 || (*pubkey == "") != (*sig == "") {
+		fs.Usage()
+		return exitUsage
+	}
+
+	res := verifyResult{File: fs.Arg(0), Status: "ok"}
+	code := verifyFile(&res, *sum, *pubkey, *sig)
+	res.OK = code == exitOK
+	if *jsonOut {
+		json.NewEncoder(stdout).Encode(res)
+	} else if res.OK {
+		fmt.Fprintf(stdout, "%s: ok, %d records\n", res.File, res.Records)
+	} else {
+		fmt.Fprintf(stdout, "%s: %s: %s\n", res.File, res.Status, res.Error)
+	}
+	return code
+}
+
+func verifyFile(res *verifyResult, sum, pubkey, sig string) int {
+	fail := func(code int, status string, err error) int {
+		res.Status, res.Error = status, err.Error()
+		return code
+	}
+
+	db, err := cdb.Open(res.File)
+	if err != nil {
+		return fail(exitIO, "io_error", err)
+	}
+	defer db.Close()
+	err = db.Verify(cdb.VerifyOnProgress(func(p cdb.VerifyProgress) {
+		res.Tables, res.Records = p.Tables, p.Records
+	}))
+	if err == nil {
+		// Databases without checksums only have their structure checked.
+		if err = db.VerifyChecksums(); err == cdb.ErrNoChecksums {
+			err = nil
+		}
+	}
+	if errors.Is(err, cdb.ErrCorrupt) || errors.Is(err, cdb.ErrInvalidDatabase) {
+		return fail(exitCorrupt, "corrupt", err)
+	} else if err != nil {
+		return fail(exitIO, "io_error", err)
+	}
+
+	if sum == "" && pubkey == "" {
+		return exitOK
+	}
+	want, err := hex.DecodeString(sum)
+	if err != nil {
+		return fail(exitUsage, "usage", fmt.Errorf("bad -sha256: %v", err))
+	}
+	var key, signature []byte
+	if pubkey != "" {
+		if key, err = ioutil.ReadFile(pubkey); err != nil {
+			return fail(exitIO, "io_error", err)
+		}
+		if signature, err = ioutil.ReadFile(sig); err != nil {
+			return fail(exitIO, "io_error", err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return fail(exitUsage, "usage", fmt.Errorf("public key is %d bytes, expected %d", len(key), ed25519.PublicKeySize))
+		}
+	}
+
+	// Hash the file once for both checks.
+	h256, h512 := sha256.New(), sha512.New()
+	f, err := os.Open(res.File)
+	if err != nil {
+		return fail(exitIO, "io_error", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(io.MultiWriter(h256, h512), f); err != nil {
+		return fail(exitIO, "io_error", err)
+	}
+	if sum != "" && !bytes.Equal(h256.Sum(nil), want) {
+		return fail(exitMismatch, "checksum_mismatch", fmt.Errorf("sha256 is %x, expected %s", h256.Sum(nil), sum))
+	}
+	if key != nil {
+		opts := &ed25519.Options{Hash: crypto.SHA512}
+		if err := ed25519.VerifyWithOptions(ed25519.PublicKey(key), h512.Sum(nil), signature, opts); err != nil {
+			return fail(exitMismatch, "signature_invalid", err)
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+// newDB writes a small database to a file in dir and returns its name.
+func newDB(t *testing.T, dir string) string {
+	name := filepath.Join(dir, "test.cdb")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := cdb.NewWriter(f)
+	for _, kv := range [][2]string{{"one", "1"}, {"two", "2"}, {"two", "22"}} {
+		if err := w.Write([]byte(kv[0]), []byte(kv[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := newDB(t, dir)
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(b)
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha512.Sum512(b)
+	sig, err := priv.Sign(nil, digest[:], &ed25519.Options{Hash: crypto.SHA512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubName, sigName := filepath.Join(dir, "pub"), filepath.Join(dir, "sig")
+	ioutil.WriteFile(pubName, pub, 0644)
+	ioutil.WriteFile(sigName, sig, 0644)
+
+	corrupt := filepath.Join(dir, "corrupt.cdb")
+	ioutil.WriteFile(corrupt, b[:len(b)-8], 0644)
+
+	for _, tc := range []struct {
+		args   []string
+		code   int
+		status string
+	}{
+		{[]string{name}, exitOK, "ok"},
+		{[]string{"-sha256", hex.EncodeToString(sum[:]), name}, exitOK, "ok"},
+		{[]string{"-sha256", "00", name}, exitMismatch, "checksum_mismatch"},
+		{[]string{"-pubkey", pubName, "-sig", sigName, name}, exitOK, "ok"},
+		{[]string{"-pubkey", pubName, "-sig", pubName, name}, exitMismatch, "signature_invalid"},
+		{[]string{corrupt}, exitCorrupt, "corrupt"},
+		{[]string{filepath.Join(dir, "missing")}, exitIO, "io_error"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(append([]string{"verify", "-json"}, tc.args...), &stdout, &stderr)
+		var res verifyResult
+		if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+			t.Fatalf("%v: bad JSON output %q: %v", tc.args, stdout.String(), err)
+		}
+		if code != tc.code || res.Status != tc.status || res.OK != (tc.code == exitOK) {
+			t.Errorf("%v: expected %d %s, got: %d %+v", tc.args, tc.code, tc.status, code, res)
+		}
+	}
+	if res := run([]string{"verify"}, ioutil.Discard, ioutil.Discard); res != exitUsage {
+		t.Errorf("expected usage exit code without a file, got: %d", res)
+	}
+}
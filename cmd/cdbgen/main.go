@@ -0,0 +1,161 @@
+// Command cdbgen generates Go source embedding a cdb database, for shipping
+// small lookup tables inside binaries without any file I/O.
+//
+// Usage:
+//
+//	cdbgen [-text] [-pkg name] [-var name] [-prefix name] [-o file] input
+//
+// The input is a cdb file, or with -text, records in the cdbmake format
+// accepted by cdb.Make. The generated file holds the database bytes, a
+// *cdb.Cdb variable reading them, and getters returning values as strings.
+// It is typically run from a go:generate directive:
+//
+//	//go:generate cdbgen -pkg colors -o colors_cdb.go colors.txt
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/torbit/cdb"
+)
+
+func main() {
+	text := flag.Bool("text", false, "the input is cdbmake records instead of a cdb file")
+	pkg := flag.String("pkg", "main", "package name of the generated file")
+	name := flag.String("var", "DB", "name of the generated *cdb.Cdb variable")
+	prefix := flag.String("prefix", "", "prefix for the names of the generated getters")
+	out := flag.String("o", "", "output file; defaults to standard output")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: cdbgen [flags] input")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := ioutil.ReadFile(flag.Arg(0))
+	if err == nil && *text {
+		data, err = makeDB(data)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := generate(w, *pkg, *name, *prefix, data); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "cdbgen: %v\n", err)
+	os.Exit(1)
+}
+
+// makeDB builds a database from cdbmake records.
+func makeDB(records []byte) ([]byte, error) {
+	tmp, err := ioutil.TempFile("", "cdbgen")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := cdb.Make(tmp, bytes.NewReader(records)); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(tmp.Name())
+}
+
+var tmpl = template.Must(template.New("").Parse(`// Code generated by cdbgen; DO NOT EDIT.
+
+package {{.Pkg}}
+
+import (
+	"strings"
+
+	"github.com/torbit/cdb"
+)
+
+// {{.Var}} is an embedded cdb database of {{.Size}} bytes.
+var {{.Var}} = cdb.New(strings.NewReader({{.Data}}))
+
+const {{.Data}} = "" +
+{{range .Lines}}	{{printf "%q" .}} +
+{{end}}	""
+
+// {{.Prefix}}Get returns the first value for key in {{.Var}}, and whether there
+// was one.
+func {{.Prefix}}Get(key string) (string, bool) {
+	b, err := {{.Var}}.Bytes([]byte(key))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// {{.Prefix}}GetAll returns all the values for key in {{.Var}}.
+func {{.Prefix}}GetAll(key string) []string {
+	var values []string
+	iter := {{.Var}}.Iterate([]byte(key))
+	for {
+		b, err := iter.NextBytes()
+		if err != nil {
+			return values
+		}
+		values = append(values, string(b))
+	}
+}
+`))
+
+// generate writes the Go source embedding the database data to w.
+func generate(w io.Writer, pkg, name, prefix string, data []byte) error {
+	if _, err := cdb.New(bytes.NewReader(data)).Exists(nil); err != nil {
+		return fmt.Errorf("input isn't a cdb file: %v", err)
+	}
+	size := len(data)
+	// Split the data into lines of a readable length.
+	var lines []string
+	for len(data) > 0 {
+		n := 32
+		if n > len(data) {
+			n = len(data)
+		}
+		lines = append(lines, string(data[:n]))
+		data = data[n:]
+	}
+	var src bytes.Buffer
+	err := tmpl.Execute(&src, map[string]interface{}{
+		"Pkg":    pkg,
+		"Var":    name,
+		"Data":   strings.ToLower(name) + "Data",
+		"Size":   size,
+		"Prefix": prefix,
+		"Lines":  lines,
+	})
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
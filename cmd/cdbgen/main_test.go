@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	data, err := makeDB([]byte("+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n\n"))
+	if err != nil {
+		t.Fatalf("makeDB error: %v", err)
+	}
+	var src bytes.Buffer
+	if err := generate(&src, "tables", "Numbers", "Number", data); err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "gen.go", src.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("generated source doesn't parse: %v\n%s", err, src.Bytes())
+	}
+	if f.Name.Name != "tables" {
+		t.Errorf("expected package tables, got: %s", f.Name.Name)
+	}
+	decls := map[string]bool{}
+	var embedded []byte
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			decls[n.Name.Name] = true
+		case *ast.ValueSpec:
+			decls[n.Names[0].Name] = true
+			if n.Names[0].Name == "numbersData" {
+				// Concatenate the string literals.
+				ast.Inspect(n.Values[0], func(n ast.Node) bool {
+					if lit, ok := n.(*ast.BasicLit); ok {
+						s, err := strconv.Unquote(lit.Value)
+						if err != nil {
+							t.Fatal(err)
+						}
+						embedded = append(embedded, s...)
+					}
+					return true
+				})
+			}
+		}
+		return true
+	})
+	for _, name := range []string{"Numbers", "numbersData", "NumberGet", "NumberGetAll"} {
+		if !decls[name] {
+			t.Errorf("expected a declaration of %s", name)
+		}
+	}
+	if !bytes.Equal(embedded, data) {
+		t.Errorf("embedded data doesn't match the database")
+	}
+
+	if err := generate(&src, "tables", "DB", "", []byte("short")); err == nil {
+		t.Errorf("expected an error for input that isn't a cdb file")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+)
+
+// setErr stores a copy of err's message in *errp, if errp isn't NULL.
+func setErr(errp **C.char, err error) {
+	if errp != nil {
+		*errp = C.CString(err.Error())
+	}
+}
+
+// setBytes stores a copy of b in *p and its length in *n.
+func setBytes(p *unsafe.Pointer, n *C.size_t, b []byte) {
+	*p = C.CBytes(b)
+	*n = C.size_t(len(b))
+}
+
+//export cdb_open
+func cdb_open(path *C.char, errp **C.char) uintptr {
+	h, err := openDB(C.GoString(path))
+	if err != nil {
+		setErr(errp, err)
+		return 0
+	}
+	return uintptr(h)
+}
+
+//export cdb_get
+func cdb_get(db uintptr, key unsafe.Pointer, klen C.size_t, val *unsafe.Pointer, vlen *C.size_t, errp **C.char) C.int {
+	v, err := get(cgo.Handle(db), C.GoBytes(key, C.int(klen)))
+	if err != nil {
+		setErr(errp, err)
+		return -1
+	}
+	if v == nil {
+		return 0
+	}
+	setBytes(val, vlen, v)
+	return 1
+}
+
+//export cdb_verify
+func cdb_verify(db uintptr, errp **C.char) C.int {
+	if err := verify(cgo.Handle(db)); err != nil {
+		setErr(errp, err)
+		return -1
+	}
+	return 0
+}
+
+//export cdb_close
+func cdb_close(db uintptr, errp **C.char) C.int {
+	if err := closeDB(cgo.Handle(db)); err != nil {
+		setErr(errp, err)
+		return -1
+	}
+	return 0
+}
+
+//export cdb_iter
+func cdb_iter(db uintptr) uintptr {
+	return uintptr(newIter(cgo.Handle(db)))
+}
+
+//export cdb_iter_next
+func cdb_iter_next(it uintptr, key *unsafe.Pointer, klen *C.size_t, val *unsafe.Pointer, vlen *C.size_t, errp **C.char) C.int {
+	iter := iterFor(cgo.Handle(it))
+	if !iter.next() {
+		if iter.err != nil {
+			setErr(errp, iter.err)
+			return -1
+		}
+		return 0
+	}
+	setBytes(key, klen, iter.key)
+	setBytes(val, vlen, iter.val)
+	return 1
+}
+
+//export cdb_iter_free
+func cdb_iter_free(it uintptr) {
+	cgo.Handle(it).Delete()
+}
+
+//export cdb_free
+func cdb_free(p unsafe.Pointer) {
+	C.free(p)
+}
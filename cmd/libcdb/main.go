@@ -0,0 +1,128 @@
+// Command libcdb builds the package as a C shared library, so programs in
+// other languages, like Python with ctypes or Ruby with FFI, can read
+// databases with this implementation and its extensions, such as the 64 bit
+// layout and record checksums, instead of a separate C library.
+//
+// Build it with:
+//
+//	go build -buildmode=c-shared -o libcdb.so ./cmd/libcdb
+//
+// which also writes libcdb.h, declaring:
+//
+//	GoUintptr cdb_open(char* path, char** errp);
+//	int cdb_get(GoUintptr db, void* key, size_t klen, void** val, size_t* vlen, char** errp);
+//	int cdb_verify(GoUintptr db, char** errp);
+//	int cdb_close(GoUintptr db, char** errp);
+//	GoUintptr cdb_iter(GoUintptr db);
+//	int cdb_iter_next(GoUintptr it, void** key, size_t* klen, void** val, size_t* vlen, char** errp);
+//	void cdb_iter_free(GoUintptr it);
+//	void cdb_free(void* p);
+//
+// Databases and iterators are passed around as handles. Cdb_open returns 0,
+// and the other functions -1, on failure, with a message in *errp if errp
+// isn't NULL. Cdb_get and cdb_iter_next return 1 for a record and 0 when
+// there is none. Messages, keys and values are copied into memory allocated
+// with malloc, which the caller frees with cdb_free.
+//
+// Handles are threadsafe, except that an iterator must only be used by one
+// thread at a time. A database must not be closed while iterators over it
+// are in use.
+package main
+
+import (
+	"runtime/cgo"
+
+	"github.com/torbit/cdb"
+)
+
+// The library has no main, but c-shared builds still need one.
+func main() {}
+
+func openDB(path string) (cgo.Handle, error) {
+	db, err := cdb.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	return cgo.NewHandle(db), nil
+}
+
+func dbFor(h cgo.Handle) *cdb.Cdb {
+	return h.Value().(*cdb.Cdb)
+}
+
+// get returns the first value for key, or nil if there isn't one.
+func get(h cgo.Handle, key []byte) ([]byte, error) {
+	val, err := dbFor(h).Bytes(key)
+	if err == cdb.ErrNotFound {
+		return nil, nil
+	}
+	if val == nil {
+		val = []byte{}
+	}
+	return val, err
+}
+
+// verify checks the database's structure, and its records' checksums if it
+// has them.
+func verify(h cgo.Handle) error {
+	db := dbFor(h)
+	if err := db.Verify(); err != nil {
+		return err
+	}
+	if err := db.VerifyChecksums(); err != cdb.ErrNoChecksums {
+		return err
+	}
+	return nil
+}
+
+func closeDB(h cgo.Handle) error {
+	db := dbFor(h)
+	h.Delete()
+	return db.Close()
+}
+
+// iterator visits the records of a database. The values of a key are visited
+// together, in the order the keys were first written.
+type iterator struct {
+	db    *cdb.Cdb
+	token []byte
+	keys  [][]byte
+	vals  [][]byte
+	key   []byte
+	val   []byte
+	done  bool
+	err   error
+}
+
+// iterPage is the number of keys an iterator lists at a time.
+const iterPage = 64
+
+func newIter(h cgo.Handle) cgo.Handle {
+	return cgo.NewHandle(&iterator{db: dbFor(h)})
+}
+
+func iterFor(h cgo.Handle) *iterator {
+	return h.Value().(*iterator)
+}
+
+// next moves to the next record, and returns false once there are none left
+// or an error happened.
+func (it *iterator) next() bool {
+	for len(it.vals) == 0 {
+		if it.err != nil {
+			return false
+		}
+		if len(it.keys) == 0 {
+			if it.done {
+				return false
+			}
+			it.keys, it.token, it.err = it.db.ListKeys(it.token, iterPage)
+			it.done = it.token == nil
+			continue
+		}
+		it.key, it.keys = it.keys[0], it.keys[1:]
+		it.vals, it.err = it.db.AllValues(it.key)
+	}
+	it.val, it.vals = it.vals[0], it.vals[1:]
+	return true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/torbit/cdb"
+)
+
+func TestLibrary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.cdb")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "+3,1:one->1\n+3,1:two->2\n+3,2:two->22\n+5,0:empty->\n\n"
+	if err := cdb.Make(f, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	h, err := openDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string][]byte{"two": []byte("2"), "empty": {}, "missing": nil} {
+		if val, err := get(h, []byte(key)); !reflect.DeepEqual(val, expected) || err != nil {
+			t.Errorf("%s: expected %q, got: %q, %v", key, expected, val, err)
+		}
+	}
+	if err := verify(h); err != nil {
+		t.Error(err)
+	}
+
+	ih := newIter(h)
+	it := iterFor(ih)
+	var got []string
+	for it.next() {
+		got = append(got, string(it.key)+"="+string(it.val))
+	}
+	ih.Delete()
+	if it.err != nil {
+		t.Fatal(it.err)
+	}
+	if expected := []string{"one=1", "two=2", "two=22", "empty="}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected records %v, got: %v", expected, got)
+	}
+
+	if err := closeDB(h); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openDB(filepath.Join(dir, "missing.cdb")); !os.IsNotExist(err) {
+		t.Errorf("expected a missing file error, got: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package cdb
+
+import "fmt"
+
+// Compact writes the records a Stack's lookups see to out, flattening its
+// layers into one database so lookups don't slow down as deltas pile up. A
+// key's values come from the topmost layer that has it, keys deleted by
+// tombstones are left out, and the tombstones themselves are dropped, since
+// there is nothing left under them. Records are written layer by layer from
+// the bottom up, in the order they were written to each.
+//
+// Every layer must be a Scanner, like Cdb. Compact doesn't close out, so more
+// records can be added before closing it.
+//
+// Threadsafe.
+func Compact(s *Stack, out *Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scanners := make([]Scanner, len(s.layers))
+	for i, layer := range s.layers {
+		scanner, ok := layer.(Scanner)
+		if !ok {
+			return fmt.Errorf("cdb: layer %d is a %T, which can't be scanned", i, layer)
+		}
+		scanners[i] = scanner
+	}
+	for i, scanner := range scanners {
+		above := s.layers[i+1:]
+		err := scanner.ForEachBytes(func(key, val []byte) error {
+			if len(val) == 0 && isTombstoning(s.layers[i]) {
+				return nil
+			}
+			if shadowed, err := shadowed(above, key); shadowed || err != nil {
+				return err
+			}
+			return out.Put(key, val)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTombstoning returns true if g treats empty values as tombstones.
+func isTombstoning(g Getter) bool {
+	c, ok := g.(*Cdb)
+	return ok && c.tombstones
+}
+
+// shadowed returns true if any of layers has key, or a tombstone for it.
+func shadowed(layers []Getter, key []byte) (bool, error) {
+	for _, layer := range layers {
+		if ok, err := layer.Exists(key); ok || err != nil {
+			return ok, err
+		}
+		if del, err := deleted(layer, key); del || err != nil {
+			return del, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,40 @@
+package cdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	base := newDB(records)
+	bw := NewBufferWriter()
+	bw.Put([]byte("two"), []byte("two"))
+	bw.Tombstone([]byte("three"))
+	bw.Put([]byte("four"), []byte("4"))
+	delta, err := bw.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStack(base, New(bytes.NewReader(delta), TombstoneEmptyValues()))
+
+	out := NewBufferWriter()
+	if err := Compact(s, out.Writer); err != nil {
+		t.Fatal(err)
+	}
+	got, err := out.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"one=1", "two=two", "four=4"}
+	if order := dataOrder(t, New(bytes.NewReader(got))); !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got: %v", want, order)
+	}
+}
+
+func TestCompactNeedsScanners(t *testing.T) {
+	s := NewStack(newDB(records), NewStack())
+	if err := Compact(s, NewBufferWriter().Writer); err == nil {
+		t.Error("expected an error for a layer that can't be scanned")
+	}
+}
@@ -0,0 +1,79 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// JoinKey returns the composite key made of parts. Composite keys hold
+// several parts, like a tenant, a type and an id, each written as its
+// length, a uvarint, followed by its bytes. Unlike joining the parts with a
+// separator, this is safe for parts holding any bytes, and keys with
+// different parts never collide.
+func JoinKey(parts ...[]byte) []byte {
+	return AppendKey(nil, parts...)
+}
+
+// AppendKey appends the composite key made of parts to dst and returns the
+// extended buffer. It doesn't allocate if dst has room for the key.
+func AppendKey(dst []byte, parts ...[]byte) []byte {
+	for _, p := range parts {
+		dst = binary.AppendUvarint(dst, uint64(len(p)))
+		dst = append(dst, p...)
+	}
+	return dst
+}
+
+// SplitKey returns the parts of the composite key. The parts share key's
+// memory. Returns an error matching ErrBadKey if key isn't a composite key.
+func SplitKey(key []byte) ([][]byte, error) {
+	var parts [][]byte
+	for pos := 0; pos < len(key); {
+		n, size := binary.Uvarint(key[pos:])
+		if size <= 0 {
+			return nil, fmt.Errorf("%w: bad length at %d", ErrBadKey, pos)
+		}
+		pos += size
+		if n > uint64(len(key)-pos) {
+			return nil, fmt.Errorf("%w: part at %d is %d bytes, only %d left", ErrBadKey, pos, n, len(key)-pos)
+		}
+		parts = append(parts, key[pos:pos+int(n):pos+int(n)])
+		pos += int(n)
+	}
+	return parts, nil
+}
+
+// keyPool holds buffers for building composite keys for lookups.
+var keyPool = sync.Pool{New: func() interface{} { return new([]byte) }}
+
+// withKey calls fn with the composite key made of parts, built in a pooled
+// buffer that is reused once fn returns.
+func withKey(parts [][]byte, fn func(key []byte)) {
+	bp := keyPool.Get().(*[]byte)
+	*bp = AppendKey((*bp)[:0], parts...)
+	fn(*bp)
+	keyPool.Put(bp)
+}
+
+// ExistsParts is Exists for the composite key made of parts, which it builds
+// without allocating.
+//
+// Threadsafe.
+func (c *Cdb) ExistsParts(parts ...[]byte) (ok bool, err error) {
+	withKey(parts, func(key []byte) {
+		ok, err = c.Exists(key)
+	})
+	return ok, err
+}
+
+// BytesParts is Bytes for the composite key made of parts, which it builds
+// without allocating. ValueTransforms mustn't keep the key they are given.
+//
+// Threadsafe.
+func (c *Cdb) BytesParts(parts ...[]byte) (value []byte, err error) {
+	withKey(parts, func(key []byte) {
+		value, err = c.Bytes(key)
+	})
+	return value, err
+}
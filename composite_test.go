@@ -0,0 +1,69 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompositeKeys(t *testing.T) {
+	for _, parts := range [][][]byte{
+		{},
+		{[]byte("tenant"), []byte("type"), []byte("id")},
+		{[]byte("a|b"), {}, []byte("\x00\xff"), bytes.Repeat([]byte("x"), 300)},
+	} {
+		key := JoinKey(parts...)
+		got, err := SplitKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(parts) || (len(parts) > 0 && !reflect.DeepEqual(got, parts)) {
+			t.Errorf("expected parts %q, got: %q", parts, got)
+		}
+	}
+
+	// Parts holding the separator of a naive join don't collide.
+	if bytes.Equal(JoinKey([]byte("a|b"), []byte("c")), JoinKey([]byte("a"), []byte("b|c"))) {
+		t.Error("expected different keys")
+	}
+
+	for _, key := range []string{"\x05abc", "\xff", "\x01a\x02b"} {
+		if _, err := SplitKey([]byte(key)); !errors.Is(err, ErrBadKey) {
+			t.Errorf("%q: expected ErrBadKey, got: %v", key, err)
+		}
+	}
+}
+
+func TestCompositeLookups(t *testing.T) {
+	w := NewBufferWriter()
+	if err := w.Put(JoinKey([]byte("acme"), []byte("user"), []byte("42")), []byte("alice")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(b))
+
+	tenant, typ, id := []byte("acme"), []byte("user"), []byte("42")
+	if ok, err := db.ExistsParts(tenant, typ, id); !ok || err != nil {
+		t.Errorf("expected key to exist, got: %v, %v", ok, err)
+	}
+	if val, err := db.BytesParts(tenant, typ, id); string(val) != "alice" || err != nil {
+		t.Errorf("expected alice, got: %q, %v", val, err)
+	}
+	if _, err := db.BytesParts(tenant, id); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if raceEnabled {
+		t.Skip("the race detector makes ExistsParts allocate")
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		db.ExistsParts(tenant, typ, id)
+	})
+	if allocs != 0 {
+		t.Errorf("expected ExistsParts not to allocate, got %v allocations", allocs)
+	}
+}
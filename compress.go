@@ -0,0 +1,70 @@
+package cdb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrUnsupportedCompression is returned when input is compressed with a
+// known format that has no registered decompressor.
+var ErrUnsupportedCompression = errors.New("unsupported compression format")
+
+type decompressor struct {
+	name  string
+	magic []byte
+	// newReader is nil for formats that are recognized, but need a
+	// decompressor to be registered.
+	newReader func(io.Reader) (io.Reader, error)
+}
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = []decompressor{
+		{"gzip", []byte{0x1f, 0x8b}, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}, nil},
+	}
+)
+
+// RegisterDecompressor registers a decompressor for compressed input to Make.
+// Input that starts with magic is passed through newReader before being
+// parsed. Registering a name that is already registered replaces it.
+//
+// Decompressors for formats that need other packages are registered by
+// importing a subpackage, for example github.com/torbit/cdb/cdbzstd.
+func RegisterDecompressor(name string, magic []byte, newReader func(io.Reader) (io.Reader, error)) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	for i, d := range decompressors {
+		if d.name == name {
+			decompressors[i] = decompressor{name, magic, newReader}
+			return
+		}
+	}
+	decompressors = append(decompressors, decompressor{name, magic, newReader})
+}
+
+// decompress detects compressed input by its magic bytes and returns a reader
+// for the decompressed data. Uncompressed input is returned unchanged.
+func decompress(r *bufio.Reader) (*bufio.Reader, error) {
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+	for _, d := range decompressors {
+		magic, _ := r.Peek(len(d.magic))
+		if !bytes.Equal(magic, d.magic) {
+			continue
+		}
+		if d.newReader == nil {
+			return nil, ErrUnsupportedCompression
+		}
+		dr, err := d.newReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(dr), nil
+	}
+	return r, nil
+}
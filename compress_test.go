@@ -0,0 +1,46 @@
+package cdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMakeGzip(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(data)
+	zw.Close()
+
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := Make(tmp, &gz); err != nil {
+		t.Fatalf("Make failed: %v", err)
+	}
+	b, err := New(tmp).Bytes([]byte("three"))
+	if err != nil {
+		t.Fatalf("Bytes error: %v", err)
+	}
+	if string(b) != "3" {
+		t.Errorf("expected 3, got: %s", b)
+	}
+}
+
+func TestMakeUnregisteredCompression(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	err = Make(tmp, bytes.NewReader([]byte{0x28, 0xb5, 0x2f, 0xfd, 0}))
+	if err != ErrUnsupportedCompression {
+		t.Errorf("expected ErrUnsupportedCompression, got: %v", err)
+	}
+}
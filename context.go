@@ -0,0 +1,78 @@
+package cdb
+
+import (
+	"context"
+	"io"
+)
+
+// BytesContext is like Bytes, but gives up when ctx is done, returning an
+// error that matches ctx.Err() with errors.Is. A read of the underlying
+// ReaderAt that hangs, as reads over a network can, is abandoned rather than
+// waited for.
+//
+// Threadsafe.
+func (c *Cdb) BytesContext(ctx context.Context, key []byte) ([]byte, error) {
+	defer c.observeSince(c.now())
+	iter := iterPool.Get().(*CdbIterator)
+	iter.resetContext(ctx, c, key)
+	b, err := iter.NextBytes()
+	putIter(iter)
+	return b, err
+}
+
+// IterateContext is like Iterate, but the iterator gives up when ctx is done,
+// like BytesContext. The readers returned by NextReader are bound to ctx too.
+//
+// Threadsafe.
+func (c *Cdb) IterateContext(ctx context.Context, key []byte) *CdbIterator {
+	iter := new(CdbIterator)
+	iter.resetContext(ctx, c, key)
+	return iter
+}
+
+// ForEachBytesContext is like ForEachBytes, but stops when ctx is done,
+// returning an error that matches ctx.Err() with errors.Is.
+//
+// Threadsafe.
+func (c *Cdb) ForEachBytesContext(ctx context.Context, onRecordFn func(key, val []byte) error) error {
+	return c.forEachBytes(contextReader(ctx, c.r), onRecordFn)
+}
+
+// contextReader returns r bound to ctx, or r itself if ctx can't be done.
+func contextReader(ctx context.Context, r io.ReaderAt) io.ReaderAt {
+	if ctx.Done() == nil {
+		return r
+	}
+	return &ctxReaderAt{ctx, r}
+}
+
+// ctxReaderAt is a ReaderAt whose reads fail once its context is done. Each
+// read runs in its own goroutine, into its own buffer, so that a read that
+// hangs can be left behind.
+type ctxReaderAt struct {
+	ctx context.Context
+	r   io.ReaderAt
+}
+
+func (r *ctxReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	type result struct {
+		n   int
+		err error
+	}
+	buf := make([]byte, len(p))
+	done := make(chan result, 1)
+	go func() {
+		n, err := r.r.ReadAt(buf, off)
+		done <- result{n, err}
+	}()
+	select {
+	case res := <-done:
+		copy(p, buf[:res.n])
+		return res.n, res.err
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	}
+}
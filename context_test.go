@@ -0,0 +1,87 @@
+package cdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// hangingReaderAt blocks every read until unblock is closed, like a network
+// filesystem that has stopped responding.
+type hangingReaderAt struct {
+	r       io.ReaderAt
+	unblock chan struct{}
+}
+
+func (h *hangingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	<-h.unblock
+	return h.r.ReadAt(p, off)
+}
+
+func TestBytesContext(t *testing.T) {
+	db := newDB(records)
+	ctx, cancel := context.WithCancel(context.Background())
+	if b, err := db.BytesContext(ctx, []byte("two")); err != nil || string(b) != "2" {
+		t.Errorf("expected 2, got: %q, %v", b, err)
+	}
+	if _, err := db.BytesContext(ctx, []byte("missing")); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	cancel()
+	if _, err := db.BytesContext(ctx, []byte("two")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Canceled, got: %v", err)
+	}
+
+	h := &hangingReaderAt{bytes.NewReader(newDBBytes(records)), make(chan struct{})}
+	defer close(h.unblock)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := New(h).BytesContext(ctx, []byte("two")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded from a hanging read, got: %v", err)
+	}
+}
+
+func TestIterateContext(t *testing.T) {
+	db := newDB(records)
+	ctx, cancel := context.WithCancel(context.Background())
+	iter := db.IterateContext(ctx, []byte("three"))
+	if b, err := iter.NextBytes(); err != nil || string(b) != "3" {
+		t.Errorf("expected 3, got: %q, %v", b, err)
+	}
+	r, err := iter.NextReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := iter.NextBytes(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Canceled, got: %v", err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the value reader to be bound to the context, got: %v", err)
+	}
+}
+
+func TestForEachBytesContext(t *testing.T) {
+	db := newDB(records)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	err := db.ForEachBytesContext(ctx, func(key, val []byte) error {
+		n++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || n != 1 {
+		t.Errorf("expected Canceled after 1 record, got: %v after %d", err, n)
+	}
+	n = 0
+	if err := db.ForEachBytesContext(context.Background(), func(key, val []byte) error {
+		n++
+		return nil
+	}); err != nil || n != 6 {
+		t.Errorf("expected 6 records, got: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,88 @@
+package cdb
+
+import "io"
+
+// Format describes the variant of the cdb format a database is stored in.
+type Format struct {
+	// Wide is true for the 64 bit layout of the Cdb64 WriterOption, and
+	// false for classic cdb files.
+	Wide bool
+	// Checksums is true if the database holds record checksums, as written
+	// with the Checksums WriterOption.
+	Checksums bool
+	// Hash is the hash of keys, or nil for the cdb hash.
+	Hash func(key []byte) uint32
+}
+
+// Format returns the format of the database. The hash can't be detected, so
+// it is the one given with the WithHash Option.
+//
+// Threadsafe.
+func (c *Cdb) Format() (Format, error) {
+	lay, err := c.layout()
+	if err != nil {
+		return Format{}, err
+	}
+	sums, err := c.extension(extChecksums)
+	if err != nil {
+		return Format{}, err
+	}
+	return Format{Wide: lay.wide, Checksums: sums != nil, Hash: c.hash}, nil
+}
+
+// writerOptions returns the WriterOptions that build a database in the
+// format.
+func (f Format) writerOptions() []WriterOption {
+	var opts []WriterOption
+	if f.Wide {
+		opts = append(opts, Cdb64())
+	}
+	if f.Checksums {
+		opts = append(opts, Checksums())
+	}
+	if f.Hash != nil {
+		opts = append(opts, Hash(f.Hash))
+	}
+	return opts
+}
+
+// ConvertFormat writes the records of src to dst as a database in format f,
+// in a single pass over src that keeps the records in the same order. The
+// build time and annotations of src are kept too, though annotations can't
+// be converted to the 64 bit layout. Src is only scanned, never looked up in,
+// so it can be opened without WithHash.
+func ConvertFormat(dst io.WriteSeeker, src *Cdb, f Format) error {
+	built, err := src.BuildTime()
+	if err != nil {
+		return err
+	}
+	annotations, err := src.extension(extAnnotations)
+	if err != nil {
+		return err
+	}
+	opts := f.writerOptions()
+	if !built.IsZero() {
+		opts = append(opts, BuildTime(built))
+	}
+	w := NewWriter(dst, opts...)
+	err = src.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		if err := w.copyRecord(src.r, pos, klen, dlen); err != nil {
+			return err
+		}
+		if annotations == nil {
+			return nil
+		}
+		meta, err := src.Annotation(pos)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return w.Annotate(meta)
+	})
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
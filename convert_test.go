@@ -0,0 +1,111 @@
+package cdb
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fnvHash(key []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(key)
+	return h.Sum32()
+}
+
+func convertBytes(t *testing.T, src *Cdb, f Format) []byte {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := ConvertFormat(tmp, src, f); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// dataOrder returns the records of db as key=value strings in data order.
+func dataOrder(t *testing.T, db *Cdb) []string {
+	var out []string
+	if err := db.ForEachBytes(func(key, val []byte) error {
+		out = append(out, string(key)+"="+string(val))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestConvertFormat(t *testing.T) {
+	classic := newDBBytes(records)
+	src := New(bytes.NewReader(classic))
+	f := Format{Wide: true, Checksums: true, Hash: fnvHash}
+	db := New(bytes.NewReader(convertBytes(t, src, f)), WithHash(fnvHash))
+	got, err := db.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Wide || !got.Checksums || got.Hash == nil {
+		t.Errorf("expected a wide database with checksums and a hash, got: %+v", got)
+	}
+	checkRecords(t, db, records)
+	if err := db.VerifyChecksums(); err != nil {
+		t.Errorf("VerifyChecksums: %v", err)
+	}
+	if expected, got := dataOrder(t, src), dataOrder(t, db); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected records in order %v, got: %v", expected, got)
+	}
+
+	// Converting back gives the original file.
+	if b := convertBytes(t, db, Format{}); !bytes.Equal(b, classic) {
+		t.Errorf("expected converting back to give the original database")
+	}
+}
+
+func TestConvertFormatMetadata(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	built := time.Unix(1500000000, 0)
+	w := NewWriter(tmp, BuildTime(built))
+	w.Write([]byte("one"), []byte("1"))
+	w.Write([]byte("two"), []byte("2"))
+	w.Annotate([]byte("from two"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	src := New(tmp)
+
+	db := New(bytes.NewReader(convertBytes(t, src, Format{Checksums: true})))
+	if got, err := db.BuildTime(); err != nil || !got.Equal(built) {
+		t.Errorf("expected build time %v, got: %v, %v", built, got, err)
+	}
+	iter := db.Iterate([]byte("two"))
+	if _, err := iter.NextBytes(); err != nil {
+		t.Fatal(err)
+	}
+	if meta, err := db.Annotation(iter.RecordPos()); err != nil || string(meta) != "from two" {
+		t.Errorf("expected the annotation to be kept, got: %q, %v", meta, err)
+	}
+
+	wide, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(wide.Name())
+	defer wide.Close()
+	if err := ConvertFormat(wide, src, Format{Wide: true}); err == nil {
+		t.Errorf("expected an error converting annotations to the 64 bit layout")
+	}
+}
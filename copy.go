@@ -0,0 +1,109 @@
+package cdb
+
+import "io"
+
+// Handle identifies a record in a database by its file position. The handle
+// of the record last returned by an iterator is given by CdbIterator.Handle.
+type Handle uint64
+
+// Handle returns the handle of the record whose value was last returned by
+// NextBytes or NextReader.
+//
+// Not threadsafe.
+func (iter *CdbIterator) Handle() Handle {
+	return Handle(iter.recordPos())
+}
+
+// CopyRecord writes the record identified by h in src to dst, copying its raw
+// bytes without decoding them. Values are copied as stored, so a
+// ValueTransform on src isn't applied.
+func CopyRecord(dst *Writer, src *Cdb, h Handle) error {
+	lay, err := src.layout()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 16)
+	end, _, err := lay.readEntry(src.r, buf, 0)
+	if err != nil {
+		return err
+	}
+	pos := uint64(h)
+	if pos < lay.headerSize() || pos >= end {
+		return corruptf(pos, "handle outside of the data section")
+	}
+	klen, dlen, err := readNums(src.r, buf, int64(pos))
+	if err != nil {
+		return err
+	}
+	if pos+8+uint64(klen)+uint64(dlen) > end {
+		return corruptf(pos, "record runs past the end of the data section")
+	}
+	return dst.copyRecord(src.r, pos, klen, dlen)
+}
+
+// CopyAll copies the records of src for which filter returns true to dst in
+// data order, like CopyRecord. A nil filter copies every record.
+func CopyAll(dst *Writer, src *Cdb, filter func(key []byte) bool) error {
+	var key []byte
+	return src.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		if filter != nil {
+			if uint32(cap(key)) < klen {
+				key = make([]byte, klen)
+			}
+			key = key[:klen]
+			if err := readFull(src.r, key, int64(pos)+8); err != nil {
+				return err
+			}
+			if !filter(key) {
+				return nil
+			}
+		}
+		return dst.copyRecord(src.r, pos, klen, dlen)
+	})
+}
+
+// copyRecord writes the raw record at pos in r to the builder. The key is
+// read into memory to be hashed, and unless the record has to be teed the
+// value is streamed straight through.
+func (w *Writer) copyRecord(r io.ReaderAt, pos uint64, klen, dlen uint32) error {
+	if err := w.checkErr(); err != nil {
+		return err
+	}
+	if w.tee != nil {
+		raw := make([]byte, int64(klen)+int64(dlen))
+		if err := readFull(r, raw, int64(pos)+8); err != nil {
+			return err
+		}
+		return w.Put(raw[:klen], raw[klen:])
+	}
+	key := make([]byte, klen)
+	if err := readFull(r, key, int64(pos)+8); err != nil {
+		return err
+	}
+	val := io.NewSectionReader(r, int64(pos)+8+int64(klen), int64(dlen))
+	return w.putFrom(key, val, dlen)
+}
+
+// putFrom adds a record whose value is the next dlen bytes of r, streaming
+// it to the builder. The builder then has part of a record if reading r
+// fails, so that stops the build.
+func (w *Writer) putFrom(key []byte, r io.Reader, dlen uint32) error {
+	if skip, err := w.checkDuplicate(key); skip || err != nil {
+		if _, discardErr := io.CopyN(io.Discard, r, int64(dlen)); err == nil && discardErr != nil {
+			err = unexpectedEOF(discardErr)
+		}
+		return err
+	}
+	klen := uint32(len(key))
+	rw := w.recordWriter()
+	if err := w.writeHeader(rw, klen, dlen); err != nil {
+		return err
+	}
+	if _, err := rw.Write(key); err != nil {
+		return w.fail(err)
+	}
+	if _, err := io.CopyN(rw, r, int64(dlen)); err != nil {
+		return w.fail(unexpectedEOF(err))
+	}
+	return w.wrote(key, dlen)
+}
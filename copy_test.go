@@ -0,0 +1,72 @@
+package cdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCopyRecord(t *testing.T) {
+	src := New(bytes.NewReader(newDBBytes(records)))
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var tee bytes.Buffer
+	dst := NewWriter(tmp, Tee(&tee))
+	iter := src.Iterate([]byte("three"))
+	if _, err := iter.NextBytes(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := iter.NextBytes(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyRecord(dst, src, iter.Handle()); err != nil {
+		t.Fatalf("CopyRecord error: %v", err)
+	}
+	if err := CopyRecord(dst, src, Handle(1)); err == nil {
+		t.Errorf("expected an error copying a bad handle")
+	}
+	if err := dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, New(tmp), []rec{{"three", []string{"33"}}})
+	if tee.String() != "+5,2:three->33\n\n" {
+		t.Errorf("unexpected tee output: %q", tee.String())
+	}
+}
+
+func TestCopyAll(t *testing.T) {
+	src := New(bytes.NewReader(newDBBytes(records)))
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	dst := NewWriter(tmp)
+	if err := dst.Write([]byte("zero"), []byte("0")); err != nil {
+		t.Fatal(err)
+	}
+	err = CopyAll(dst, src, func(key []byte) bool { return string(key) != "two" })
+	if err != nil {
+		t.Fatalf("CopyAll error: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db := New(tmp)
+	if _, err := db.Bytes([]byte("two")); err != ErrNotFound {
+		t.Errorf("expected filtered out key to be missing, got: %v", err)
+	}
+	checkRecords(t, db, []rec{
+		{"zero", []string{"0"}},
+		{"one", []string{"1"}},
+		{"three", []string{"3", "33", "333"}},
+	})
+}
@@ -0,0 +1,109 @@
+package cdb
+
+import (
+	"sort"
+	"sync"
+)
+
+// countState holds the number of records and keys in a database, which are
+// counted on first use. Like layoutState, a failed count isn't kept.
+type countState struct {
+	mu      sync.Mutex
+	known   bool
+	records int64
+	keys    int64
+}
+
+// NumRecords returns the number of records in the database, counted from the
+// used hash table slots the first time it is called.
+//
+// Threadsafe.
+func (c *Cdb) NumRecords() (int64, error) {
+	if err := c.count(); err != nil {
+		return 0, err
+	}
+	return c.counts.records, nil
+}
+
+// NumKeys returns the number of distinct keys in the database, counted from
+// the hash tables the first time it is called. Only the keys of slots that
+// share a hash have to be read to tell them apart.
+//
+// Threadsafe.
+func (c *Cdb) NumKeys() (int64, error) {
+	if err := c.count(); err != nil {
+		return 0, err
+	}
+	return c.counts.keys, nil
+}
+
+// count counts the records and keys, if they haven't been counted yet.
+func (c *Cdb) count() error {
+	c.counts.mu.Lock()
+	defer c.counts.mu.Unlock()
+	if c.counts.known {
+		return nil
+	}
+	lay, err := c.layout()
+	if err != nil {
+		return err
+	}
+	type slot struct{ h, pos uint64 }
+	var records, keys int64
+	var slots []slot
+	buf := make([]byte, 16)
+	// Keys are read from the records, which end where the first table starts.
+	dataEnd, _, err := lay.readEntry(c.r, buf, 0)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < 256; i++ {
+		hpos, hslots, err := lay.readTable(c.r, buf, i)
+		if err != nil {
+			return err
+		}
+		slots = slots[:0]
+		err = lay.forEachSlot(c.r, hpos, hslots, func(n, h, pos uint64) error {
+			if pos != 0 {
+				slots = append(slots, slot{h, pos})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		records += int64(len(slots))
+		sort.Slice(slots, func(i, j int) bool { return slots[i].h < slots[j].h })
+		for start := 0; start < len(slots); {
+			end := start + 1
+			for end < len(slots) && slots[end].h == slots[start].h {
+				end++
+			}
+			if end-start == 1 {
+				keys++
+			} else {
+				// Several records with the same hash, which may be values of
+				// one key or colliding keys.
+				distinct := make(map[string]bool)
+				for _, s := range slots[start:end] {
+					klen, _, err := readNums(c.r, buf, int64(s.pos))
+					if err != nil {
+						return err
+					}
+					if s.pos+8+uint64(klen) > dataEnd {
+						return corruptf(s.pos, "key runs past the end of the data section")
+					}
+					key := make([]byte, klen)
+					if err := readFull(c.r, key, int64(s.pos)+8); err != nil {
+						return err
+					}
+					distinct[string(key)] = true
+				}
+				keys += int64(len(distinct))
+			}
+			start = end
+		}
+	}
+	c.counts.records, c.counts.keys, c.counts.known = records, keys, true
+	return nil
+}
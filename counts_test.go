@@ -0,0 +1,82 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNumRecordsAndKeys(t *testing.T) {
+	for _, tc := range []struct {
+		recs          []rec
+		records, keys int64
+	}{
+		{records, 6, 3},
+		{nil, 0, 0},
+		{[]rec{{"a", []string{"1"}}, {"b", []string{"2", "3"}}}, 3, 2},
+	} {
+		db := newDB(tc.recs)
+		if n, err := db.NumRecords(); err != nil || n != tc.records {
+			t.Errorf("NumRecords: expected %d, got: %d, %v", tc.records, n, err)
+		}
+		if n, err := db.NumKeys(); err != nil || n != tc.keys {
+			t.Errorf("NumKeys: expected %d, got: %d, %v", tc.keys, n, err)
+		}
+	}
+}
+
+func TestNumKeysCollisions(t *testing.T) {
+	// Every key has the same hash, so the keys have to be compared.
+	same := func(key []byte) uint32 { return 42 }
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp, Hash(same))
+	for _, kv := range [][2]string{{"a", "1"}, {"b", "2"}, {"a", "3"}, {"c", "4"}} {
+		if err := w.Write([]byte(kv[0]), []byte(kv[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(b), WithHash(same))
+	if n, err := db.NumKeys(); err != nil || n != 3 {
+		t.Errorf("expected 3 keys, got: %d, %v", n, err)
+	}
+	if n, err := db.NumRecords(); err != nil || n != 4 {
+		t.Errorf("expected 4 records, got: %d, %v", n, err)
+	}
+}
+
+func TestNumRecordsHugeTable(t *testing.T) {
+	// A damaged slot count has to be reported, not allocated.
+	b := newDBBytes(records)
+	putNum(b[4:], 0x7fffffff)
+	var err error
+	if _, err := New(bytes.NewReader(b)).NumRecords(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got: %v", err)
+	}
+
+	// So does a key length that runs past the records, read to tell apart
+	// keys with the same hash.
+	same := func(key []byte) uint32 { return 42 }
+	w := NewBufferWriter(Hash(same))
+	w.Write([]byte("a"), []byte("1"))
+	w.Write([]byte("b"), []byte("2"))
+	if b, err = w.Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	putNum(b[2048:], 0xfffffff0)
+	if _, err := New(bytes.NewReader(b), WithHash(same)).NumKeys(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got: %v", err)
+	}
+}
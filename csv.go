@@ -0,0 +1,243 @@
+package cdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVOption configures MakeFromCSV and DumpCSV. Pass the same options to both
+// for a round trip.
+type CSVOption func(*csvConfig)
+
+type csvConfig struct {
+	comma   rune
+	keyCol  int
+	valCols []int
+	header  bool
+	names   []string
+}
+
+// CSVDelimiter returns a CSVOption that separates fields with comma instead
+// of ','. Use '\t' for TSV.
+func CSVDelimiter(comma rune) CSVOption {
+	return func(c *csvConfig) {
+		c.comma = comma
+	}
+}
+
+// CSVKeyColumn returns a CSVOption that takes keys from column i, counting
+// from 0. The default is the first column.
+func CSVKeyColumn(i int) CSVOption {
+	return func(c *csvConfig) {
+		c.keyCol = i
+	}
+}
+
+// CSVValueColumns returns a CSVOption that takes values from the given
+// columns, counting from 0. The default is the second column. A value made
+// of several columns is stored as a CSV row of them, with the same delimiter.
+func CSVValueColumns(cols ...int) CSVOption {
+	return func(c *csvConfig) {
+		c.valCols = cols
+	}
+}
+
+// CSVHeader returns a CSVOption for data with a header row. MakeFromCSV skips
+// the first row, and DumpCSV writes names as the first row, or "key" and
+// "value" in the key and value columns if there are no names.
+func CSVHeader(names ...string) CSVOption {
+	return func(c *csvConfig) {
+		c.header, c.names = true, names
+	}
+}
+
+func newCSVConfig(opts []CSVOption) (*csvConfig, error) {
+	c := &csvConfig{comma: ',', valCols: []int{1}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	for _, i := range append([]int{c.keyCol}, c.valCols...) {
+		if i < 0 {
+			return nil, fmt.Errorf("cdb: negative CSV column %d", i)
+		}
+	}
+	return c, nil
+}
+
+// headerNames returns the header row DumpCSV writes for rows of width
+// columns.
+func (c *csvConfig) headerNames(width int) []string {
+	if c.names != nil {
+		return c.names
+	}
+	names := make([]string, width)
+	names[c.keyCol] = "key"
+	for j, i := range c.valCols {
+		if len(c.valCols) == 1 {
+			names[i] = "value"
+		} else {
+			names[i] = fmt.Sprintf("value%d", j+1)
+		}
+	}
+	return names
+}
+
+// MakeFromCSV reads CSV rows from r and writes a database to ws, with a
+// record for each row. Like Make, the input may be compressed.
+func MakeFromCSV(ws io.WriteSeeker, r io.Reader, opts ...CSVOption) error {
+	w := NewWriter(ws)
+	if err := w.PutCSV(r, opts...); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// PutCSV reads CSV rows from r and adds a record for each row to the
+// database, like MakeFromCSV.
+func (w *Writer) PutCSV(r io.Reader, opts ...CSVOption) error {
+	c, err := newCSVConfig(opts)
+	if err != nil {
+		return err
+	}
+	rb, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	cr := csv.NewReader(rb)
+	cr.Comma = c.comma
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	for line := 1; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return fmt.Errorf("%w: row %d: %v", ErrBadFormat, line, err)
+		}
+		if err != nil {
+			return err
+		}
+		if c.header && line == 1 {
+			continue
+		}
+		key, val, err := c.record(row)
+		if err != nil {
+			return fmt.Errorf("%w: row %d: %v", ErrBadFormat, line, err)
+		}
+		if err := w.Put(key, val); err != nil {
+			return fmt.Errorf("row %d: %w", line, err)
+		}
+	}
+}
+
+// PutTSV reads lines of tab separated values from r and adds a record for
+// each, with the text before the first tab as the key and the rest of the
+// line as the value. Unlike PutCSV with CSVDelimiter('\t'), nothing is
+// quoted, so quotes are kept as they are, and values can hold tabs. Lines
+// may end in "\r\n". Like Make, the input may be compressed.
+func (w *Writer) PutTSV(r io.Reader) error {
+	rb, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	for line := 1; ; line++ {
+		b, err := rb.ReadBytes('\n')
+		if len(b) == 0 && err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		b = bytes.TrimSuffix(bytes.TrimSuffix(b, []byte("\n")), []byte("\r"))
+		key, val, ok := bytes.Cut(b, []byte("\t"))
+		if !ok {
+			return fmt.Errorf("%w: line %d has no tab", ErrBadFormat, line)
+		}
+		if err := w.Put(key, val); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// record returns the key and value of the record for row.
+func (c *csvConfig) record(row []string) ([]byte, []byte, error) {
+	for _, i := range append([]int{c.keyCol}, c.valCols...) {
+		if i >= len(row) {
+			return nil, nil, fmt.Errorf("no column %d in %d columns", i, len(row))
+		}
+	}
+	if len(c.valCols) == 1 {
+		return []byte(row[c.keyCol]), []byte(row[c.valCols[0]]), nil
+	}
+	vals := make([]string, len(c.valCols))
+	for j, i := range c.valCols {
+		vals[j] = row[i]
+	}
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Comma = c.comma
+	cw.Write(vals)
+	cw.Flush()
+	return []byte(row[c.keyCol]), bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// DumpCSV writes the records of db to w as CSV rows, in the order they were
+// written, putting keys and values in the columns given by the options.
+// Columns no option names are left empty.
+//
+// Threadsafe.
+func DumpCSV(w io.Writer, db *Cdb, opts ...CSVOption) error {
+	c, err := newCSVConfig(opts)
+	if err != nil {
+		return err
+	}
+	width := c.keyCol + 1
+	for _, i := range c.valCols {
+		if i+1 > width {
+			width = i + 1
+		}
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = c.comma
+	if c.header {
+		if err := cw.Write(c.headerNames(width)); err != nil {
+			return err
+		}
+	}
+	row := make([]string, width)
+	err = db.ForEachBytes(func(key, val []byte) error {
+		row[c.keyCol] = string(key)
+		if len(c.valCols) == 1 {
+			row[c.valCols[0]] = string(val)
+		} else {
+			vr := csv.NewReader(strings.NewReader(string(val)))
+			vr.Comma = c.comma
+			vals, err := vr.Read()
+			if err == io.EOF {
+				vals = nil
+			} else if err != nil {
+				return fmt.Errorf("value of %q isn't a CSV row: %w", key, err)
+			}
+			if len(vals) != len(c.valCols) {
+				return fmt.Errorf("value of %q has %d columns, expected %d", key, len(vals), len(c.valCols))
+			}
+			for j, i := range c.valCols {
+				row[i] = vals[j]
+			}
+		}
+		return cw.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,105 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	in := "id\tname\tcity\n1\tann\tparis\n2\t\"bob, jr\"\toslo\n"
+	opts := []CSVOption{CSVDelimiter('\t'), CSVValueColumns(1, 2), CSVHeader("id", "name", "city")}
+	out := new(memBuffer)
+	if err := MakeFromCSV(out, strings.NewReader(in), opts...); err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(out.b))
+	if b, err := db.Bytes([]byte("2")); err != nil || string(b) != "bob, jr\toslo" {
+		t.Errorf("expected the name and city, got: %q, %v", b, err)
+	}
+
+	var buf bytes.Buffer
+	if err := DumpCSV(&buf, db, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id\tname\tcity\n1\tann\tparis\n2\tbob, jr\toslo\n"; buf.String() != want {
+		t.Errorf("expected %q, got: %q", want, buf.String())
+	}
+
+	// By default keys are in the first column and values in the second.
+	buf.Reset()
+	if err := DumpCSV(&buf, newDB(records[:2])); err != nil {
+		t.Fatal(err)
+	}
+	if want := "one,1\ntwo,2\ntwo,22\n"; buf.String() != want {
+		t.Errorf("expected %q, got: %q", want, buf.String())
+	}
+}
+
+func TestMakeFromCSVShortRow(t *testing.T) {
+	err := MakeFromCSV(new(memBuffer), strings.NewReader("a,1\nb\n"))
+	if !errors.Is(err, ErrBadFormat) || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("expected ErrBadFormat for row 2, got: %v", err)
+	}
+}
+
+func TestCSVHeaderRoundTrip(t *testing.T) {
+	// A header without names still gets a row, so MakeFromCSV doesn't skip
+	// the first record.
+	opts := []CSVOption{CSVHeader()}
+	var buf bytes.Buffer
+	if err := DumpCSV(&buf, newDB(records[:1]), opts...); err != nil {
+		t.Fatal(err)
+	}
+	if want := "key,value\none,1\n"; buf.String() != want {
+		t.Errorf("expected %q, got: %q", want, buf.String())
+	}
+	out := new(memBuffer)
+	if err := MakeFromCSV(out, &buf, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := New(bytes.NewReader(out.b)).Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected the first record, got: %q, %v", b, err)
+	}
+}
+
+func TestCSVErrors(t *testing.T) {
+	for _, opt := range []CSVOption{CSVKeyColumn(-1), CSVValueColumns(1, -2)} {
+		if err := MakeFromCSV(new(memBuffer), strings.NewReader("a,1\n"), opt); err == nil {
+			t.Error("expected MakeFromCSV to reject a negative column")
+		}
+		if err := DumpCSV(new(bytes.Buffer), newDB(records), opt); err == nil {
+			t.Error("expected DumpCSV to reject a negative column")
+		}
+	}
+
+	// Write errors aren't reported as bad input.
+	ws := &failingWriteSeeker{WriteSeeker: new(memBuffer), fail: true}
+	in := "a," + strings.Repeat("x", 1<<17) + "\n"
+	if err := MakeFromCSV(ws, strings.NewReader(in)); err == nil || errors.Is(err, ErrBadFormat) {
+		t.Errorf("expected the write error, got: %v", err)
+	}
+}
+
+func TestPutTSV(t *testing.T) {
+	out := new(memBuffer)
+	w := NewWriter(out)
+	if err := w.PutTSV(strings.NewReader("k\tsay \"hi\"\ntabs\ta\tb\r\nempty\t\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(out.b))
+	for key, want := range map[string]string{"k": `say "hi"`, "tabs": "a\tb", "empty": ""} {
+		if b, err := db.Bytes([]byte(key)); err != nil || string(b) != want {
+			t.Errorf("%s: expected %q, got: %q, %v", key, want, b, err)
+		}
+	}
+
+	err := NewWriter(new(memBuffer)).PutTSV(strings.NewReader("a\t1\nb\n"))
+	if !errors.Is(err, ErrBadFormat) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected ErrBadFormat for line 2, got: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package cdb
+
+import "sync"
+
+// Delivery is the order ForEachDecoded passes decoded records on in.
+type Delivery int
+
+const (
+	// Ordered delivers the records in the order they are in the database,
+	// holding back records decoded early until those before them are done.
+	Ordered Delivery = iota
+	// Unordered delivers each record as soon as it is decoded.
+	Unordered
+)
+
+// decodeJob is a record passed through the ForEachDecoded pipeline.
+type decodeJob struct {
+	seq      int
+	key, val []byte
+	decoded  interface{}
+	err      error
+}
+
+// ForEachDecoded reads every record in the database in order, decodes the
+// values with decode on up to workers goroutines at once, and calls
+// onRecordFn with each key and decoded value, in the given Delivery order.
+// It is for scans where decoding costs more than reading.
+//
+// OnRecordFn is never called concurrently, and its key is its own to keep.
+// Decode may be called concurrently, and may keep the slices it is given.
+//
+// If decode or onRecordFn returns an error, iteration will stop and the error
+// will be returned. With Ordered delivery, a decode error is returned once
+// the records before it have been delivered.
+//
+// Threadsafe.
+func (c *Cdb) ForEachDecoded(decode func(key, val []byte) (interface{}, error), workers int, delivery Delivery, onRecordFn func(key []byte, decoded interface{}) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan *decodeJob)
+	results := make(chan *decodeJob)
+	// tokens bounds the records read but not yet delivered, which is how
+	// many Ordered delivery may have to hold back.
+	tokens := make(chan struct{}, 2*workers)
+	done := make(chan struct{})
+	scanned := make(chan struct{})
+	var scanErr error
+
+	go func() {
+		defer close(scanned)
+		defer close(jobs)
+		seq := 0
+		scanErr = c.ForEachBytes(func(key, val []byte) error {
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return errStopScan
+			}
+			job := &decodeJob{seq: seq, key: append([]byte(nil), key...), val: append([]byte(nil), val...)}
+			seq++
+			select {
+			case jobs <- job:
+				return nil
+			case <-done:
+				return errStopScan
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.decoded, job.err = decode(job.key, job.val)
+				job.val = nil
+				select {
+				case results <- job:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	deliver := func(job *decodeJob) error {
+		<-tokens
+		if job.err != nil {
+			return job.err
+		}
+		return onRecordFn(job.key, job.decoded)
+	}
+	var err error
+	pending := make(map[int]*decodeJob)
+	next := 0
+	for job := range results {
+		if err != nil {
+			// Stopping; drain the workers.
+			continue
+		}
+		if delivery == Unordered {
+			err = deliver(job)
+		} else {
+			pending[job.seq] = job
+			for ready, ok := pending[next]; ok && err == nil; ready, ok = pending[next] {
+				delete(pending, next)
+				next++
+				err = deliver(ready)
+			}
+		}
+		if err != nil {
+			close(done)
+		}
+	}
+	<-scanned
+	if err == nil {
+		err = scanErr
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func manyRecords(n int) []rec {
+	recs := make([]rec, n)
+	for i := range recs {
+		recs[i] = rec{fmt.Sprintf("key%d", i), []string{strconv.Itoa(i)}}
+	}
+	return recs
+}
+
+// slowAtoi decodes values as numbers, taking a random time about it so
+// workers finish out of order.
+func slowAtoi(key, val []byte) (interface{}, error) {
+	time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+	return strconv.Atoi(string(val))
+}
+
+func TestForEachDecoded(t *testing.T) {
+	db := newDB(manyRecords(200))
+	expected := make([]int, 200)
+	for i := range expected {
+		expected[i] = i
+	}
+
+	var got []int
+	err := db.ForEachDecoded(slowAtoi, 8, Ordered, func(key []byte, decoded interface{}) error {
+		got = append(got, decoded.(int))
+		if string(key) != fmt.Sprintf("key%d", decoded) {
+			t.Errorf("key %s delivered with value %d", key, decoded)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Ordered: expected the records in order, got: %v", got)
+	}
+
+	got = nil
+	err = db.ForEachDecoded(slowAtoi, 8, Unordered, func(key []byte, decoded interface{}) error {
+		got = append(got, decoded.(int))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unordered: expected every record, got: %v", got)
+	}
+}
+
+func TestForEachDecodedErrors(t *testing.T) {
+	db := newDB(manyRecords(200))
+	errBad := errors.New("bad record")
+	n := 0
+	err := db.ForEachDecoded(func(key, val []byte) (interface{}, error) {
+		if string(val) == "50" {
+			return nil, errBad
+		}
+		return slowAtoi(key, val)
+	}, 4, Ordered, func(key []byte, decoded interface{}) error {
+		n++
+		return nil
+	})
+	if err != errBad || n != 50 {
+		t.Errorf("expected the decode error after 50 records, got: %v after %d", err, n)
+	}
+
+	n = 0
+	err = db.ForEachDecoded(slowAtoi, 4, Unordered, func(key []byte, decoded interface{}) error {
+		n++
+		if n == 10 {
+			return errBad
+		}
+		return nil
+	})
+	if err != errBad || n != 10 {
+		t.Errorf("expected the callback error to stop delivery, got: %v after %d", err, n)
+	}
+}
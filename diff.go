@@ -0,0 +1,126 @@
+package cdb
+
+import "bytes"
+
+// DiffKind says how a key differs between two databases.
+type DiffKind int
+
+const (
+	// DiffAdded keys are only in the second database.
+	DiffAdded DiffKind = iota
+	// DiffRemoved keys are only in the first database.
+	DiffRemoved
+	// DiffChanged keys are in both, with different values.
+	DiffChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// DiffEntry is a key that differs between two databases, with its values in
+// each, in the order lookups return them. Old is empty for added keys, and
+// New for removed ones.
+type DiffEntry struct {
+	Kind     DiffKind
+	Key      []byte
+	Old, New [][]byte
+}
+
+// Diff returns the keys that were added, removed and changed going from a to
+// b. A key is changed if its values, in order, are different. See ForEachDiff
+// for a form that doesn't hold every difference in memory.
+//
+// Threadsafe.
+func Diff(a, b *Cdb) (added, removed, changed []DiffEntry, err error) {
+	err = ForEachDiff(a, b, func(e DiffEntry) error {
+		switch e.Kind {
+		case DiffAdded:
+			added = append(added, e)
+		case DiffRemoved:
+			removed = append(removed, e)
+		default:
+			changed = append(changed, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return added, removed, changed, nil
+}
+
+// ForEachDiff calls fn for every key that differs between a and b. Removed and
+// changed keys come first, in the order they were first written to a, then
+// added keys, in the order they were first written to b. The entries are
+// fn's to keep.
+//
+// Neither database is loaded into memory: each is scanned once, and each key
+// is looked up in the other.
+//
+// If fn returns an error, iteration will stop and the error will be returned.
+//
+// Threadsafe.
+func ForEachDiff(a, b *Cdb, fn func(e DiffEntry) error) error {
+	err := forEachDistinctKey(a, func(key []byte, valsA [][]byte) error {
+		valsB, err := b.AllValues(key)
+		if err != nil {
+			return err
+		}
+		if len(valsB) == 0 {
+			return fn(DiffEntry{Kind: DiffRemoved, Key: key, Old: valsA})
+		}
+		if !equalValues(valsA, valsB) {
+			return fn(DiffEntry{Kind: DiffChanged, Key: key, Old: valsA, New: valsB})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return forEachDistinctKey(b, func(key []byte, valsB [][]byte) error {
+		if ok, err := a.Exists(key); ok || err != nil {
+			return err
+		}
+		return fn(DiffEntry{Kind: DiffAdded, Key: key, New: valsB})
+	})
+}
+
+// forEachDistinctKey calls fn once for each key in c that has values, at its
+// first record, with a copy of the key and its values.
+func forEachDistinctKey(c *Cdb, fn func(key []byte, vals [][]byte) error) error {
+	return c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		key := make([]byte, klen)
+		if err := readFull(c.r, key, int64(pos)+8); err != nil {
+			return err
+		}
+		if first, err := c.isFirstRecord(key, pos); !first || err != nil {
+			return err
+		}
+		vals, err := c.AllValues(key)
+		if err != nil || len(vals) == 0 {
+			return err
+		}
+		return fn(key, vals)
+	})
+}
+
+func equalValues(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
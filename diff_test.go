@@ -0,0 +1,53 @@
+package cdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := newDB(records)
+	b := newDB([]rec{
+		{"one", []string{"1"}},
+		{"two", []string{"22", "2"}},
+		{"four", []string{"4"}},
+	})
+	added, removed, changed, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := func(s ...string) [][]byte {
+		var out [][]byte
+		for _, v := range s {
+			out = append(out, []byte(v))
+		}
+		return out
+	}
+	if want := []DiffEntry{{Kind: DiffAdded, Key: []byte("four"), New: vals("4")}}; !reflect.DeepEqual(added, want) {
+		t.Errorf("expected %v, got: %v", want, added)
+	}
+	if want := []DiffEntry{{Kind: DiffRemoved, Key: []byte("three"), Old: vals("3", "33", "333")}}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("expected %v, got: %v", want, removed)
+	}
+	if want := []DiffEntry{{Kind: DiffChanged, Key: []byte("two"), Old: vals("2", "22"), New: vals("22", "2")}}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("expected %v, got: %v", want, changed)
+	}
+
+	added, removed, changed, err = Diff(a, newDB(records))
+	if err != nil || added != nil || removed != nil || changed != nil {
+		t.Errorf("expected no differences, got: %v, %v, %v, %v", added, removed, changed, err)
+	}
+}
+
+func TestForEachDiffStops(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := ForEachDiff(newDB(records), newDB(nil), func(e DiffEntry) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("expected one call and the error, got: %d, %v", calls, err)
+	}
+}
@@ -2,6 +2,7 @@ package cdb
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -18,17 +19,22 @@ func Dump(w io.Writer, r io.Reader) (err error) {
 		}
 	}()
 
-	rb := bufio.NewReader(r)
+	rb := bufio.NewReaderSize(r, 256*16)
 	readNum := makeNumReader(rb)
 	rw := &recWriter{bufio.NewWriter(w)}
 
-	eod := readNum()
-	// Read rest of header.
-	for i := 0; i < 511; i++ {
-		readNum()
+	// Peek at enough of the header to tell its layout, then skip it.
+	header, _ := rb.Peek(256 * 16)
+	lay, err := detectLayout(bytes.NewReader(header))
+	if err != nil {
+		return err
+	}
+	eod, _ := lay.entry(header)
+	if _, err := rb.Discard(int(lay.headerSize())); err != nil {
+		return err
 	}
 
-	pos := headerSize
+	pos := lay.headerSize()
 	for pos < eod {
 		klen, dlen := readNum(), readNum()
 		rw.writeString(fmt.Sprintf("+%d,%d:", klen, dlen))
@@ -36,7 +42,7 @@ func Dump(w io.Writer, r io.Reader) (err error) {
 		rw.writeString("->")
 		rw.copyn(rb, dlen)
 		rw.writeString("\n")
-		pos += 8 + klen + dlen
+		pos += 8 + uint64(klen) + uint64(dlen)
 	}
 	rw.writeString("\n")
 
@@ -0,0 +1,192 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DuplicatePolicy says what a Writer does with a record whose key has
+// already been written.
+type DuplicatePolicy int
+
+const (
+	// AllowDuplicates writes every record, so a key can have several
+	// values. This is the default.
+	AllowDuplicates DuplicatePolicy = iota
+	// RejectDuplicates returns an error wrapping ErrDuplicateKey for a
+	// record whose key has been written, and doesn't write it.
+	RejectDuplicates
+	// KeepFirst silently drops records whose key has been written.
+	KeepFirst
+	// KeepLast writes every record, and has Close drop the earlier records
+	// of each key. The output has to be an io.ReaderAt, like *os.File, so
+	// Close can move the records that are kept down over the dropped ones,
+	// and if it has a Truncate method, like *os.File, the file is truncated
+	// to the new size.
+	KeepLast
+)
+
+// Duplicates returns a WriterOption that handles records whose key has
+// already been written according to policy. Every policy but
+// AllowDuplicates keeps all the keys written in memory.
+func Duplicates(policy DuplicatePolicy) WriterOption {
+	return func(w *Writer) {
+		w.duplicates = policy
+	}
+}
+
+// dupState tracks the keys written by a Writer with a DuplicatePolicy.
+type dupState struct {
+	// keys holds the index in recs of the record of each key written.
+	keys map[string]int
+	// recs holds the records in order, for KeepLast.
+	recs []dupRecord
+	// dropped is the number of records dropped by KeepLast.
+	dropped int
+	// skipped is true if the last record wasn't written, because it was
+	// rejected or dropped by KeepFirst.
+	skipped bool
+	// r reads the output back, for KeepLast.
+	r io.ReaderAt
+}
+
+type dupRecord struct {
+	h    uint32
+	pos  uint64
+	size uint64
+	dead bool
+}
+
+// newDupState returns the duplicate state for a Writer writing to ws.
+func newDupState(policy DuplicatePolicy, ws io.WriteSeeker) (*dupState, error) {
+	d := &dupState{keys: make(map[string]int)}
+	if policy == KeepLast {
+		r, ok := ws.(io.ReaderAt)
+		if !ok {
+			return nil, errors.New("cdb: KeepLast needs an output that is an io.ReaderAt")
+		}
+		d.r = r
+	}
+	return d, nil
+}
+
+// checkDuplicate returns true if the record with key shouldn't be written,
+// or an error if it is rejected.
+func (w *Writer) checkDuplicate(key []byte) (bool, error) {
+	if w.dups == nil {
+		return false, nil
+	}
+	w.dups.skipped = false
+	if _, ok := w.dups.keys[string(key)]; ok {
+		switch w.duplicates {
+		case RejectDuplicates:
+			w.dups.skipped = true
+			w.countDuplicate()
+			return true, fmt.Errorf("%w: %q", ErrDuplicateKey, key)
+		case KeepFirst:
+			w.dups.skipped = true
+			w.countDuplicate()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// countDuplicate counts a record that wasn't written, in the build's stats.
+func (w *Writer) countDuplicate() {
+	if w.metrics != nil {
+		w.metrics.stats.Duplicates++
+	}
+}
+
+// addKey remembers that a record with key and hash h was written at pos.
+func (w *Writer) addKey(key []byte, h uint32, pos uint64) {
+	if w.dups == nil {
+		return
+	}
+	if w.duplicates != KeepLast {
+		w.dups.keys[string(key)] = 0
+		return
+	}
+	d := w.dups
+	if i, ok := d.keys[string(key)]; ok {
+		d.recs[i].dead = true
+		d.dropped++
+	}
+	d.keys[string(key)] = len(d.recs)
+	d.recs = append(d.recs, dupRecord{h: h, pos: pos, size: w.b.pos - pos})
+}
+
+// dropDuplicates moves the records kept by KeepLast down over the ones
+// dropped, and rebuilds the hash tables, checksums and annotations to match.
+func (w *Writer) dropDuplicates() error {
+	d := w.dups
+	if d == nil || d.dropped == 0 {
+		return nil
+	}
+	b := w.b
+	if err := b.wb.Flush(); err != nil {
+		return err
+	}
+	moved := make(map[uint64]uint64, len(d.recs)-d.dropped)
+	htables := make(map[uint32][]slot)
+	var sums []byte
+	pos := b.lay.headerSize()
+	// Once the first record is dropped, every record after it moves down.
+	// Records are only ever written below where they are read from, so none
+	// is overwritten before it is moved.
+	moving := false
+	for i, rec := range d.recs {
+		if rec.dead {
+			continue
+		}
+		if pos != rec.pos {
+			if !moving {
+				if _, err := b.w.Seek(int64(pos), io.SeekStart); err != nil {
+					return err
+				}
+				moving = true
+			}
+			if _, err := io.Copy(b.wb, io.NewSectionReader(d.r, int64(rec.pos), int64(rec.size))); err != nil {
+				return err
+			}
+		}
+		moved[rec.pos] = pos
+		htables[rec.h%256] = append(htables[rec.h%256], slot{rec.h, pos})
+		if w.crc != nil {
+			sums = append(sums, w.sums[i*4:i*4+4]...)
+		}
+		pos += rec.size
+	}
+	if err := b.wb.Flush(); err != nil {
+		return err
+	}
+	if _, err := b.w.Seek(int64(pos), io.SeekStart); err != nil {
+		return err
+	}
+	if t, ok := d.r.(interface {
+		Truncate(size int64) error
+	}); ok {
+		if err := t.Truncate(int64(pos)); err != nil {
+			return err
+		}
+	}
+	b.htables, b.pos = htables, pos
+	if w.crc != nil {
+		w.sums = sums
+	}
+	annotations := w.annotations[:0]
+	w.annotationsSize = 0
+	for _, a := range w.annotations {
+		if newPos, ok := moved[uint64(a.pos)]; ok {
+			annotations = append(annotations, annotation{uint32(newPos), a.meta})
+			w.annotationsSize += annotationEntrySize + uint64(len(a.meta))
+		}
+	}
+	if len(annotations) > 0 {
+		w.annotationsSize += 4
+	}
+	w.annotations = annotations
+	return nil
+}
@@ -0,0 +1,95 @@
+package cdb
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	for _, tc := range []struct {
+		policy   DuplicatePolicy
+		expected []string
+	}{
+		{AllowDuplicates, []string{"one=1", "two=2", "two=22", "three=3", "three=33", "three=333"}},
+		{RejectDuplicates, []string{"one=1", "two=2", "three=3"}},
+		{KeepFirst, []string{"one=1", "two=2", "three=3"}},
+		{KeepLast, []string{"one=1", "two=22", "three=333"}},
+	} {
+		tmp, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		w := NewWriter(tmp, Duplicates(tc.policy), Checksums())
+		for _, rec := range records {
+			for _, val := range rec.values {
+				err := w.Put([]byte(rec.key), []byte(val))
+				if tc.policy == RejectDuplicates && val != rec.values[0] {
+					if !errors.Is(err, ErrDuplicateKey) {
+						t.Errorf("expected ErrDuplicateKey, got: %v", err)
+					}
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Annotate([]byte("from " + val)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		db := New(tmp)
+		if got := dataOrder(t, db); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("policy %d: expected records %v, got: %v", tc.policy, tc.expected, got)
+		}
+		if err := db.Verify(); err != nil {
+			t.Errorf("policy %d: Verify: %v", tc.policy, err)
+		}
+		if err := db.VerifyChecksums(); err != nil {
+			t.Errorf("policy %d: VerifyChecksums: %v", tc.policy, err)
+		}
+		// Every annotation stays with its record.
+		err = db.ForEachBytes(func(key, val []byte) error {
+			iter := db.Iterate(key)
+			for {
+				v, err := iter.NextBytes()
+				if err != nil {
+					return err
+				}
+				if string(v) != string(val) {
+					continue
+				}
+				meta, err := db.Annotation(uint64(iter.Handle()))
+				if err != nil {
+					return err
+				}
+				if string(meta) != "from "+string(val) {
+					t.Errorf("policy %d: expected the annotation of %s=%s, got: %q", tc.policy, key, val, meta)
+				}
+				return nil
+			}
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestKeepLastNeedsReaderAt(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := NewWriter(&failingWriteSeeker{WriteSeeker: tmp}, Duplicates(KeepLast))
+	if err := w.Put([]byte("a"), []byte("1")); err == nil {
+		t.Error("expected an error for an output that can't be read")
+	}
+}
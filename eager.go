@@ -0,0 +1,76 @@
+package cdb
+
+import "io"
+
+// EagerHeader returns an Option that makes Open read the header and detect the
+// layout of the database, so a file that isn't a database fails to open.
+// WithPreloadedHeader does the same, and keeps the header in memory.
+func EagerHeader() Option {
+	return func(c *Cdb) {
+		c.eager = append(c.eager, (*Cdb).loadHeader)
+	}
+}
+
+// EagerVerify returns an Option that makes Open check the database with
+// Verify, and with VerifyChecksums if it has checksums, failing if it is
+// corrupt. Both read the whole database.
+func EagerVerify() Option {
+	return func(c *Cdb) {
+		c.eager = append(c.eager, (*Cdb).verifyAll)
+	}
+}
+
+// EagerWarm returns an Option that makes Open read the hash tables, so the
+// operating system has them cached and the first lookups of each table don't
+// wait for the disk.
+func EagerWarm() Option {
+	return func(c *Cdb) {
+		c.eager = append(c.eager, (*Cdb).warmTables)
+	}
+}
+
+// runEager does the work the Eager options moved to Open, in order.
+func (c *Cdb) runEager() error {
+	for _, fn := range c.eager {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cdb) loadHeader() error {
+	lay, err := c.layout()
+	if err != nil {
+		return err
+	}
+	return readFull(c.r, make([]byte, lay.headerSize()), 0)
+}
+
+func (c *Cdb) verifyAll() error {
+	if err := c.Verify(); err != nil {
+		return err
+	}
+	f, err := c.Format()
+	if err != nil || !f.Checksums {
+		return err
+	}
+	return c.VerifyChecksums()
+}
+
+func (c *Cdb) warmTables() error {
+	lay, err := c.layout()
+	if err != nil {
+		return err
+	}
+	start, _, err := lay.readEntry(c.r, make([]byte, 16), 0)
+	if err != nil {
+		return err
+	}
+	end, err := tablesEnd(c.r)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, io.NewSectionReader(c.r, int64(start), int64(end-start)))
+	return err
+}
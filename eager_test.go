@@ -0,0 +1,84 @@
+package cdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEagerOptions(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.cdb")
+	if err := os.WriteFile(good, newDBBytes(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.cdb")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Point the first hash table past the end of the file.
+	damaged := filepath.Join(dir, "damaged.cdb")
+	b := newDBBytes(records)
+	putNum(b[4:], 1000)
+	if err := os.WriteFile(damaged, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening reads nothing by default.
+	for _, name := range []string{empty, damaged} {
+		db, err := Open(name)
+		if err != nil {
+			t.Fatalf("expected %s to open lazily, got: %v", name, err)
+		}
+		db.Close()
+	}
+
+	for _, tc := range []struct {
+		opt  Option
+		name string
+		want error
+	}{
+		{EagerHeader(), empty, ErrInvalidDatabase},
+		{WithPreloadedHeader(), empty, ErrInvalidDatabase},
+		{EagerVerify(), damaged, ErrCorrupt},
+	} {
+		if _, err := Open(tc.name, tc.opt); !errors.Is(err, tc.want) {
+			t.Errorf("expected %v, got: %v", tc.want, err)
+		}
+	}
+
+	db, err := Open(good, EagerHeader(), EagerVerify(), EagerWarm(), WithPreloadedHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkRecords(t, db, records)
+}
+
+func TestPreloadedHeaderAtOpen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.cdb")
+	if err := os.WriteFile(name, newDBBytes(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A key in a hash table none of the records are in.
+	key := []byte("missing")
+	for _, rec := range records {
+		if Prepare([]byte(rec.key)).Table() == Prepare(key).Table() {
+			t.Fatalf("%s shares a table with %s", key, rec.key)
+		}
+	}
+
+	db, err := Open(name, WithPreloadedHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Lookups in empty tables only need the header, which is in memory.
+	if err := os.Truncate(name, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Bytes(key); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound from the preloaded header, got: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The errors returned by the package. Check for them with errors.Is, since
+// they are usually wrapped with more detail. Errors from the underlying
+// reader or writer are wrapped too, so errors.Is also finds those.
+var (
+	// ErrNotFound is returned by lookups of keys that have no values. It
+	// matches io.EOF, which lookups used to return, with errors.Is.
+	ErrNotFound error = &compatError{"key not found", io.EOF}
+	// ErrInvalidDatabase is returned when the file isn't a cdb database at
+	// all, like one too short to hold a header.
+	ErrInvalidDatabase = errors.New("not a cdb database")
+	// ErrCorrupt is matched by CorruptError, for databases whose structure is
+	// damaged.
+	ErrCorrupt = errors.New("corrupt database")
+	// ErrTooLarge is returned when building a database that doesn't fit in
+	// its layout's positions: 4GB for classic cdb files.
+	ErrTooLarge = errors.New("database too large")
+	// ErrClosed is returned by reads from a closed Cdb. It matches
+	// os.ErrClosed with errors.Is.
+	ErrClosed error = &compatError{"database closed", os.ErrClosed}
+	// ErrWriterClosed is returned by a Writer that has been closed. It
+	// matches io.ErrClosedPipe with errors.Is.
+	ErrWriterClosed error = &compatError{"writer closed", io.ErrClosedPipe}
+	// ErrValueTooLarge is returned for keys and values longer than the 32 bit
+	// lengths of a record header can hold. The limit is per key and value,
+	// and the same in both layouts: 64 bit databases can be bigger than 4GB,
+	// but not their records. Lookups also return it for values over the
+	// limit set with WithMaxValueSize.
+	ErrValueTooLarge = errors.New("value too large")
+	// ErrNoChecksums is returned by Cdb.VerifyChecksums for databases built
+	// without the Checksums WriterOption.
+	ErrNoChecksums = errors.New("database has no checksums")
+	// ErrDuplicateKey is returned by a Writer with the RejectDuplicates
+	// policy for a record whose key has already been written.
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrWrongType is returned by the typed getters, like TypedString, for
+	// values without the type they read.
+	ErrWrongType = errors.New("wrong value type")
+	// ErrBadKey is returned by SplitKey for keys that aren't composite keys.
+	ErrBadKey = errors.New("bad composite key")
+	// ErrBadFormat is returned by Make and the other builders for input that
+	// isn't in the format they read.
+	ErrBadFormat = errors.New("bad format")
+)
+
+// BadFormatError is the old name of ErrBadFormat.
+//
+// Deprecated: Use ErrBadFormat.
+var BadFormatError = ErrBadFormat
+
+// compatError is a sentinel error that also matches the error the package
+// returned in its place before it existed.
+type compatError struct {
+	msg    string
+	compat error
+}
+
+func (e *compatError) Error() string {
+	return e.msg
+}
+
+func (e *compatError) Is(target error) bool {
+	return target == e.compat
+}
+
+// CorruptError describes damage found in a database. It matches ErrCorrupt,
+// and ErrBadFormat for compatibility, with errors.Is.
+type CorruptError struct {
+	// Offset is the file position of the damage.
+	Offset uint64
+	// Detail says what is wrong.
+	Detail string
+	// Err is the underlying error, if there is one, like io.ErrUnexpectedEOF
+	// for a truncated file.
+	Err error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("%v at %d: %s", ErrCorrupt, e.Offset, e.Detail)
+}
+
+func (e *CorruptError) Is(target error) bool {
+	return target == ErrCorrupt || target == ErrBadFormat
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// corruptf returns a CorruptError for the damage at pos.
+func corruptf(pos uint64, format string, args ...interface{}) error {
+	return &CorruptError{Offset: pos, Detail: fmt.Sprintf(format, args...)}
+}
+
+// ownErrors are returned by the package's own reader wrappers, so reads pass
+// them on as they are.
+var ownErrors = []error{ErrDatabaseChanged, ErrFileTruncated, ErrNotCanonical, ErrClosed, ErrCorrupt, ErrInvalidDatabase}
+
+// readErr converts the result of a read of want bytes at pos that read n into
+// the package's errors: nil if the read was complete, a CorruptError if the
+// file ended first, ErrClosed if the file was closed, and a wrapped error
+// otherwise.
+func readErr(pos int64, n, want int, err error) error {
+	if n == want && (err == nil || err == io.EOF) {
+		return nil
+	}
+	switch {
+	case err == nil || err == io.EOF || err == io.ErrUnexpectedEOF:
+		return &CorruptError{Offset: uint64(pos) + uint64(n), Detail: "unexpected end of file", Err: io.ErrUnexpectedEOF}
+	case errors.Is(err, os.ErrClosed):
+		return ErrClosed
+	}
+	var re *readError
+	if errors.As(err, &re) {
+		return err
+	}
+	for _, own := range ownErrors {
+		if errors.Is(err, own) {
+			return err
+		}
+	}
+	return &readError{pos, err}
+}
+
+// readError is an error from the underlying reader, with the position of the
+// read that failed.
+type readError struct {
+	pos int64
+	err error
+}
+
+func (e *readError) Error() string {
+	return fmt.Sprintf("cdb: read at %d: %v", e.pos, e.err)
+}
+
+func (e *readError) Unwrap() error {
+	return e.err
+}
+
+// underlying returns the error from the underlying reader that err wraps, if
+// readErr wrapped it, or err.
+func underlying(err error) error {
+	if re, ok := err.(*readError); ok {
+		return re.err
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestErrorCompat(t *testing.T) {
+	for _, tc := range []struct {
+		err, target error
+	}{
+		{ErrNotFound, io.EOF},
+		{ErrClosed, os.ErrClosed},
+		{ErrWriterClosed, io.ErrClosedPipe},
+		{&CorruptError{Detail: "x"}, ErrCorrupt},
+		{&CorruptError{Detail: "x"}, ErrBadFormat},
+		{&CorruptError{Err: io.ErrUnexpectedEOF}, io.ErrUnexpectedEOF},
+	} {
+		if !errors.Is(tc.err, tc.target) {
+			t.Errorf("expected %v to match %v", tc.err, tc.target)
+		}
+	}
+	if errors.Is(ErrNotFound, ErrClosed) {
+		t.Errorf("expected ErrNotFound not to match ErrClosed")
+	}
+}
+
+func TestErrClosed(t *testing.T) {
+	db := newDB(records)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Bytes([]byte("one")); err != ErrClosed {
+		t.Errorf("Bytes: expected ErrClosed, got: %v", err)
+	}
+	if err := db.ForEachBytes(func(key, val []byte) error { return nil }); err != ErrClosed {
+		t.Errorf("ForEachBytes: expected ErrClosed, got: %v", err)
+	}
+}
+
+func TestErrWriterClosed(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp)
+	if err := w.Write([]byte("k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]byte("k"), []byte("v")); err != ErrWriterClosed {
+		t.Errorf("Write: expected ErrWriterClosed, got: %v", err)
+	}
+	if err := w.Flush(); err != ErrWriterClosed {
+		t.Errorf("Flush: expected ErrWriterClosed, got: %v", err)
+	}
+	if err := w.Close(); err != ErrWriterClosed {
+		t.Errorf("Close: expected ErrWriterClosed, got: %v", err)
+	}
+}
+
+func TestErrInvalidDatabase(t *testing.T) {
+	_, err := New(strings.NewReader("not a database")).Bytes([]byte("one"))
+	if !errors.Is(err, ErrInvalidDatabase) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected ErrInvalidDatabase, got: %v", err)
+	}
+}
+
+func TestCorruptErrorOffset(t *testing.T) {
+	b := newDBBytes(records)
+	// Cut the file off in the middle of the first record's key.
+	_, err := New(bytes.NewReader(b[:headerSize+10])).Bytes([]byte("one"))
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) {
+		t.Fatalf("expected a CorruptError, got: %v", err)
+	}
+	if !errors.Is(err, ErrCorrupt) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected the error to match ErrCorrupt and ErrUnexpectedEOF, got: %v", err)
+	}
+}
+
+func TestMakeValueTooLarge(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	err = Make(tmp, strings.NewReader("+1,4294967296:k->v\n\n"))
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("expected ErrValueTooLarge, got: %v", err)
+	}
+}
+
+func TestErrNotFoundLookups(t *testing.T) {
+	db := newDB(records)
+	key := []byte("missing")
+	_, bytesErr := db.Bytes(key)
+	_, readerErr := db.Reader(key)
+	_, nextBytesErr := db.Iterate(key).NextBytes()
+	_, nextReaderErr := db.Iterate(key).NextReader()
+	_, release, pooledErr := db.BytesPooled(key)
+	release()
+	_, stackErr := NewStack(db, newDB(nil)).Bytes(key)
+	_, annotationErr := db.Annotation(uint64(headerSize))
+	for name, err := range map[string]error{
+		"Bytes":       bytesErr,
+		"Reader":      readerErr,
+		"NextBytes":   nextBytesErr,
+		"NextReader":  nextReaderErr,
+		"BytesPooled": pooledErr,
+		"Stack.Bytes": stackErr,
+		"Annotation":  annotationErr,
+	} {
+		if err != ErrNotFound || !errors.Is(err, io.EOF) {
+			t.Errorf("%s: expected ErrNotFound, got: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package cdb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ProbeResult is what a lookup concluded about a hash slot.
+type ProbeResult int
+
+const (
+	// ProbeEmpty is an empty slot, which ends the lookup.
+	ProbeEmpty ProbeResult = iota
+	// ProbeHashMismatch is a slot for a key with a different hash.
+	ProbeHashMismatch
+	// ProbeKeyLenMismatch is a slot for a key with the same hash but a
+	// different length.
+	ProbeKeyLenMismatch
+	// ProbeKeyMismatch is a slot for a different key of the same hash and
+	// length.
+	ProbeKeyMismatch
+	// ProbeMatch is a slot for a record with the key.
+	ProbeMatch
+)
+
+var probeResultNames = [...]string{"empty slot", "hash mismatch", "key length mismatch", "key mismatch", "match"}
+
+func (r ProbeResult) String() string {
+	if r < 0 || int(r) >= len(probeResultNames) {
+		return "unknown"
+	}
+	return probeResultNames[r]
+}
+
+// Probe is a hash slot visited by a lookup.
+type Probe struct {
+	// Slot is the index of the slot in its hash table, and SlotPos is its file
+	// position.
+	Slot    uint32
+	SlotPos uint64
+	// Hash and RecordPos are the contents of the slot.
+	Hash      uint32
+	RecordPos uint64
+	// KeyLen and DataLen are the lengths in the record header, if the record
+	// was read.
+	KeyLen, DataLen uint32
+	Result          ProbeResult
+}
+
+// Explanation is the full trace of a lookup, returned by Explain.
+type Explanation struct {
+	Key  []byte
+	Hash uint32
+	// Table is the hash table for the key, at TablePos with TableSlots slots.
+	Table      int
+	TablePos   uint64
+	TableSlots uint32
+	// StartSlot is the first slot probed.
+	StartSlot uint32
+	// NegativeCached is true if the negative cache holds the key's hash, so a
+	// normal lookup would have returned ErrNotFound without reading the table.
+	NegativeCached bool
+	Probes         []Probe
+	// DataPos holds the file positions of the values found, in lookup order.
+	DataPos []uint64
+}
+
+// Explain looks up key like Iterate, visiting every slot the lookup would, and
+// returns a trace of what it saw and why each slot was skipped. The negative
+// cache is checked but not trusted, so the trace is always complete.
+//
+// Threadsafe.
+func (c *Cdb) Explain(key []byte) (*Explanation, error) {
+	e := &Explanation{Key: key, Hash: c.checksum(key)}
+	e.Table = int(e.Hash % 256)
+	e.NegativeCached = c.negCache != nil && c.negCache.contains(e.Hash)
+	lay, err := c.layout()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 16)
+	size := lay.entrySize()
+	var slots, h uint64
+	if e.TablePos, slots, err = lay.readEntry(c.r, buf, uint64(e.Hash%256)*size); err != nil {
+		return nil, err
+	}
+	e.TableSlots = uint32(slots)
+	if e.TableSlots == 0 {
+		return e, nil
+	}
+	e.StartSlot = e.Hash / 256 % e.TableSlots
+	klen := uint32(len(key))
+	for n := uint32(0); n < e.TableSlots; n++ {
+		p := Probe{Slot: (e.StartSlot + n) % e.TableSlots}
+		p.SlotPos = e.TablePos + uint64(p.Slot)*size
+		if h, p.RecordPos, err = lay.readEntry(c.r, buf, p.SlotPos); err != nil {
+			return nil, err
+		}
+		p.Hash = uint32(h)
+		switch {
+		case p.RecordPos == 0:
+			p.Result = ProbeEmpty
+		case p.Hash != e.Hash:
+			p.Result = ProbeHashMismatch
+		default:
+			if p.KeyLen, p.DataLen, err = readNums(c.r, buf, int64(p.RecordPos)); err != nil {
+				return nil, err
+			}
+			if p.KeyLen != klen {
+				p.Result = ProbeKeyLenMismatch
+				break
+			}
+			k := make([]byte, klen)
+			if err := readFull(c.r, k, int64(p.RecordPos)+8); err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(k, key) {
+				p.Result = ProbeKeyMismatch
+				break
+			}
+			p.Result = ProbeMatch
+			e.DataPos = append(e.DataPos, p.RecordPos+8+uint64(klen))
+		}
+		e.Probes = append(e.Probes, p)
+		if p.Result == ProbeEmpty {
+			break
+		}
+	}
+	return e, nil
+}
+
+// String formats the trace with one line per probe.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "key %q hash %#08x: table %d at %d with %d slots, starting at slot %d\n",
+		e.Key, e.Hash, e.Table, e.TablePos, e.TableSlots, e.StartSlot)
+	if e.NegativeCached {
+		b.WriteString("hash is in the negative cache\n")
+	}
+	for _, p := range e.Probes {
+		fmt.Fprintf(&b, "  slot %d at %d: hash %#08x record %d", p.Slot, p.SlotPos, p.Hash, p.RecordPos)
+		switch p.Result {
+		case ProbeKeyLenMismatch, ProbeKeyMismatch, ProbeMatch:
+			fmt.Fprintf(&b, " klen %d dlen %d", p.KeyLen, p.DataLen)
+		}
+		fmt.Fprintf(&b, ": %v\n", p.Result)
+	}
+	if len(e.DataPos) == 0 {
+		b.WriteString("not found\n")
+	} else {
+		fmt.Fprintf(&b, "found %d values at %v\n", len(e.DataPos), e.DataPos)
+	}
+	return b.String()
+}
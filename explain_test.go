@@ -0,0 +1,47 @@
+package cdb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	db := New(bytes.NewReader(newDBBytes(records)))
+
+	e, err := db.Explain([]byte("three"))
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	if e.Hash != checksum([]byte("three")) || e.Table != int(e.Hash%256) {
+		t.Errorf("unexpected hash or table: %+v", e)
+	}
+	if len(e.DataPos) != 3 {
+		t.Fatalf("expected 3 values, got: %v", e)
+	}
+	iter := db.Iterate([]byte("three"))
+	for i, pos := range e.DataPos {
+		if err := iter.next(); err != nil {
+			t.Fatal(err)
+		}
+		if iter.dpos != pos {
+			t.Errorf("value %d: expected position %d, got: %d", i, iter.dpos, pos)
+		}
+	}
+	for _, p := range e.Probes {
+		if p.Result != ProbeMatch && p.Result != ProbeEmpty {
+			t.Errorf("unexpected probe: %+v", p)
+		}
+	}
+	if s := e.String(); !strings.Contains(s, "found 3 values") || !strings.Contains(s, "match") {
+		t.Errorf("unexpected String: %s", s)
+	}
+
+	e, err = db.Explain([]byte("missing"))
+	if err != nil {
+		t.Fatalf("Explain error: %v", err)
+	}
+	if len(e.DataPos) != 0 || !strings.Contains(e.String(), "not found") {
+		t.Errorf("expected missing key not to be found, got: %s", e)
+	}
+}
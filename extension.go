@@ -0,0 +1,157 @@
+package cdb
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Extensions are optional sections stored after the last hash table, where
+// readers that follow the original cdb specification never look. The region
+// starts with extMagic and the number of sections, followed by the sections,
+// each a tag, a length and that many bytes of data. All numbers are 32 bit
+// little endian.
+const extMagic = "cdbext01"
+
+// Extension section tags.
+const (
+	extAnnotations uint32 = 1
+	extBuildTime   uint32 = 2
+	extChecksums   uint32 = 3
+)
+
+// extension is a section to be written after the hash tables.
+type extension struct {
+	tag  uint32
+	data []byte
+}
+
+// extensions holds the location of the extension sections of a database,
+// read on first use.
+type extensions struct {
+	// loaded is set to 1, after sections and err, once the sections have
+	// been read or found to be corrupt. Other failures aren't kept, so reads
+	// that fail for a while are tried again.
+	loaded   uint32
+	mu       sync.Mutex
+	sections map[uint32]*io.SectionReader
+	err      error
+}
+
+// extension returns a reader for the section of the database with the given
+// tag, or nil if there is no such section.
+//
+// Threadsafe.
+func (c *Cdb) extension(tag uint32) (*io.SectionReader, error) {
+	if atomic.LoadUint32(&c.ext.loaded) == 0 {
+		c.ext.mu.Lock()
+		if c.ext.loaded == 0 {
+			sections, _, err := readExtensions(c.r)
+			if err != nil && !errors.Is(err, ErrCorrupt) {
+				c.ext.mu.Unlock()
+				return nil, err
+			}
+			c.ext.sections, c.ext.err = sections, err
+			atomic.StoreUint32(&c.ext.loaded, 1)
+		}
+		c.ext.mu.Unlock()
+	}
+	if c.ext.err != nil {
+		return nil, c.ext.err
+	}
+	return c.ext.sections[tag], nil
+}
+
+// tablesEnd returns the file position just past the last hash table.
+func tablesEnd(r io.ReaderAt) (uint64, error) {
+	lay, err := detectLayout(r)
+	if err != nil {
+		return 0, err
+	}
+	header := make([]byte, lay.headerSize())
+	if err := readFull(r, header, 0); err != nil {
+		return 0, err
+	}
+	var end uint64
+	for i := uint64(0); i < 256; i++ {
+		pos, nslots := lay.entry(header[i*lay.entrySize():])
+		if pos+nslots*lay.entrySize() > end {
+			end = pos + nslots*lay.entrySize()
+		}
+	}
+	return end, nil
+}
+
+// readExtensions returns the extension sections of the database in r, by tag,
+// and the file position just past them, which is the end of the database.
+func readExtensions(r io.ReaderAt) (map[uint32]*io.SectionReader, uint64, error) {
+	pos, err := tablesEnd(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	buf := make([]byte, len(extMagic))
+	n, err := r.ReadAt(buf, int64(pos))
+	if n < len(buf) && err != nil && err != io.EOF {
+		return nil, 0, readErr(int64(pos), n, len(buf), err)
+	}
+	if n < len(buf) || string(buf) != extMagic {
+		// There are no extensions.
+		return nil, pos, nil
+	}
+	pos += uint64(len(extMagic))
+	count, _, err := readNums(r, buf, int64(pos))
+	if err != nil {
+		return nil, 0, err
+	}
+	pos += 4
+	sections := make(map[uint32]*io.SectionReader, count)
+	for i := uint32(0); i < count; i++ {
+		tag, length, err := readNums(r, buf, int64(pos))
+		if err != nil {
+			return nil, 0, err
+		}
+		sections[tag] = io.NewSectionReader(r, int64(pos+8), int64(length))
+		pos += 8 + uint64(length)
+	}
+	return sections, pos, nil
+}
+
+// extensionsSize returns the number of bytes writeExtensions writes for exts.
+func extensionsSize(exts []extension) uint64 {
+	if len(exts) == 0 {
+		return 0
+	}
+	n := uint64(len(extMagic) + 4)
+	for _, ext := range exts {
+		n += 8 + uint64(len(ext.data))
+	}
+	return n
+}
+
+// writeExtensions writes the extension region, if there are any extensions.
+func writeExtensions(w *bufio.Writer, exts []extension) error {
+	if len(exts) == 0 {
+		return nil
+	}
+	buf := make([]byte, 8)
+	if _, err := w.WriteString(extMagic); err != nil {
+		return err
+	}
+	putNum(buf, uint32(len(exts)))
+	if _, err := w.Write(buf[:4]); err != nil {
+		return err
+	}
+	for _, ext := range exts {
+		putNum(buf, ext.tag)
+		putNum(buf[4:], uint32(len(ext.data)))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		if _, err := w.Write(ext.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
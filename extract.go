@@ -0,0 +1,62 @@
+package cdb
+
+import (
+	"bufio"
+	"io"
+)
+
+// Extract writes every value of each of the given keys in db to out, for
+// building a smaller database from a subset of a larger one. Keys that aren't
+// in db are skipped, and keys that are listed more than once are only copied
+// once. Records are copied raw, like CopyRecord does, so a ValueTransform on
+// db isn't applied, and tombstones are kept.
+//
+// Out is not closed.
+func Extract(db *Cdb, keys [][]byte, out *Writer) error {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		if err := extractKey(db, key, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractReader is like Extract, but reads the keys from r, one per line.
+func ExtractReader(db *Cdb, r io.Reader, out *Writer) error {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	for scanner.Scan() {
+		key := scanner.Bytes()
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		if err := extractKey(db, key, out); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// extractKey copies the raw records of key in db to out.
+func extractKey(db *Cdb, key []byte, out *Writer) error {
+	iter := db.Iterate(key)
+	for {
+		err := iter.next()
+		if err == ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := out.copyRecord(iter.r, iter.recordPos(), uint32(len(key)), iter.dlen); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package cdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	db := newDB(records)
+	for _, useReader := range []bool{false, true} {
+		tmp, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmp.Name())
+		out := NewWriter(tmp)
+		if useReader {
+			err = ExtractReader(db, strings.NewReader("three\nmissing\none\nthree\n"), out)
+		} else {
+			err = Extract(db, [][]byte{[]byte("three"), []byte("missing"), []byte("one"), []byte("three")}, out)
+		}
+		if err != nil {
+			t.Fatalf("Extract error: %v", err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		err = New(bytes.NewReader(b)).ForEachBytes(func(key, val []byte) error {
+			got = append(got, string(key)+"="+string(val))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"three=3", "three=33", "three=333", "one=1"}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got: %v", expected, got)
+		}
+	}
+}
+
+func TestExtractRaw(t *testing.T) {
+	// The transform and tombstone options of db don't change what is copied.
+	upper := ValueTransform(func(key, val []byte) ([]byte, error) {
+		return bytes.ToUpper(val), nil
+	})
+	db := New(bytes.NewReader(newDBBytes([]rec{{"a", []string{"x", ""}}})), upper, TombstoneEmptyValues())
+	out := new(memBuffer)
+	w := NewWriter(out)
+	if err := Extract(db, [][]byte{[]byte("a")}, w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := New(bytes.NewReader(out.b)).AllValues([]byte("a")); err != nil || !reflect.DeepEqual(got, [][]byte{[]byte("x"), {}}) {
+		t.Errorf("expected the raw values, got: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package cdb
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// NewFromFd returns a new Cdb reading from an already open file descriptor,
+// such as one opened by C code using tinycdb, to help migrate mixed C and Go
+// code to this package a piece at a time. The descriptor is duplicated, so
+// the caller keeps ownership of fd and may close it at any time, and Close
+// only closes the duplicate.
+//
+// The descriptor can also be one passed from another process, as the cdbfd
+// package does. On Linux it can be opened with O_PATH, which only names the
+// file, and is then reopened for reading. WithMmap is supported.
+func NewFromFd(fd uintptr, opts ...Option) (*Cdb, error) {
+	dup, err := syscall.Dup(int(fd))
+	if err != nil {
+		return nil, os.NewSyscallError("dup", err)
+	}
+	syscall.CloseOnExec(dup)
+	f, err := reopenPath(os.NewFile(uintptr(dup), fmt.Sprintf("fd%d", fd)))
+	if err != nil {
+		return nil, err
+	}
+	return newCdb(opts).fromFile(f)
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNewFromFd(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(newDBBytes(records))
+
+	db, err := NewFromFd(tmp.Fd())
+	if err != nil {
+		t.Fatalf("NewFromFd error: %v", err)
+	}
+	// The caller still owns the original descriptor.
+	if err := tmp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected 1, got: %s, %v", b, err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// varintRecords returns recs in the varint framing.
+func varintRecords(recs []rec) []byte {
+	var b []byte
+	for _, r := range recs {
+		for _, val := range r.values {
+			b = binary.AppendUvarint(b, uint64(len(r.key)))
+			b = binary.AppendUvarint(b, uint64(len(val)))
+			b = append(b, r.key...)
+			b = append(b, val...)
+		}
+	}
+	return b
+}
+
+func TestWriterReadFrom(t *testing.T) {
+	for _, tee := range []bool{false, true} {
+		tmp, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmp.Name())
+		var teeBuf bytes.Buffer
+		var opts []WriterOption
+		if tee {
+			opts = append(opts, Tee(&teeBuf))
+		}
+		w := NewWriter(tmp, opts...)
+		frames := varintRecords(records)
+		n, err := w.ReadFrom(bytes.NewReader(frames))
+		if err != nil || n != int64(len(frames)) {
+			t.Fatalf("expected %d bytes read, got: %d, %v", len(frames), n, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, newDBBytes(records)) {
+			t.Errorf("tee %v: expected the same database as Write builds", tee)
+		}
+		if tee && !bytes.Equal(teeBuf.Bytes(), data) {
+			t.Errorf("expected the records teed, got: %q", teeBuf.String())
+		}
+	}
+}
+
+func TestWriterReadFromTruncated(t *testing.T) {
+	frames := varintRecords(records)
+	// Cut in the last value, and between the first key and value lengths.
+	for _, cut := range []int{len(frames) - 1, 1} {
+		for _, tee := range []bool{false, true} {
+			var opts []WriterOption
+			if tee {
+				opts = append(opts, Tee(ioutil.Discard))
+			}
+			w := NewWriter(new(memBuffer), opts...)
+			if _, err := w.ReadFrom(bytes.NewReader(frames[:cut])); err != io.ErrUnexpectedEOF {
+				t.Errorf("cut %d, tee %v: expected ErrUnexpectedEOF, got: %v", cut, tee, err)
+			}
+			if err := w.Close(); err == nil {
+				t.Errorf("cut %d, tee %v: expected Close to fail after a truncated record", cut, tee)
+			}
+		}
+	}
+}
+
+func TestCdbWriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := newDB(records).WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := varintRecords(records)
+	if n != int64(len(expected)) || !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("expected %q, got: %d bytes, %q", expected, n, buf.Bytes())
+	}
+
+	// A truncated database fails rather than exporting part of a record.
+	b := newDBBytes(records)
+	if _, err := New(bytes.NewReader(b[:headerSize+10])).WriteTo(ioutil.Discard); err == nil {
+		t.Errorf("expected an error exporting a truncated database")
+	}
+}
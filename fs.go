@@ -0,0 +1,49 @@
+package cdb
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"runtime"
+)
+
+// File is an open database file. Files from os, from most fs.FS
+// implementations, and from filesystem libraries such as afero and go-billy
+// satisfy it.
+type File interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// NewFile returns a new Cdb reading from f, which should be a cdb-format
+// database. Unlike New, the Cdb owns f, and closes it on Close.
+func NewFile(f File, opts ...Option) *Cdb {
+	c := newCdb(opts)
+	c.setReader(f)
+	c.closer = f
+	runtime.SetFinalizer(c, (*Cdb).Close)
+	return c
+}
+
+// OpenFS opens the named file in fsys and returns a new Cdb object, so
+// databases can live in embedded or in-memory filesystems. The file must
+// implement io.ReaderAt.
+func OpenFS(fsys fs.FS, name string, opts ...Option) (*Cdb, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		f.Close()
+		return nil, fmt.Errorf("cdb: %s does not implement io.ReaderAt", name)
+	}
+	return NewFile(fsFileReader{ra, f}, opts...), nil
+}
+
+// fsFileReader is a file opened by OpenFS. It keeps the file's Stat method,
+// for DetectChanges.
+type fsFileReader struct {
+	io.ReaderAt
+	fs.File
+}
@@ -0,0 +1,26 @@
+package cdb
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOpenFS(t *testing.T) {
+	fsys := fstest.MapFS{"dbs/test.cdb": {Data: newDBBytes(records)}}
+	db, err := OpenFS(fsys, "dbs/test.cdb")
+	if err != nil {
+		t.Fatalf("OpenFS error: %v", err)
+	}
+	defer db.Close()
+	b, err := db.Bytes([]byte("two"))
+	if err != nil {
+		t.Fatalf("Bytes error: %v", err)
+	}
+	if !bytes.Equal(b, []byte("2")) {
+		t.Errorf("expected 2, got: %s", b)
+	}
+	if _, err := OpenFS(fsys, "dbs/missing.cdb"); err == nil {
+		t.Errorf("expected an error opening a missing file")
+	}
+}
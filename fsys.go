@@ -0,0 +1,263 @@
+package cdb
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FS returns the database as a read-only fs.FS. Each key that is a valid path
+// names a file holding the key's first value, and the directories are the
+// ones the keys imply, so the key "a/b" is the file b in the directory a. A
+// key that is also the directory of other keys is a file. Everything has the
+// ModTime recorded with the BuildTime WriterOption.
+//
+// Use http.FS to serve the database with http.FileServer, or template.ParseFS
+// to load templates from it. The first directory read scans every key.
+//
+// Threadsafe.
+func (c *Cdb) FS() fs.FS {
+	return &cdbFS{c: c}
+}
+
+type cdbFS struct {
+	c *Cdb
+
+	// mu guards loading modTime and dirs, which are read on first use.
+	// Failures aren't kept, so they are tried again on the next call.
+	mu sync.Mutex
+	// timeLoaded is set to 1, after modTime, once it has been read.
+	timeLoaded uint32
+	modTime    time.Time
+	// dirsLoaded is set to 1, after dirs, once they have been built.
+	dirsLoaded uint32
+	// dirs holds the entries of each directory, sorted by name.
+	dirs map[string][]fs.DirEntry
+}
+
+func (f *cdbFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if err := f.loadModTime(); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if name != "." {
+		r, err := f.c.Reader([]byte(name))
+		if err == nil {
+			return &fsFile{r, fileInfo{path.Base(name), r.Size(), 0444, f.modTime}}, nil
+		}
+		if err != ErrNotFound {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	if err := f.loadDirs(); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	entries, ok := f.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &fsDir{fileInfo{path.Base(name), 0, fs.ModeDir | 0555, f.modTime}, entries, 0}, nil
+}
+
+// loadModTime reads the build time of the database, which is the ModTime of
+// everything, if it hasn't been read yet.
+func (f *cdbFS) loadModTime() error {
+	if atomic.LoadUint32(&f.timeLoaded) == 1 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.timeLoaded == 0 {
+		t, err := f.c.BuildTime()
+		if err != nil {
+			return err
+		}
+		f.modTime = t
+		atomic.StoreUint32(&f.timeLoaded, 1)
+	}
+	return nil
+}
+
+// loadDirs builds the directory tree, if it hasn't been built yet.
+func (f *cdbFS) loadDirs() error {
+	if atomic.LoadUint32(&f.dirsLoaded) == 1 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dirsLoaded == 0 {
+		if err := f.readDirs(); err != nil {
+			return err
+		}
+		atomic.StoreUint32(&f.dirsLoaded, 1)
+	}
+	return nil
+}
+
+// ReadFile implements fs.ReadFileFS, so fs.ReadFile and template.ParseFS read
+// a file's value with a single lookup.
+func (f *cdbFS) ReadFile(name string) ([]byte, error) {
+	if err := f.loadModTime(); err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	if fs.ValidPath(name) && name != "." {
+		b, err := f.c.Bytes([]byte(name))
+		if err == nil {
+			return b, nil
+		}
+		if err != ErrNotFound {
+			return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+		}
+	}
+	// Let Open report what name is instead.
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// readDirs builds the directory tree from the keys.
+func (f *cdbFS) readDirs() error {
+	files := make(map[string]bool)
+	dirs := map[string]bool{".": true}
+	var key []byte
+	err := f.c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		if uint32(cap(key)) < klen {
+			key = make([]byte, klen)
+		}
+		key = key[:klen]
+		if err := readFull(f.c.r, key, int64(pos)+8); err != nil {
+			return err
+		}
+		name := string(key)
+		if name == "." || !fs.ValidPath(name) || files[name] {
+			return nil
+		}
+		files[name] = true
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	f.dirs = make(map[string][]fs.DirEntry, len(dirs))
+	for dir := range dirs {
+		if !files[dir] {
+			f.dirs[dir] = nil
+		}
+	}
+	add := func(name string, isDir bool) {
+		dir := path.Dir(name)
+		f.dirs[dir] = append(f.dirs[dir], &dirEntry{f, name, isDir})
+	}
+	for name := range files {
+		if f.reachable(path.Dir(name)) {
+			add(name, false)
+		}
+	}
+	for dir := range f.dirs {
+		if dir != "." && f.reachable(path.Dir(dir)) {
+			add(dir, true)
+		}
+	}
+	for _, entries := range f.dirs {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+	return nil
+}
+
+// reachable returns true if dir and all of its parents are directories
+// rather than files.
+func (f *cdbFS) reachable(dir string) bool {
+	for ; dir != "."; dir = path.Dir(dir) {
+		if _, ok := f.dirs[dir]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// fileInfo describes a file or directory of a cdbFS.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+// dirEntry is an entry of a directory listing. Its info is looked up when it
+// is asked for, since that reads the value.
+type dirEntry struct {
+	fsys  *cdbFS
+	path  string
+	isDir bool
+}
+
+func (e *dirEntry) Name() string { return path.Base(e.path) }
+func (e *dirEntry) IsDir() bool  { return e.isDir }
+
+func (e *dirEntry) Type() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	return fs.Stat(e.fsys, e.path)
+}
+
+// fsFile is a file of a cdbFS, reading a value.
+type fsFile struct {
+	*io.SectionReader
+	info fileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *fsFile) Close() error               { return nil }
+
+// fsDir is a directory of a cdbFS.
+type fsDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	off     int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries[d.off:]
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	d.off += len(entries)
+	return entries, nil
+}
@@ -0,0 +1,142 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var fsRecords = []rec{
+	{"index.html", []string{"<h1>hi</h1>", "shadowed"}},
+	{"css/site.css", []string{"body{}"}},
+	{"css/print/a.css", []string{""}},
+	{"/absolute", []string{"not a valid path"}},
+	{"x/../y", []string{"not a valid path"}},
+	{"page", []string{"a file"}},
+	{"page/hidden", []string{"under a file"}},
+}
+
+func newFSDB(t *testing.T, opts ...WriterOption) *Cdb {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp, opts...)
+	for _, rec := range fsRecords {
+		for _, val := range rec.values {
+			if err := w.Write([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(bytes.NewReader(b))
+}
+
+func TestFS(t *testing.T) {
+	built := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	db := newFSDB(t, BuildTime(built))
+	if bt, err := db.BuildTime(); err != nil || !bt.Equal(built) {
+		t.Fatalf("expected build time %v, got: %v, %v", built, bt, err)
+	}
+	fsys := db.FS()
+	if err := fstest.TestFS(fsys, "index.html", "css/site.css", "css/print/a.css", "page"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fsys, "index.html")
+	if err != nil || string(b) != "<h1>hi</h1>" {
+		t.Errorf("expected the first value, got: %q, %v", b, err)
+	}
+	fi, err := fs.Stat(fsys, "css")
+	if err != nil || !fi.IsDir() || !fi.ModTime().Equal(built) {
+		t.Errorf("expected css to be a directory built at %v, got: %+v, %v", built, fi, err)
+	}
+	if _, err := fs.ReadFile(fsys, "css"); err == nil {
+		t.Errorf("expected an error reading a directory")
+	}
+	if _, err := fsys.Open("missing"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist for a missing file, got: %v", err)
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 3 || names[0] != "css" || names[1] != "index.html" || names[2] != "page" {
+		t.Errorf("expected css, index.html and page in the root, got: %v", names)
+	}
+}
+
+func TestFSHTTP(t *testing.T) {
+	built := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	srv := httptest.NewServer(http.FileServer(http.FS(newFSDB(t, BuildTime(built)).FS())))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/css/site.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "body{}" {
+		t.Errorf("expected 200 body{}, got: %d %q", resp.StatusCode, body)
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != built.Format(http.TimeFormat) {
+		t.Errorf("expected Last-Modified %q, got: %q", built.Format(http.TimeFormat), lm)
+	}
+}
+
+func TestBuildTimeMissing(t *testing.T) {
+	if bt, err := newDB(records).BuildTime(); err != nil || !bt.IsZero() {
+		t.Errorf("expected the zero time, got: %v, %v", bt, err)
+	}
+}
+
+func TestFSRetryAfterReadErrors(t *testing.T) {
+	built := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := &flakyReaderAt{r: newFSDB(t, BuildTime(built)).r, failing: true}
+	fsys := New(r).FS()
+
+	// A failed read of the build time isn't remembered.
+	if _, err := fs.ReadFile(fsys, "index.html"); !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the read error, got: %v", err)
+	}
+	r.failing = false
+	if b, err := fs.ReadFile(fsys, "index.html"); err != nil || string(b) != "<h1>hi</h1>" {
+		t.Fatalf("expected index.html, got: %q, %v", b, err)
+	}
+
+	// Nor is a failed scan of the keys.
+	r.failing = true
+	if _, err := fs.ReadDir(fsys, "."); !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the read error, got: %v", err)
+	}
+	r.failing = false
+	entries, err := fs.ReadDir(fsys, "css")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("expected the css directory, got: %v, %v", entries, err)
+	}
+	info, err := fs.Stat(fsys, "css/site.css")
+	if err != nil || !info.ModTime().Equal(built) {
+		t.Errorf("expected ModTime %v, got: %v, %v", built, info, err)
+	}
+}
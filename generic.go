@@ -0,0 +1,115 @@
+//go:build go1.18
+
+package cdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+)
+
+// Codec converts keys or values of type T to and from bytes. Codecs for
+// other encodings, like protocol buffers, can be made from their marshal and
+// unmarshal functions.
+type Codec[T any] struct {
+	Encode func(T) ([]byte, error)
+	Decode func([]byte) (T, error)
+}
+
+// StringCodec stores strings as their bytes.
+func StringCodec() Codec[string] {
+	return Codec[string]{
+		Encode: func(s string) ([]byte, error) { return []byte(s), nil },
+		Decode: func(b []byte) (string, error) { return string(b), nil },
+	}
+}
+
+// BytesCodec stores byte slices as they are.
+func BytesCodec() Codec[[]byte] {
+	return Codec[[]byte]{
+		Encode: func(b []byte) ([]byte, error) { return b, nil },
+		Decode: func(b []byte) ([]byte, error) { return b, nil },
+	}
+}
+
+// JSONCodec stores values as JSON.
+func JSONCodec[T any]() Codec[T] {
+	return Codec[T]{
+		Encode: func(v T) ([]byte, error) { return json.Marshal(v) },
+		Decode: func(b []byte) (T, error) {
+			var v T
+			err := json.Unmarshal(b, &v)
+			return v, err
+		},
+	}
+}
+
+// GobCodec stores values encoded with encoding/gob. Each value carries its
+// own type description, so gob suits small databases of complex values
+// better than large ones.
+func GobCodec[T any]() Codec[T] {
+	return Codec[T]{
+		Encode: func(v T) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(v)
+			return buf.Bytes(), err
+		},
+		Decode: func(b []byte) (T, error) {
+			var v T
+			err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+			return v, err
+		},
+	}
+}
+
+// Typed reads and writes keys of type K and values of type V through codecs,
+// so callers don't convert to and from bytes at every call.
+//
+// Threadsafe if the Writer is only used by one goroutine at a time.
+type Typed[K, V any] struct {
+	db   *Cdb
+	w    *Writer
+	keys Codec[K]
+	vals Codec[V]
+}
+
+// NewTyped returns a Typed reading from db and writing to w. Either may be
+// nil, for a Typed that only writes or only reads.
+func NewTyped[K, V any](db *Cdb, w *Writer, keys Codec[K], vals Codec[V]) *Typed[K, V] {
+	return &Typed[K, V]{db: db, w: w, keys: keys, vals: vals}
+}
+
+// Get returns the first value for k. Returns ErrNotFound when there is no
+// value.
+func (t *Typed[K, V]) Get(k K) (V, error) {
+	var zero V
+	if t.db == nil {
+		return zero, errors.New("cdb: Typed has no database to read")
+	}
+	key, err := t.keys.Encode(k)
+	if err != nil {
+		return zero, err
+	}
+	b, err := t.db.Bytes(key)
+	if err != nil {
+		return zero, err
+	}
+	return t.vals.Decode(b)
+}
+
+// Put writes a record for k and v.
+func (t *Typed[K, V]) Put(k K, v V) error {
+	if t.w == nil {
+		return errors.New("cdb: Typed has no Writer")
+	}
+	key, err := t.keys.Encode(k)
+	if err != nil {
+		return err
+	}
+	val, err := t.vals.Encode(v)
+	if err != nil {
+		return err
+	}
+	return t.w.Put(key, val)
+}
@@ -0,0 +1,42 @@
+//go:build go1.18
+
+package cdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type point struct{ X, Y int }
+
+func TestTyped(t *testing.T) {
+	for name, vals := range map[string]Codec[point]{
+		"json": JSONCodec[point](),
+		"gob":  GobCodec[point](),
+	} {
+		bw := NewBufferWriter()
+		w := NewTyped[string, point](nil, bw.Writer, StringCodec(), vals)
+		if err := w.Put("a", point{1, 2}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Get("a"); err == nil {
+			t.Errorf("%s: expected an error reading without a database", name)
+		}
+		data, err := bw.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := NewTyped[string, point](New(bytes.NewReader(data)), nil, StringCodec(), vals)
+		if p, err := r.Get("a"); err != nil || !reflect.DeepEqual(p, point{1, 2}) {
+			t.Errorf("%s: expected {1 2}, got: %v, %v", name, p, err)
+		}
+		if _, err := r.Get("b"); err != ErrNotFound {
+			t.Errorf("%s: expected ErrNotFound, got: %v", name, err)
+		}
+		if err := r.Put("b", point{}); err == nil {
+			t.Errorf("%s: expected an error writing without a Writer", name)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package cdb
+
+// setLowIOPriority does nothing where IO priorities aren't supported.
+func setLowIOPriority() error {
+	return nil
+}
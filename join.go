@@ -0,0 +1,32 @@
+package cdb
+
+// ForEachInBoth calls fn for every key that is in both a and b, with a value
+// from each. Records of a are visited in data order, and each is paired with
+// every value for its key in b, so keys with several values in both give
+// every combination.
+//
+// Neither database is loaded into memory: a is scanned once and each of its
+// keys is looked up in b. Iterating over the smaller database as a is
+// cheapest.
+//
+// The byte slices are only valid for the length of a call to fn. If fn returns
+// an error, iteration will stop and the error will be returned.
+//
+// Threadsafe.
+func ForEachInBoth(a, b *Cdb, fn func(key, valA, valB []byte) error) error {
+	return a.ForEachBytes(func(key, valA []byte) error {
+		iter := b.Iterate(key)
+		for {
+			valB, err := iter.NextBytes()
+			if err == ErrNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(key, valA, valB); err != nil {
+				return err
+			}
+		}
+	})
+}
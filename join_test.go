@@ -0,0 +1,32 @@
+package cdb
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestForEachInBoth(t *testing.T) {
+	a := New(bytes.NewReader(newDBBytes(records)))
+	b := New(bytes.NewReader(newDBBytes([]rec{
+		{"two", []string{"b2"}},
+		{"three", []string{"b3", "b33"}},
+		{"four", []string{"b4"}},
+	})))
+	var got []string
+	err := ForEachInBoth(a, b, func(key, valA, valB []byte) error {
+		got = append(got, fmt.Sprintf("%s:%s:%s", key, valA, valB))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachInBoth error: %v", err)
+	}
+	expected := []string{
+		"two:2:b2", "two:22:b2",
+		"three:3:b3", "three:3:b33", "three:33:b3", "three:33:b33", "three:333:b3", "three:333:b33",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got: %v", expected, got)
+	}
+}
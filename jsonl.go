@@ -0,0 +1,123 @@
+package cdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// jsonRecord is the JSON Lines representation of a record. Keys and values
+// that are valid UTF-8 are stored as strings, anything else is stored base64
+// encoded in the _base64 fields.
+type jsonRecord struct {
+	Key         *string `json:"key,omitempty"`
+	KeyBase64   []byte  `json:"key_base64,omitempty"`
+	Value       *string `json:"value,omitempty"`
+	ValueBase64 []byte  `json:"value_base64,omitempty"`
+}
+
+func newJSONRecord(key, val []byte) jsonRecord {
+	var rec jsonRecord
+	if utf8.Valid(key) {
+		s := string(key)
+		rec.Key = &s
+	} else {
+		rec.KeyBase64 = key
+	}
+	if utf8.Valid(val) {
+		s := string(val)
+		rec.Value = &s
+	} else {
+		rec.ValueBase64 = val
+	}
+	return rec
+}
+
+// WriteJSONLine writes a record to w as one line of the JSON Lines format
+// written by DumpJSON and read by MakeFromJSON.
+func WriteJSONLine(w io.Writer, key, val []byte) error {
+	b, err := json.Marshal(newJSONRecord(key, val))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// record returns the key and value of the record. Both must be given, as a
+// string or base64.
+func (rec *jsonRecord) record() (key, val []byte, err error) {
+	switch {
+	case rec.Key != nil:
+		key = []byte(*rec.Key)
+	case rec.KeyBase64 != nil:
+		key = rec.KeyBase64
+	default:
+		return nil, nil, fmt.Errorf("%w: record has no key", ErrBadFormat)
+	}
+	switch {
+	case rec.Value != nil:
+		val = []byte(*rec.Value)
+	case rec.ValueBase64 != nil:
+		val = rec.ValueBase64
+	default:
+		return nil, nil, fmt.Errorf("%w: record has no value", ErrBadFormat)
+	}
+	return key, val, nil
+}
+
+// DumpJSON writes the records of db to w as JSON Lines, in the order they
+// were written: one {"key":...,"value":...} object per line. Keys and values
+// that aren't valid UTF-8 are written base64 encoded, as key_base64 or
+// value_base64. The output is suitable as input to MakeFromJSON.
+//
+// Threadsafe.
+func DumpJSON(w io.Writer, db *Cdb) error {
+	bw := bufio.NewWriter(w)
+	err := db.ForEachBytes(func(key, val []byte) error {
+		return WriteJSONLine(bw, key, val)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// MakeFromJSON reads records in the JSON Lines format written by DumpJSON
+// from r and writes a database to ws. Like Make, the input may be compressed.
+func MakeFromJSON(ws io.WriteSeeker, r io.Reader) error {
+	w := NewWriter(ws)
+	if err := w.PutJSONLines(r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// PutJSONLines reads records in the JSON Lines format written by DumpJSON
+// from r and adds them to the database. Like Make, the input may be
+// compressed.
+func (w *Writer) PutJSONLines(r io.Reader) error {
+	rb, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(rb)
+	for {
+		var rec jsonRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", ErrBadFormat, err)
+		}
+		key, val, err := rec.record()
+		if err == nil {
+			err = w.Put(key, val)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
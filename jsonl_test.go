@@ -0,0 +1,33 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONLines(t *testing.T) {
+	recs := append([]rec{{"bin\xff", []string{"\x00\xfe"}}}, records...)
+	var buf bytes.Buffer
+	if err := DumpJSON(&buf, newDB(recs)); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"key_base64":"Ymlu/w==","value_base64":"AP4="}` + "\n" + `{"key":"one","value":"1"}` + "\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("expected the output to start with %q, got: %q", want, buf.String())
+	}
+
+	out := new(memBuffer)
+	if err := MakeFromJSON(out, &buf); err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, New(bytes.NewReader(out.b)), recs)
+}
+
+func TestMakeFromJSONErrors(t *testing.T) {
+	for _, in := range []string{`{"key":"a"}`, `{"value":"a"}`, `{"key":`} {
+		if err := MakeFromJSON(new(memBuffer), strings.NewReader(in)); !errors.Is(err, ErrBadFormat) {
+			t.Errorf("%s: expected ErrBadFormat, got: %v", in, err)
+		}
+	}
+}
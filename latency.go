@@ -0,0 +1,109 @@
+package cdb
+
+import (
+	"expvar"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets are the histogram buckets used by RecordLatency when
+// none are given.
+var DefaultLatencyBuckets = []time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// RecordLatency returns an Option that records how long lookups with Exists,
+// Bytes and Reader take in a histogram with the given bucket upper bounds, for
+// tracking latency objectives. The histogram is read with LatencyStats.
+func RecordLatency(buckets ...time.Duration) Option {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return func(c *Cdb) {
+		c.latency = &latencyHistogram{
+			buckets: buckets,
+			counts:  make([]uint64, len(buckets)+1),
+		}
+	}
+}
+
+// LatencyStats is a snapshot of a lookup latency histogram.
+type LatencyStats struct {
+	// Buckets are the upper bounds of the histogram buckets.
+	Buckets []time.Duration
+	// Counts are the number of lookups in each bucket. It has one more element
+	// than Buckets, counting lookups slower than the last bucket.
+	Counts []uint64
+	// Count is the total number of lookups and Sum their total duration.
+	Count uint64
+	Sum   time.Duration
+}
+
+// latencyHistogram is a histogram that can be updated without locking.
+type latencyHistogram struct {
+	buckets []time.Duration
+	counts  []uint64
+	count   uint64
+	sum     int64
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := sort.Search(len(h.buckets), func(i int) bool { return d <= h.buckets[i] })
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+// observeSince records the time since start, if latency is being recorded.
+func (c *Cdb) observeSince(start time.Time) {
+	if c.latency != nil {
+		c.latency.observe(time.Since(start))
+	}
+}
+
+// now returns the current time if latency is being recorded.
+func (c *Cdb) now() time.Time {
+	if c.latency == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// LatencyStats returns a snapshot of the lookup latency histogram. It is empty
+// unless the RecordLatency option was used. The counts are read one at a time,
+// so lookups made during the call may only be counted in some of them.
+//
+// Threadsafe.
+func (c *Cdb) LatencyStats() LatencyStats {
+	h := c.latency
+	if h == nil {
+		return LatencyStats{}
+	}
+	stats := LatencyStats{
+		Buckets: append([]time.Duration(nil), h.buckets...),
+		Counts:  make([]uint64, len(h.counts)),
+		Count:   atomic.LoadUint64(&h.count),
+		Sum:     time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.counts {
+		stats.Counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return stats
+}
+
+// LatencyVar returns an expvar.Var reporting LatencyStats, for publishing
+// with expvar.Publish.
+func (c *Cdb) LatencyVar() expvar.Var {
+	return expvar.Func(func() interface{} { return c.LatencyStats() })
+}
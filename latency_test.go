@@ -0,0 +1,42 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecordLatency(t *testing.T) {
+	db := New(bytes.NewReader(newDBBytes(records)), RecordLatency(time.Hour, time.Nanosecond))
+	db.Bytes([]byte("one"))
+	db.Exists([]byte("missing"))
+	db.Reader([]byte("two"))
+
+	stats := db.LatencyStats()
+	if stats.Count != 3 {
+		t.Errorf("expected 3 lookups, got: %v", stats.Count)
+	}
+	if len(stats.Buckets) != 2 || stats.Buckets[0] != time.Nanosecond {
+		t.Errorf("expected sorted buckets, got: %v", stats.Buckets)
+	}
+	var total uint64
+	for _, n := range stats.Counts {
+		total += n
+	}
+	if total != stats.Count {
+		t.Errorf("expected bucket counts to add up to %v, got: %v", stats.Count, total)
+	}
+
+	var decoded LatencyStats
+	if err := json.Unmarshal([]byte(db.LatencyVar().String()), &decoded); err != nil {
+		t.Fatalf("LatencyVar isn't JSON: %v", err)
+	}
+	if decoded.Count != 3 {
+		t.Errorf("expected LatencyVar count 3, got: %v", decoded.Count)
+	}
+
+	if stats := newDB(records).LatencyStats(); stats.Count != 0 || stats.Counts != nil {
+		t.Errorf("expected no stats without RecordLatency, got: %+v", stats)
+	}
+}
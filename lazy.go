@@ -0,0 +1,112 @@
+package cdb
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// LazyOpen returns a new Cdb for the named file without opening it. The file
+// is opened on the first lookup. If it can't be opened yet, for example
+// because it hasn't been pushed, the lookup returns the open error and the
+// next lookup tries again.
+//
+// The file is opened like Open would: WithMmap maps it into memory, and the
+// Eager options do their work before the first lookup reads it. If they
+// fail, the file is closed, and that lookup and every later one return their
+// error, as Open would have failed.
+func LazyOpen(name string, opts ...Option) *Cdb {
+	c := newCdb(opts)
+	l := &lazyFile{name: name, c: c}
+	c.setReader(l)
+	c.closer = l
+	c.lazy = l
+	return c
+}
+
+// lazyFile is an io.ReaderAt that opens the file on the first read.
+type lazyFile struct {
+	name string
+	// c is the Cdb reading the file, whose options apply when it is opened.
+	c *Cdb
+
+	mu sync.Mutex
+	// f is the open file, set under mu, so reads load it without taking mu
+	// once the file is open.
+	f atomic.Pointer[lazyOpened]
+	// err is the error the Eager options failed with.
+	err    error
+	closed bool
+}
+
+// lazyOpened holds the file a lazyFile opened.
+type lazyOpened struct {
+	statFile
+}
+
+// file returns the open file, opening it if this is the first successful
+// call.
+//
+// Threadsafe.
+func (l *lazyFile) file() (*lazyOpened, error) {
+	if f := l.f.Load(); f != nil {
+		return f, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, os.ErrClosed
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	if f := l.f.Load(); f != nil {
+		return f, nil
+	}
+	f, err := openFile(l.name, l.c.access)
+	if err != nil {
+		return nil, err
+	}
+	file, err := l.c.mapped(f)
+	if err != nil {
+		return nil, err
+	}
+	// The Eager work reads the file through l, and finds it without taking
+	// mu.
+	opened := &lazyOpened{file}
+	l.f.Store(opened)
+	if err := l.c.runEager(); err != nil {
+		l.f.Store(nil)
+		file.Close()
+		l.err = err
+		return nil, err
+	}
+	return opened, nil
+}
+
+func (l *lazyFile) ReadAt(p []byte, off int64) (int, error) {
+	f, err := l.file()
+	if err != nil {
+		return 0, err
+	}
+	return f.ReadAt(p, off)
+}
+
+func (l *lazyFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	f := l.f.Swap(nil)
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+func (l *lazyFile) Stat() (os.FileInfo, error) {
+	f, err := l.file()
+	if err != nil {
+		return nil, err
+	}
+	return f.Stat()
+}
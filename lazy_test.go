@@ -0,0 +1,78 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLazyOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "test.cdb")
+
+	db := LazyOpen(name)
+	defer db.Close()
+	if _, err := db.Bytes([]byte("one")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error before the file appears, got: %v", err)
+	}
+
+	if err := ioutil.WriteFile(name, newDBBytes(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := db.Bytes([]byte("one"))
+	if err != nil {
+		t.Fatalf("Bytes error: %v", err)
+	}
+	if !bytes.Equal(b, []byte("1")) {
+		t.Errorf("b: expected 1, got: %s", b)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if _, err := db.Bytes([]byte("one")); err == nil {
+		t.Errorf("expected an error after Close")
+	}
+}
+
+func TestLazyOpenEager(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.cdb")
+	// Point the first hash table past the end of the file.
+	b := newDBBytes(records)
+	putNum(b[4:], 1000)
+	if err := os.WriteFile(name, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db := LazyOpen(name, EagerVerify())
+	defer db.Close()
+	if _, err := db.Bytes([]byte("one")); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt from EagerVerify, got: %v", err)
+	}
+
+	// Like a failed Open, the Cdb stays failed, since it has read the
+	// damaged file.
+	if err := os.WriteFile(name, newDBBytes(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Bytes([]byte("one")); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt again, got: %v", err)
+	}
+
+	// Options the Eager work uses apply to the Cdb itself.
+	lazy := LazyOpen(name, WithPreloadedHeader(), EagerVerify())
+	defer lazy.Close()
+	checkRecords(t, lazy, records)
+	if h := lazy.r.(*headerCache); atomic.LoadUint32(&h.loaded) != 1 {
+		t.Error("expected the header to be preloaded")
+	}
+}
@@ -0,0 +1,136 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrInvalidToken is returned by ListKeys when given a token it didn't
+// return.
+var ErrInvalidToken = errors.New("invalid continuation token")
+
+// errStopScan stops a record scan early.
+var errStopScan = errors.New("stop scan")
+
+// ListKeys returns a page of up to limit distinct keys, in the order they were
+// first written, along with a token for the next page. Pass a nil token to
+// get the first page, and the returned token to get the following ones. The
+// returned token is nil once there are no keys left.
+//
+// Tokens are opaque, and only valid for the database that returned them.
+//
+// Threadsafe.
+func (c *Cdb) ListKeys(token []byte, limit int) (keys [][]byte, next []byte, err error) {
+	lay, err := c.layout()
+	if err != nil {
+		return nil, nil, err
+	}
+	start := lay.headerSize()
+	if token != nil {
+		// Tokens are 4 bytes, or 8 if the position doesn't fit in 4.
+		switch len(token) {
+		case 4:
+			start = uint64(binary.LittleEndian.Uint32(token))
+		case 8:
+			start = binary.LittleEndian.Uint64(token)
+		default:
+			return nil, nil, ErrInvalidToken
+		}
+		if err := c.checkToken(lay, start); err != nil {
+			return nil, nil, err
+		}
+	}
+	if limit <= 0 {
+		return nil, token, nil
+	}
+	var nextPos uint64
+	err = c.scanRecords(start, func(pos uint64, klen, dlen uint32) error {
+		if len(keys) == limit {
+			nextPos = pos
+			return errStopScan
+		}
+		key := make([]byte, klen)
+		if err := readFull(c.r, key, int64(pos)+8); err != nil {
+			return err
+		}
+		// Only list a key at its first record.
+		first, err := c.isFirstRecord(key, pos)
+		if err != nil {
+			return err
+		}
+		if first {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err == errStopScan {
+		if nextPos > 1<<32-1 {
+			next = make([]byte, 8)
+			binary.LittleEndian.PutUint64(next, nextPos)
+		} else {
+			next = make([]byte, 4)
+			binary.LittleEndian.PutUint32(next, uint32(nextPos))
+		}
+		return keys, next, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys, nil, nil
+}
+
+// checkToken returns ErrInvalidToken unless start is the position of a
+// record, so a forged token can't make ListKeys read lengths from the middle
+// of a record. The record has to fit in the data section, and be found by a
+// lookup of its key.
+func (c *Cdb) checkToken(lay layout, start uint64) error {
+	buf := make([]byte, 16)
+	end, _, err := lay.readEntry(c.r, buf, 0)
+	if err != nil {
+		return err
+	}
+	if start < lay.headerSize() || start >= end || end-start < 8 {
+		return ErrInvalidToken
+	}
+	klen, dlen, err := readNums(c.r, buf, int64(start))
+	if err != nil {
+		return err
+	}
+	if 8+uint64(klen)+uint64(dlen) > end-start {
+		return ErrInvalidToken
+	}
+	key := make([]byte, klen)
+	if err := readFull(c.r, key, int64(start)+8); err != nil {
+		return err
+	}
+	iter := c.getIter(key)
+	defer putIter(iter)
+	for {
+		err := iter.next()
+		if err == ErrNotFound {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		if iter.recordPos() == start {
+			return nil
+		}
+	}
+}
+
+// isFirstRecord returns true if the record at pos, with the given key, is the
+// key's first record, which is the first one a lookup finds.
+func (c *Cdb) isFirstRecord(key []byte, pos uint64) (bool, error) {
+	iter := c.getIter(key)
+	err := iter.next()
+	first := iter.recordPos()
+	putIter(iter)
+	if err == ErrNotFound {
+		return false, corruptf(pos, "record isn't in the hash tables")
+	}
+	if err != nil {
+		return false, err
+	}
+	return first == pos, nil
+}
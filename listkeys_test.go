@@ -0,0 +1,65 @@
+package cdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListKeys(t *testing.T) {
+	db := newDB(records)
+	var pages [][]string
+	var token []byte
+	for {
+		keys, next, err := db.ListKeys(token, 2)
+		if err != nil {
+			t.Fatalf("ListKeys error: %v", err)
+		}
+		var page []string
+		for _, key := range keys {
+			page = append(page, string(key))
+		}
+		pages = append(pages, page)
+		if next == nil {
+			break
+		}
+		token = next
+	}
+	expected := [][]string{{"one", "two"}, {"three"}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("expected pages %v, got: %v", expected, pages)
+	}
+
+	if _, _, err := db.ListKeys([]byte{1, 2, 3}, 2); err != ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken, got: %v", err)
+	}
+	// A token in the middle of a record is rejected before any lengths are
+	// read from there.
+	for _, pos := range []uint32{2048 + 1, 2048 + 8} {
+		token := make([]byte, 4)
+		putNum(token, pos)
+		if _, _, err := db.ListKeys(token, 2); err != ErrInvalidToken {
+			t.Errorf("expected ErrInvalidToken for %d, got: %v", pos, err)
+		}
+	}
+
+	// Every record is a valid place to continue from, including values after
+	// a key's first.
+	var all []string
+	token = nil
+	for {
+		keys, next, err := db.ListKeys(token, 1)
+		if err != nil {
+			t.Fatalf("ListKeys error: %v", err)
+		}
+		for _, key := range keys {
+			all = append(all, string(key))
+		}
+		if next == nil {
+			break
+		}
+		token = next
+	}
+	if expected := []string{"one", "two", "three"}; !reflect.DeepEqual(all, expected) {
+		t.Errorf("expected keys %v, got: %v", expected, all)
+	}
+}
@@ -0,0 +1,25 @@
+package cdb
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// madviseHugePages asks for data to be backed by transparent huge pages.
+func madviseHugePages(data []byte) error {
+	err := syscall.Madvise(data, syscall.MADV_HUGEPAGE)
+	if err == syscall.EINVAL {
+		// The kernel was built without transparent huge pages.
+		return errors.ErrUnsupported
+	}
+	return os.NewSyscallError("madvise", err)
+}
+
+// mlock locks data into memory until it is unmapped.
+func mlock(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return os.NewSyscallError("mlock", syscall.Mlock(data))
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package cdb
+
+import "errors"
+
+// madviseHugePages is unsupported where there are no transparent huge pages.
+func madviseHugePages(data []byte) error {
+	return errors.ErrUnsupported
+}
+
+// mlock is unsupported outside Linux.
+func mlock(data []byte) error {
+	return errors.ErrUnsupported
+}
@@ -4,74 +4,221 @@ import (
 	"bufio"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
+	"time"
 )
 
-var BadFormatError = errors.New("bad format")
-
 // Make reads cdb-formatted records from r and writes a cdb-format database
-// to w.  See the documentation for Dump for details on the input record format. 
-func Make(w io.WriteSeeker, r io.Reader) (err error) {
+// to w.  See the documentation for Dump for details on the input record format.
+//
+// The input may be compressed with gzip, or with any format registered with
+// RegisterDecompressor. Compression is detected automatically. TSV input, a
+// key and a value per line separated by a tab, is read by Writer.PutTSV.
+func Make(w io.WriteSeeker, r io.Reader) error {
+	rb, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	return makeFrom(w, &recReader{rb}, textFormat, nil)
+}
+
+// PutRecords reads records in the format Make reads from r and adds them to
+// the database, so text input can be built with Writer options or with
+// CreateAtomic. Like Make, the input may be compressed, and values are
+// streamed to the output rather than held in memory.
+func (w *Writer) PutRecords(r io.Reader) (err error) {
+	rb, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	defer func() { // The record reader panics on bad input.
+		if e := recover(); e != nil {
+			err = e.(error)
+		}
+	}()
+	rr := &recReader{rb}
+	for {
+		klen, dlen, ok := textFormat.header(rr)
+		if !ok {
+			return nil
+		}
+		key := make([]byte, klen)
+		if _, err := io.ReadFull(rr, key); err != nil {
+			return unexpectedEOF(err)
+		}
+		textFormat.separator(rr)
+		if err := w.PutReader(key, rr, int64(dlen)); err != nil {
+			return err
+		}
+		textFormat.trailer(rr)
+	}
+}
+
+// MakeWriterAt is Make for outputs that can only be written at offsets, like
+// sparse files or object store uploads, rather than seeked. The header is
+// written as zeros first, so the output has no holes, and filled in at the
+// end.
+func MakeWriterAt(w io.WriterAt, r io.Reader) error {
+	if _, err := w.WriteAt(make([]byte, headerSize), 0); err != nil {
+		return err
+	}
+	return Make(io.NewOffsetWriter(w, 0), r)
+}
+
+// recordFormat describes how records are framed in the input to makeFrom.
+// Each function panics with an error if the input is badly formatted.
+type recordFormat struct {
+	// header reads everything before the key of the next record and returns
+	// the key and data lengths. It returns false at the end of the records.
+	header func(rr *recReader) (klen, dlen uint32, ok bool)
+	// separator reads everything between the key and the data.
+	separator func(rr *recReader)
+	// trailer reads everything after the data.
+	trailer func(rr *recReader)
+}
+
+// textFormat is the cdbmake format: "+klen,dlen:key->data\n" for each record,
+// followed by a blank line.
+var textFormat = recordFormat{
+	header: func(rr *recReader) (uint32, uint32, bool) {
+		c := rr.readByte()
+		if c == '\n' { // end of records
+			return 0, 0, false
+		}
+		if c != '+' {
+			panic(ErrBadFormat)
+		}
+		return rr.readNum(','), rr.readNum(':'), true
+	},
+	separator: func(rr *recReader) {
+		rr.eatByte('-')
+		rr.eatByte('>')
+	},
+	trailer: func(rr *recReader) {
+		rr.eatByte('\n')
+	},
+}
+
+// makeHooks change how makeFrom builds a database.
+type makeHooks struct {
+	// resume holds the records already in the output, if the build is being
+	// resumed.
+	resume *resumeState
+	// metrics collects the stats of the build, if they are wanted.
+	metrics *buildMetrics
+}
+
+// makeFrom reads records framed in the given format from rr and writes a
+// cdb-format database to w. Hooks may be nil.
+func makeFrom(w io.WriteSeeker, rr *recReader, format recordFormat, hooks *makeHooks) (err error) {
 	defer func() { // Centralize error handling.
 		if e := recover(); e != nil {
 			err = e.(error)
 		}
 	}()
 
-	if _, err = w.Seek(int64(headerSize), 0); err != nil {
+	b := newBuilder(w, layout{})
+	if hooks != nil {
+		b.metrics = hooks.metrics
+	}
+	hash := cdbHash()
+	if hooks != nil && hooks.resume != nil {
+		b.pos = hooks.resume.end
+		hooks.resume.skip(rr, format, hash, b.htables)
+	}
+	if err = b.start(); err != nil {
 		return
 	}
 
 	buf := make([]byte, 8)
-	rb := bufio.NewReader(r)
-	wb := bufio.NewWriter(w)
-	hash := cdbHash()
-	hw := io.MultiWriter(hash, wb) // Computes hash when writing record key.
-	rr := &recReader{rb}
-	htables := make(map[uint32][]slot)
-	pos := headerSize
+	hw := io.MultiWriter(hash, b.wb) // Computes hash when writing record key.
 	// Read all records and write to output.
 	for {
-		// Record format is "+klen,dlen:key->data\n"
-		c := rr.readByte()
-		if c == '\n' { // end of records
+		klen, dlen, ok := format.header(rr)
+		if !ok {
 			break
 		}
-		if c != '+' {
-			return BadFormatError
-		}
-		klen, dlen := rr.readNum(','), rr.readNum(':')
-		writeNums(wb, klen, dlen, buf)
+		writeNums(b.wb, klen, dlen, buf)
 		hash.Reset()
 		rr.copyn(hw, klen)
-		rr.eatByte('-')
-		rr.eatByte('>')
-		rr.copyn(wb, dlen)
-		rr.eatByte('\n')
-		h := hash.Sum32()
-		tableNum := h % 256
-		htables[tableNum] = append(htables[tableNum], slot{h, pos})
-		pos += 8 + klen + dlen
+		format.separator(rr)
+		rr.copyn(b.wb, dlen)
+		format.trailer(rr)
+		if err = b.add(hash.Sum32(), klen, dlen); err != nil {
+			return
+		}
+	}
+	return b.finish(nil)
+}
+
+// builder writes the records of a database as they come, remembering a slot
+// for each one, then writes the hash tables and header at the end.
+type builder struct {
+	w   io.WriteSeeker
+	wb  *bufio.Writer
+	lay layout
+	// htables holds the slots of each hash table.
+	htables map[uint32][]slot
+	// pos is the file position of the next record.
+	pos uint64
+	// metrics collects the stats of the build, if they are wanted.
+	metrics *buildMetrics
+}
+
+func newBuilder(w io.WriteSeeker, lay layout) *builder {
+	return &builder{
+		w:       w,
+		wb:      bufio.NewWriterSize(w, 64<<10),
+		lay:     lay,
+		htables: make(map[uint32][]slot),
+		pos:     lay.headerSize(),
+	}
+}
+
+// start seeks to where the next record goes. The header is written last.
+func (b *builder) start() error {
+	_, err := b.w.Seek(int64(b.pos), io.SeekStart)
+	return err
+}
+
+// add records the slot of a record with hash h and the given key and data
+// lengths, which has just been written to b.wb.
+func (b *builder) add(h uint32, klen, dlen uint32) error {
+	tableNum := h % 256
+	b.htables[tableNum] = append(b.htables[tableNum], slot{h, b.pos})
+	b.pos += 8 + uint64(klen) + uint64(dlen)
+	if b.metrics != nil {
+		b.metrics.record(8 + uint64(klen) + uint64(dlen))
 	}
+	if b.pos > b.lay.maxPos() {
+		return ErrTooLarge
+	}
+	return nil
+}
 
-	// Write hash tables and header.
+// finish writes the hash tables, the extensions and the header.
+func (b *builder) finish(exts []extension) (err error) {
+	lay, wb, pos := b.lay, b.wb, b.pos
+	buf := make([]byte, 16)
+	tableStart := time.Now()
 
 	// Create and reuse a single hash table.
 	maxSlots := 0
-	for _, slots := range htables {
+	for _, slots := range b.htables {
 		if len(slots) > maxSlots {
 			maxSlots = len(slots)
 		}
 	}
 	slotTable := make([]slot, maxSlots*2)
 
-	header := make([]byte, headerSize)
+	header := make([]byte, lay.headerSize())
 	// Write hash tables.
-	for i := uint32(0); i < 256; i++ {
-		slots := htables[i]
+	for i := uint64(0); i < 256; i++ {
+		slots := b.htables[uint32(i)]
 		if slots == nil {
-			putNum(header[i*8:], pos)
+			lay.putEntry(header[i*lay.entrySize():], pos, 0)
 			continue
 		}
 
@@ -94,26 +241,57 @@ func Make(w io.WriteSeeker, r io.Reader) (err error) {
 			hashSlotTable[slotPos] = slot
 		}
 
-		if err = writeSlots(wb, hashSlotTable, buf); err != nil {
+		if err = writeSlots(wb, lay, hashSlotTable, buf); err != nil {
 			return
 		}
 
-		putNum(header[i*8:], pos)
-		putNum(header[i*8+4:], nslots)
-		pos += 8 * nslots
+		lay.putEntry(header[i*lay.entrySize():], pos, uint64(nslots))
+		pos += lay.entrySize() * uint64(nslots)
+		if pos > lay.maxPos() {
+			return ErrTooLarge
+		}
+	}
+
+	tablesEnd := pos
+	if err = writeExtensions(wb, exts); err != nil {
+		return
 	}
 
 	if err = wb.Flush(); err != nil {
 		return
 	}
 
-	if _, err = w.Seek(0, 0); err != nil {
+	if _, err = b.w.Seek(0, 0); err != nil {
 		return
 	}
 
-	_, err = w.Write(header)
+	if _, err = b.w.Write(header); err != nil {
+		return
+	}
+	if m := b.metrics; m != nil {
+		m.stats.Done = true
+		m.stats.TableTime = time.Since(tableStart)
+		m.stats.HeaderSize = lay.headerSize()
+		m.stats.DataSize = b.pos - lay.headerSize()
+		m.stats.TableSize = tablesEnd - b.pos
+		m.stats.ExtensionSize = extensionsSize(exts)
+		m.report()
+	}
+	return nil
+}
 
-	return
+// flush writes out everything buffered in wb, and syncs w to stable storage
+// if it can be.
+func flush(wb *bufio.Writer, w io.Writer) error {
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+	if s, ok := w.(interface {
+		Sync() error
+	}); ok {
+		return s.Sync()
+	}
+	return nil
 }
 
 type recReader struct {
@@ -143,6 +321,9 @@ func (rr *recReader) readNum(delim byte) uint32 {
 
 	s = s[:len(s)-1] // Strip delim
 	n, err := strconv.ParseUint(s, 10, 32)
+	if errors.Is(err, strconv.ErrRange) {
+		panic(fmt.Errorf("%w: length %s", ErrValueTooLarge, s))
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -169,14 +350,14 @@ func writeNums(w io.Writer, x, y uint32, buf []byte) {
 }
 
 type slot struct {
-	h, pos uint32
+	h   uint32
+	pos uint64
 }
 
-func writeSlots(w io.Writer, slots []slot, buf []byte) (err error) {
+func writeSlots(w io.Writer, lay layout, slots []slot, buf []byte) (err error) {
 	for _, np := range slots {
-		putNum(buf, np.h)
-		putNum(buf[4:], np.pos)
-		if _, err = w.Write(buf[:8]); err != nil {
+		lay.putEntry(buf, uint64(np.h), np.pos)
+		if _, err = w.Write(buf[:lay.entrySize()]); err != nil {
 			return
 		}
 	}
@@ -0,0 +1,40 @@
+package cdb
+
+import (
+	"io"
+	"sort"
+)
+
+// WriteMap writes a database of the records in m to ws, in key order so the
+// same map always gives the same file.
+func WriteMap(ws io.WriteSeeker, m map[string][]byte) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	w := NewWriter(ws)
+	for _, key := range keys {
+		if err := w.Put([]byte(key), m[key]); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// ToMap returns all of the records in the database, with the values of each
+// key in order.
+//
+// Threadsafe.
+func (c *Cdb) ToMap() (map[string][][]byte, error) {
+	m := make(map[string][][]byte)
+	err := c.ForEachBytes(func(key, val []byte) error {
+		m[string(key)] = append(m[string(key)], append([]byte(nil), val...))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
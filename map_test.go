@@ -0,0 +1,33 @@
+package cdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	m, err := newDB(records).ToMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][][]byte{
+		"one":   {[]byte("1")},
+		"two":   {[]byte("2"), []byte("22")},
+		"three": {[]byte("3"), []byte("33"), []byte("333")},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %q, got: %q", expected, m)
+	}
+
+	var bufs [2]memBuffer
+	for i := range bufs {
+		if err := WriteMap(&bufs[i], map[string][]byte{"b": []byte("2"), "a": []byte("1"), "c": nil}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(bufs[0].b, bufs[1].b) {
+		t.Errorf("expected WriteMap to write the same file every time")
+	}
+	checkRecords(t, New(bytes.NewReader(bufs[0].b)), []rec{{"a", []string{"1"}}, {"b", []string{"2"}}, {"c", []string{""}}})
+}
@@ -0,0 +1,101 @@
+package cdb
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// BuildStats reports the progress of a database build to the function given
+// to BuildMetrics or MakeWithMetrics.
+type BuildStats struct {
+	// Records and Bytes count the records written so far, and their bytes,
+	// headers included.
+	Records int64
+	Bytes   int64
+	// Duplicates counts the records rejected, skipped or dropped by the
+	// Writer's DuplicatePolicy. Dropped records are counted by Close.
+	Duplicates int64
+	// Elapsed is the time since the build started.
+	Elapsed time.Duration
+
+	// The rest are only set in the final report, once the database is
+	// complete.
+	Done bool
+	// TableTime is the time taken to build and write the hash tables.
+	TableTime time.Duration
+	// HeaderSize, DataSize, TableSize and ExtensionSize break the size of the
+	// database down into its sections.
+	HeaderSize    uint64
+	DataSize      uint64
+	TableSize     uint64
+	ExtensionSize uint64
+}
+
+// RecordsPerSecond returns the average rate records were written at.
+func (s BuildStats) RecordsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Records) / s.Elapsed.Seconds()
+}
+
+// BytesPerSecond returns the average rate record bytes were written at.
+func (s BuildStats) BytesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// BuildMetrics returns a WriterOption that calls fn with the stats of the
+// build at most every interval while records are written, and once more with
+// Done set when Close completes the database. An interval of 0 only reports
+// at the end. Fn is called by Put and Close, so it should be quick.
+func BuildMetrics(interval time.Duration, fn func(BuildStats)) WriterOption {
+	return func(w *Writer) {
+		w.metrics = newBuildMetrics(interval, fn)
+	}
+}
+
+// MakeWithMetrics is Make, reporting the progress of the build to fn like
+// BuildMetrics.
+func MakeWithMetrics(w io.WriteSeeker, r io.Reader, interval time.Duration, fn func(BuildStats)) error {
+	rb, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	return makeFrom(w, &recReader{rb}, textFormat, &makeHooks{metrics: newBuildMetrics(interval, fn)})
+}
+
+// buildMetrics collects the stats of a build.
+type buildMetrics struct {
+	fn       func(BuildStats)
+	interval time.Duration
+	start    time.Time
+	// last is when the stats were last reported.
+	last  time.Time
+	stats BuildStats
+}
+
+func newBuildMetrics(interval time.Duration, fn func(BuildStats)) *buildMetrics {
+	now := time.Now()
+	return &buildMetrics{fn: fn, interval: interval, start: now, last: now}
+}
+
+// record counts a record of n bytes, and reports the stats if they are due.
+func (m *buildMetrics) record(n uint64) {
+	m.stats.Records++
+	m.stats.Bytes += int64(n)
+	if m.interval > 0 {
+		if now := time.Now(); now.Sub(m.last) >= m.interval {
+			m.last = now
+			m.report()
+		}
+	}
+}
+
+func (m *buildMetrics) report() {
+	m.stats.Elapsed = time.Since(m.start)
+	m.fn(m.stats)
+}
@@ -0,0 +1,92 @@
+package cdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMetrics(t *testing.T) {
+	for _, tc := range []struct {
+		policy  DuplicatePolicy
+		records int64
+		dups    int64
+	}{
+		{AllowDuplicates, 6, 0},
+		{KeepFirst, 3, 3},
+		{KeepLast, 3, 3},
+	} {
+		tmp, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		var reports []BuildStats
+		w := NewWriter(tmp, Duplicates(tc.policy), Checksums(),
+			BuildMetrics(time.Nanosecond, func(s BuildStats) { reports = append(reports, s) }))
+		for _, rec := range records {
+			for _, val := range rec.values {
+				if err := w.Put([]byte(rec.key), []byte(val)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(reports) < 2 {
+			t.Fatalf("policy %d: expected progress and final reports, got: %+v", tc.policy, reports)
+		}
+		for _, s := range reports[:len(reports)-1] {
+			if s.Done {
+				t.Errorf("policy %d: progress report marked done: %+v", tc.policy, s)
+			}
+		}
+		s := reports[len(reports)-1]
+		if !s.Done || s.Records != tc.records || s.Duplicates != tc.dups {
+			t.Errorf("policy %d: expected %d records and %d duplicates, got: %+v", tc.policy, tc.records, tc.dups, s)
+		}
+		fi, err := tmp.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total := s.HeaderSize + s.DataSize + s.TableSize + s.ExtensionSize; total != uint64(fi.Size()) {
+			t.Errorf("policy %d: sizes add up to %d, file is %d bytes: %+v", tc.policy, total, fi.Size(), s)
+		}
+		if s.ExtensionSize == 0 || s.TableSize != 16*uint64(tc.records) {
+			t.Errorf("policy %d: unexpected size breakdown: %+v", tc.policy, s)
+		}
+		if s.RecordsPerSecond() <= 0 || s.BytesPerSecond() <= 0 {
+			t.Errorf("policy %d: expected positive rates: %+v", tc.policy, s)
+		}
+	}
+}
+
+func TestMakeWithMetrics(t *testing.T) {
+	var final BuildStats
+	buf := &memBuffer{}
+	in := "+3,1:one->1\n+3,2:two->22\n\n"
+	err := MakeWithMetrics(buf, strings.NewReader(in), 0, func(s BuildStats) { final = s })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !final.Done || final.Records != 2 || final.Bytes != 8+4+8+5 {
+		t.Errorf("unexpected stats: %+v", final)
+	}
+	if final.DataSize != uint64(final.Bytes) || final.HeaderSize+final.DataSize+final.TableSize != uint64(len(buf.b)) {
+		t.Errorf("unexpected size breakdown for %d bytes: %+v", len(buf.b), final)
+	}
+
+	var plain memBuffer
+	if err := Make(&plain, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain.b, buf.b) {
+		t.Error("expected the same database as Make")
+	}
+}
@@ -0,0 +1,163 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+// OpenMmap is like Open, but maps the file into memory and serves reads from
+// the mapping, saving a system call per read. Close unmaps the file. If the
+// file is truncated while it is mapped, reads return ErrFileTruncated.
+//
+// On platforms without mmap support it reads the file like Open.
+//
+// It is the same as Open with the WithMmap option.
+func OpenMmap(name string, opts ...Option) (*Cdb, error) {
+	c := newCdb(opts)
+	c.mmap = true
+	return c.open(name)
+}
+
+// ErrFileTruncated is returned by reads from a memory mapped database whose
+// file was truncated while it was mapped.
+var ErrFileTruncated = errors.New("file truncated while mapped")
+
+// mmapFile is an io.ReaderAt serving reads from a read-only memory mapping of
+// a file.
+//
+// Touching a page of the mapping past the end of the file raises SIGBUS, or
+// an in-page error on Windows, which normally kills the process. That happens if the file is truncated
+// while it is mapped, for example by a deploy overwriting it in place instead
+// of renaming a new file over it. Reads turn the fault into a panic with
+// debug.SetPanicOnFault and recover it, so they return ErrFileTruncated
+// instead.
+//
+// Reads hold mu for reading, so Close can't unmap the data under them. Reads
+// after Close return ErrClosed.
+type mmapFile struct {
+	f      *os.File
+	mu     sync.RWMutex
+	data   []byte
+	closed bool
+}
+
+// mapFile maps all of f. The mapping stays valid until Close, which also
+// closes f.
+func mapFile(f *os.File) (*mmapFile, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size == 0 {
+		return &mmapFile{f: f}, nil
+	}
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("cdb: %s is too large to map", f.Name())
+	}
+	data, err := mmap(f, int(size))
+	if err != nil {
+		return nil, err
+	}
+	return &mmapFile{f: f, data: data}, nil
+}
+
+// adviseMapping applies the WithHugePages and WithLockedTables options to m.
+// Platforms that don't support them skip them.
+func (c *Cdb) adviseMapping(m *mmapFile) error {
+	if len(m.data) == 0 {
+		return nil
+	}
+	if c.hugePages {
+		if err := madviseHugePages(m.data); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	if c.lockTables {
+		lay, err := detectLayout(m)
+		if err != nil {
+			return err
+		}
+		if lay.headerSize() > uint64(len(m.data)) {
+			return &CorruptError{Offset: uint64(len(m.data)), Detail: "file shorter than its header"}
+		}
+		// The hash tables run from just past the records to tablesEnd.
+		header := m.data[:lay.headerSize()]
+		start, end := uint64(len(m.data)), uint64(0)
+		for i := uint64(0); i < 256; i++ {
+			pos, nslots := lay.entry(header[i*lay.entrySize():])
+			if pos < start {
+				start = pos
+			}
+			if pos+nslots*lay.entrySize() > end {
+				end = pos + nslots*lay.entrySize()
+			}
+		}
+		if start > end || end > uint64(len(m.data)) {
+			return &CorruptError{Offset: end, Detail: "hash tables past the end of the file"}
+		}
+		if err := mlock(header); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+		if err := mlock(m.data[start:end]); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mmapFile) ReadAt(p []byte, off int64) (n int, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return 0, ErrClosed
+	}
+	if off < 0 {
+		return 0, errors.New("cdb: negative offset")
+	}
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if e := recover(); e != nil {
+			// Memory faults are runtime errors with the faulting address.
+			if _, ok := e.(interface{ Addr() uintptr }); ok {
+				n, err = 0, ErrFileTruncated
+				return
+			}
+			panic(e)
+		}
+	}()
+	n = copy(p, m.data[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (m *mmapFile) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.closed = true
+	var err error
+	if m.data != nil {
+		err = munmap(m.data)
+		m.data = nil
+	}
+	if cerr := m.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (m *mmapFile) Stat() (os.FileInfo, error) {
+	return m.f.Stat()
+}
@@ -0,0 +1,54 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestOpenMmap(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(newDBBytes(records)); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+
+	db, err := OpenMmap(tmp.Name())
+	if err != nil {
+		t.Fatalf("OpenMmap error: %v", err)
+	}
+	checkRecords(t, db, records)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if _, err := OpenMmap(tmp.Name() + ".missing"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got: %v", err)
+	}
+}
+
+func TestOpenMmapAdvice(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(newDBBytes(records)); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+
+	// The options are ignored where they aren't supported, and the tables of
+	// a small database fit in the default RLIMIT_MEMLOCK.
+	db, err := OpenMmap(tmp.Name(), WithHugePages(), WithLockedTables())
+	if err != nil {
+		t.Fatalf("OpenMmap error: %v", err)
+	}
+	checkRecords(t, db, records)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build unix
+
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestMmapFileTruncated(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	// Make the database span several pages.
+	recs := []rec{{"big", []string{string(bytes.Repeat([]byte("x"), 3*os.Getpagesize()))}}}
+	if _, err := tmp.Write(newDBBytes(recs)); err != nil {
+		t.Fatal(err)
+	}
+	m, err := mapFile(tmp)
+	if err != nil {
+		t.Fatalf("mapFile error: %v", err)
+	}
+	defer m.Close()
+	db := New(m)
+	if _, err := db.Bytes([]byte("big")); err != nil {
+		t.Fatalf("Bytes error: %v", err)
+	}
+
+	if err := os.Truncate(tmp.Name(), int64(os.Getpagesize())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Bytes([]byte("big")); err != ErrFileTruncated {
+		t.Errorf("expected ErrFileTruncated, got: %v", err)
+	}
+}
+
+func TestMmapFileClose(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(newDBBytes(records)); err != nil {
+		t.Fatal(err)
+	}
+	m, err := mapFile(tmp)
+	if err != nil {
+		t.Fatalf("mapFile error: %v", err)
+	}
+
+	// Reads racing with Close either succeed or return ErrClosed.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 16)
+			for j := 0; j < 1000; j++ {
+				if _, err := m.ReadAt(buf, 0); err == ErrClosed {
+					return
+				} else if err != nil {
+					t.Errorf("ReadAt error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	wg.Wait()
+	if _, err := m.ReadAt(make([]byte, 16), 0); err != ErrClosed {
+		t.Errorf("expected ErrClosed after Close, got: %v", err)
+	}
+	if err := m.Close(); err != ErrClosed {
+		t.Errorf("expected ErrClosed from a second Close, got: %v", err)
+	}
+}
+
+func TestLockedTablesCorrupt(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	// Cut the file short of its hash tables.
+	b := newDBBytes(records)
+	if _, err := tmp.Write(b[:len(b)-8]); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	if _, err := OpenMmap(tmp.Name(), WithLockedTables()); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got: %v", err)
+	}
+}
+
+func TestLazyOpenMmap(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.cdb")
+	if err := os.WriteFile(name, newDBBytes(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db := LazyOpen(name, WithMmap())
+	defer db.Close()
+	checkRecords(t, db, records)
+	if f := db.closer.(*lazyFile).f.Load(); f == nil {
+		t.Fatal("expected the file to be open after a lookup")
+	} else if _, ok := f.statFile.(*mmapFile); !ok {
+		t.Errorf("expected WithMmap to map the file, got: %T", f.statFile)
+	}
+}
@@ -0,0 +1,67 @@
+package cdb
+
+import "sync/atomic"
+
+// negCache is a bounded cache of key hashes that have no records. Because a
+// lookup only compares keys whose hashes match, a miss that found no slot with
+// the key's hash is a miss for every key with that hash, so caching hashes
+// never hides a record.
+//
+// It is direct mapped: each hash has one entry it can be stored in, and storing
+// it evicts whatever was there.
+//
+// Threadsafe and lock-free.
+type negCache struct {
+	// entries hold a hash in the low 32 bits, with bit 32 set if the entry is
+	// in use.
+	entries []uint64
+}
+
+const negCacheValid = 1 << 32
+
+func newNegCache(size int) *negCache {
+	return &negCache{entries: make([]uint64, size)}
+}
+
+// index returns the entry for hash. The low bits of the hash pick the hash
+// table, so they are mixed with the high bits first.
+func (nc *negCache) index(hash uint32) int {
+	return int((hash * 0x9e3779b1) % uint32(len(nc.entries)))
+}
+
+func (nc *negCache) contains(hash uint32) bool {
+	return atomic.LoadUint64(&nc.entries[nc.index(hash)]) == negCacheValid|uint64(hash)
+}
+
+func (nc *negCache) add(hash uint32) {
+	atomic.StoreUint64(&nc.entries[nc.index(hash)], negCacheValid|uint64(hash))
+}
+
+func (nc *negCache) clear() {
+	for i := range nc.entries {
+		atomic.StoreUint64(&nc.entries[i], 0)
+	}
+}
+
+// NegativeCache returns an Option that remembers up to size keys that have no
+// values, so that repeated misses, which are common for uses like blocklists,
+// don't have to read the database. Only misses are cached, and the cache is
+// separate from any value caching.
+func NegativeCache(size int) Option {
+	return func(c *Cdb) {
+		if size > 0 {
+			c.negCache = newNegCache(size)
+		}
+	}
+}
+
+// InvalidateNegativeCache empties the negative cache. A database file never
+// changes, so this is only needed if the underlying reader now reads different
+// data.
+//
+// Threadsafe.
+func (c *Cdb) InvalidateNegativeCache() {
+	if c.negCache != nil {
+		c.negCache.clear()
+	}
+}
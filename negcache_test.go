@@ -0,0 +1,43 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type countingReaderAt struct {
+	r     io.ReaderAt
+	reads int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+func TestNegativeCache(t *testing.T) {
+	r := &countingReaderAt{r: bytes.NewReader(newDBBytes(records))}
+	db := New(r, NegativeCache(16))
+	if _, err := db.Bytes([]byte("missing")); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got: %v", err)
+	}
+	reads := r.reads
+	if ok, err := db.Exists([]byte("missing")); ok || err != nil {
+		t.Fatalf("expected a miss, got: %v, %v", ok, err)
+	}
+	if r.reads != reads {
+		t.Errorf("expected a cached miss to do no reads, got: %v", r.reads-reads)
+	}
+	// Hits aren't cached.
+	if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected 1, got: %s, %v", b, err)
+	}
+
+	db.InvalidateNegativeCache()
+	reads = r.reads
+	db.Exists([]byte("missing"))
+	if r.reads == reads {
+		t.Errorf("expected a lookup after invalidation to read")
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package cdb
+
+const raceEnabled = false
@@ -0,0 +1,26 @@
+package cdb
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// oPath is O_PATH, which the syscall package doesn't define.
+const oPath = 0x200000
+
+// reopenPath returns a readable file for f, which was opened with O_PATH if it
+// can only name the file rather than read it. It reopens the file through
+// /proc, and closes f if it does.
+func reopenPath(f *os.File) (*os.File, error) {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), syscall.F_GETFL, 0)
+	if errno != 0 {
+		f.Close()
+		return nil, os.NewSyscallError("fcntl", errno)
+	}
+	if flags&oPath == 0 {
+		return f, nil
+	}
+	defer f.Close()
+	return os.Open(fmt.Sprintf("/proc/self/fd/%d", f.Fd()))
+}
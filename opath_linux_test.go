@@ -0,0 +1,30 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestNewFromFdOPath(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(newDBBytes(records))
+	tmp.Close()
+
+	fd, err := syscall.Open(tmp.Name(), oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fd)
+	db, err := NewFromFd(uintptr(fd))
+	if err != nil {
+		t.Fatalf("NewFromFd error: %v", err)
+	}
+	defer db.Close()
+	checkRecords(t, db, records)
+}
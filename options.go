@@ -0,0 +1,182 @@
+package cdb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Option configures a Cdb. Options are passed to New, Open and LazyOpen.
+type Option func(*Cdb)
+
+// ValueTransform returns an Option that passes every value through fn before
+// it is returned by a lookup or iteration, for example to decrypt or
+// decompress it. Fn is given the record's key and raw value, and the value it
+// returns is used in place of the raw one. If fn returns an error, the lookup
+// or iteration returns that error.
+//
+// Fn must be threadsafe. Values passed to fn may be reused after it returns,
+// so it shouldn't keep a reference to them.
+func ValueTransform(fn func(key, val []byte) ([]byte, error)) Option {
+	return func(c *Cdb) {
+		c.transform = fn
+		c.readerTransform = nil
+	}
+}
+
+// ValueReaderTransform is like ValueTransform, but streams values through fn
+// instead of reading them into memory first. Fn is given the record's key
+// and a reader for its raw value, and returns a reader for the value to use
+// in its place, so lookups returning readers can read parts of huge values
+// without transforming all of them. Lookups returning byte slices read the
+// whole of the returned reader.
+//
+// Fn must be threadsafe. The key passed to fn may be reused after it returns,
+// so it shouldn't keep a reference to it.
+func ValueReaderTransform(fn func(key []byte, val *io.SectionReader) (*io.SectionReader, error)) Option {
+	return func(c *Cdb) {
+		c.readerTransform = fn
+		c.transform = func(key, val []byte) ([]byte, error) {
+			r, err := fn(key, io.NewSectionReader(bytes.NewReader(val), 0, int64(len(val))))
+			if err != nil {
+				return nil, err
+			}
+			out := make([]byte, r.Size())
+			if _, err := io.ReadFull(r, out); err != nil {
+				return nil, err
+			}
+			return out, nil
+		}
+	}
+}
+
+// WithPreloadedHeader returns an Option that keeps the header of the database
+// in memory, so a lookup only reads the database for its hash slots and
+// record, saving a read of the header per lookup. Open, OpenMmap and
+// NewFromFd read the header up front, like EagerHeader, and other
+// constructors read it on the first lookup. LazyOpen also checks it, like
+// EagerHeader, when it opens the file.
+func WithPreloadedHeader() Option {
+	return func(c *Cdb) {
+		c.wrappers = append(c.wrappers, func(r io.ReaderAt) io.ReaderAt {
+			return &headerCache{r: r}
+		})
+		c.eager = append(c.eager, (*Cdb).loadHeader)
+	}
+}
+
+// WithMmap returns an Option that makes Open, NewFromFd and LazyOpen map the
+// file into memory, like OpenMmap. Other constructors ignore it.
+func WithMmap() Option {
+	return func(c *Cdb) {
+		c.mmap = true
+	}
+}
+
+// WithHugePages returns an Option that asks the kernel to back the mapping of
+// a database opened by OpenMmap or WithMmap with huge pages, reducing TLB
+// misses for lookups spread over a giant database. It is a hint, on Linux
+// with transparent huge pages, and is ignored elsewhere and without mmap.
+func WithHugePages() Option {
+	return func(c *Cdb) {
+		c.hugePages = true
+	}
+}
+
+// WithLockedTables returns an Option that locks the header and hash tables of
+// a database opened by OpenMmap or WithMmap into memory, so lookups never
+// wait for them to be paged in. Only the records are left for the kernel to
+// page in and out. Open fails if they can't be locked, usually because of
+// RLIMIT_MEMLOCK. It is supported on Linux, and ignored elsewhere and
+// without mmap.
+func WithLockedTables() Option {
+	return func(c *Cdb) {
+		c.lockTables = true
+	}
+}
+
+// WithMaxValueSize returns an Option that makes lookups and iterations fail
+// with ErrValueTooLarge for values stored with more than n bytes, instead of
+// reading them. It protects services from running out of memory on a
+// damaged or hostile database.
+func WithMaxValueSize(n int64) Option {
+	return func(c *Cdb) {
+		c.maxValueSize = n
+	}
+}
+
+// WithHash returns an Option that looks keys up with fn instead of the cdb
+// hash, for databases built with the Hash WriterOption. Fn must be
+// threadsafe, and must be the function the database was built with, or
+// lookups won't find anything.
+func WithHash(fn func(key []byte) uint32) Option {
+	return func(c *Cdb) {
+		c.hash = fn
+	}
+}
+
+// checksum returns the hash of key used by the database.
+func (c *Cdb) checksum(key []byte) uint32 {
+	if c.hash != nil {
+		return c.hash(key)
+	}
+	return checksum(key)
+}
+
+// checkValueSize returns ErrValueTooLarge if a value of dlen bytes is bigger
+// than the WithMaxValueSize limit.
+func (c *Cdb) checkValueSize(dlen uint32) error {
+	if c.maxValueSize > 0 && int64(dlen) > c.maxValueSize {
+		return fmt.Errorf("%w: %d bytes is over the limit of %d", ErrValueTooLarge, dlen, c.maxValueSize)
+	}
+	return nil
+}
+
+// headerCache is an io.ReaderAt that serves reads of the header from memory.
+type headerCache struct {
+	r io.ReaderAt
+	// loaded is set to 1, after header, once the header has been read.
+	loaded uint32
+	mu     sync.Mutex
+	header []byte
+}
+
+func (h *headerCache) ReadAt(p []byte, off int64) (int, error) {
+	header, err := h.load()
+	if err != nil {
+		return 0, err
+	}
+	if off >= int64(len(header)) {
+		return h.r.ReadAt(p, off)
+	}
+	n := copy(p, header[off:])
+	if n == len(p) {
+		return n, nil
+	}
+	m, err := h.r.ReadAt(p[n:], off+int64(n))
+	return n + m, err
+}
+
+// load returns the header, reading it if this is the first successful call.
+// Files shorter than the biggest header are kept whole.
+//
+// Threadsafe.
+func (h *headerCache) load() ([]byte, error) {
+	if atomic.LoadUint32(&h.loaded) == 1 {
+		return h.header, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.loaded == 0 {
+		header := make([]byte, 256*16)
+		n, err := h.r.ReadAt(header, 0)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		h.header = header[:n]
+		atomic.StoreUint32(&h.loaded, 1)
+	}
+	return h.header, nil
+}
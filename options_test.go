@@ -0,0 +1,188 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestValueTransform(t *testing.T) {
+	db := New(bytes.NewReader(newDBBytes(records)), ValueTransform(func(key, val []byte) ([]byte, error) {
+		return append(append(append([]byte{}, key...), '='), val...), nil
+	}))
+	b, err := db.Bytes([]byte("two"))
+	if err != nil {
+		t.Fatalf("Bytes error: %v", err)
+	}
+	if string(b) != "two=2" {
+		t.Errorf("Bytes: expected two=2, got: %s", b)
+	}
+	r, err := db.Reader([]byte("one"))
+	if err != nil {
+		t.Fatalf("Reader error: %v", err)
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != "one=1" {
+		t.Errorf("Reader: expected one=1, got: %s", b)
+	}
+	err = db.ForEachBytes(func(key, val []byte) error {
+		if !bytes.HasPrefix(val, append(key, '=')) {
+			t.Errorf("ForEachBytes: untransformed value %s for %s", val, key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.ForEachReader(func(keyReader, valReader *io.SectionReader) error {
+		key, _ := ioutil.ReadAll(keyReader)
+		val, _ := ioutil.ReadAll(valReader)
+		if !bytes.HasPrefix(val, append(key, '=')) {
+			t.Errorf("ForEachReader: untransformed value %s for %s", val, key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValueTransformError(t *testing.T) {
+	errTransform := io.ErrNoProgress
+	db := New(bytes.NewReader(newDBBytes(records)), ValueTransform(func(key, val []byte) ([]byte, error) {
+		return nil, errTransform
+	}))
+	if _, err := db.Bytes([]byte("one")); err != errTransform {
+		t.Errorf("expected transform error, got: %v", err)
+	}
+}
+
+func TestValueReaderTransform(t *testing.T) {
+	// Skip the first byte of every value, without buffering it.
+	db := New(bytes.NewReader(newDBBytes(records)), ValueReaderTransform(func(key []byte, val *io.SectionReader) (*io.SectionReader, error) {
+		return io.NewSectionReader(val, 1, val.Size()-1), nil
+	}))
+	iter := db.Iterate([]byte("three"))
+	for _, expected := range []string{"", "3", "33"} {
+		r, err := iter.NextReader()
+		if err != nil {
+			t.Fatalf("NextReader error: %v", err)
+		}
+		if b, _ := ioutil.ReadAll(r); string(b) != expected {
+			t.Errorf("NextReader: expected %q, got: %q", expected, b)
+		}
+	}
+	if b, err := db.Bytes([]byte("two")); err != nil || string(b) != "" {
+		t.Errorf("Bytes: expected an empty value, got: %q, %v", b, err)
+	}
+	err := db.ForEachBytes(func(key, val []byte) error {
+		if string(key) == "three" && len(val) > 2 {
+			t.Errorf("ForEachBytes: untransformed value %s for %s", val, key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// offsetsReaderAt records the offsets of the reads from r.
+type offsetsReaderAt struct {
+	r    io.ReaderAt
+	offs []int64
+}
+
+func (o *offsetsReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	o.offs = append(o.offs, off)
+	return o.r.ReadAt(p, off)
+}
+
+func TestWithPreloadedHeader(t *testing.T) {
+	r := &offsetsReaderAt{r: bytes.NewReader(newDBBytes(records))}
+	db := New(r, WithPreloadedHeader())
+	checkRecords(t, db, records)
+	// Small databases are preloaded whole, but past the end of the file can
+	// still be read.
+	for i, off := range r.offs {
+		if i > 0 && off < int64(headerSize) {
+			t.Errorf("expected only the first read in the header, got reads at: %v", r.offs)
+			break
+		}
+	}
+}
+
+func TestWithMmap(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(newDBBytes(records)); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	db, err := Open(tmp.Name(), WithMmap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkRecords(t, db, records)
+}
+
+func TestWithMaxValueSize(t *testing.T) {
+	db := New(bytes.NewReader(newDBBytes(records)), WithMaxValueSize(2))
+	iter := db.Iterate([]byte("three"))
+	for _, val := range []string{"3", "33"} {
+		if b, err := iter.NextBytes(); err != nil || string(b) != val {
+			t.Fatalf("expected %s, got: %s, %v", val, b, err)
+		}
+	}
+	if _, err := iter.NextBytes(); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("expected ErrValueTooLarge for 333, got: %v", err)
+	}
+	if exists, err := db.Exists([]byte("three")); !exists || err != nil {
+		t.Errorf("expected Exists to ignore the limit, got: %v, %v", exists, err)
+	}
+	if err := db.ForEachBytes(func(key, val []byte) error { return nil }); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("expected ForEachBytes to fail with ErrValueTooLarge, got: %v", err)
+	}
+}
+
+func TestWithHash(t *testing.T) {
+	fnv32a := func(key []byte) uint32 {
+		h := fnv.New32a()
+		h.Write(key)
+		return h.Sum32()
+	}
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp, Hash(fnv32a))
+	for _, rec := range records {
+		for _, val := range rec.values {
+			if err := w.Write([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(b), WithHash(fnv32a))
+	checkRecords(t, db, records)
+	if err := db.Verify(); err != nil {
+		t.Errorf("Verify error: %v", err)
+	}
+	if err := New(bytes.NewReader(b)).Verify(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected Verify with the cdb hash to fail, got: %v", err)
+	}
+}
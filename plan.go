@@ -0,0 +1,193 @@
+package cdb
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// LookupPlan looks up a fixed set of keys in a Stack, a Sharded or a Cdb,
+// for services that answer the same keys against many databases. Making the
+// plan works out, for each key, which databases could have it and where its
+// hash table is in each, skipping databases whose table for the key is
+// empty, so lookups go straight to the slots.
+//
+// Tombstones are honored like they are by Stack. The plan is for the
+// databases as they were when it was made, so make a new
+// one after swapping a Stack's layers. Readers other than Cdb, Stack and
+// Sharded are looked up in as they are.
+//
+// Threadsafe.
+type LookupPlan struct {
+	keys  []PreparedKey
+	steps [][]planStep
+}
+
+// planStep is a database to look a key up in.
+type planStep struct {
+	// db is the database to probe directly, with the key's hash h and the
+	// position and number of slots of its table, or nil to use g.
+	db     *Cdb
+	lay    layout
+	h      uint32
+	hpos   uint64
+	hslots uint64
+	g      Getter
+}
+
+// NewLookupPlan returns a plan for looking up keys in g.
+func NewLookupPlan(g Getter, keys [][]byte) (*LookupPlan, error) {
+	p := &LookupPlan{
+		keys:  make([]PreparedKey, len(keys)),
+		steps: make([][]planStep, len(keys)),
+	}
+	for i, key := range keys {
+		p.keys[i] = Prepare(append([]byte(nil), key...))
+		steps, err := planSteps(g, p.keys[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		p.steps[i] = steps
+	}
+	return p, nil
+}
+
+// planSteps appends the databases to look k up in, in order, to steps.
+func planSteps(g Getter, k PreparedKey, steps []planStep) ([]planStep, error) {
+	switch g := g.(type) {
+	case *Cdb:
+		lay, err := g.layout()
+		if err != nil {
+			return nil, err
+		}
+		h := k.hashIn(g)
+		hpos, hslots, err := lay.readEntry(g.r, make([]byte, 16), uint64(h%256)*lay.entrySize())
+		if err != nil {
+			return nil, err
+		}
+		if hslots == 0 {
+			// The key can't be in this database.
+			return steps, nil
+		}
+		return append(steps, planStep{db: g, lay: lay, h: h, hpos: hpos, hslots: hslots}), nil
+	case *Stack:
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+		var err error
+		for i := len(g.layers) - 1; i >= 0 && err == nil; i-- {
+			steps, err = planSteps(g.layers[i], k, steps)
+		}
+		return steps, err
+	case *Sharded:
+		return planSteps(g.shard(k.key), k, steps)
+	}
+	return append(steps, planStep{g: g}), nil
+}
+
+// Len returns the number of keys in the plan.
+func (p *LookupPlan) Len() int {
+	return len(p.keys)
+}
+
+// Key returns the i'th key.
+func (p *LookupPlan) Key(i int) []byte {
+	return p.keys[i].key
+}
+
+// Databases returns the number of databases the i'th key is looked up in.
+func (p *LookupPlan) Databases(i int) int {
+	return len(p.steps[i])
+}
+
+// Bytes returns the first value of the i'th key in the first database that
+// has it, like Stack.Bytes. Returns ErrNotFound when none do.
+func (p *LookupPlan) Bytes(i int) ([]byte, error) {
+	for _, step := range p.steps[i] {
+		var b []byte
+		var err error
+		if step.db != nil {
+			iter := step.iter(p.keys[i].key)
+			b, err = iter.NextBytes()
+			putIter(iter)
+		} else {
+			b, err = step.g.Bytes(p.keys[i].key)
+		}
+		if !errors.Is(err, io.EOF) {
+			return b, err
+		}
+		if del, err := step.deleted(p.keys[i].key); del || err != nil {
+			return nil, notFound(err)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Exists returns true if any database has the i'th key.
+func (p *LookupPlan) Exists(i int) (bool, error) {
+	for _, step := range p.steps[i] {
+		var ok bool
+		var err error
+		if step.db != nil {
+			iter := step.iter(p.keys[i].key)
+			if err = iter.nextLive(); err == nil {
+				ok = true
+			} else if err == ErrNotFound {
+				err = nil
+			}
+			putIter(iter)
+		} else {
+			ok, err = step.g.Exists(p.keys[i].key)
+		}
+		if ok || err != nil {
+			return ok, err
+		}
+		if del, err := step.deleted(p.keys[i].key); del || err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// All returns the first value of every key, in order, with nil for keys no
+// database has.
+func (p *LookupPlan) All() ([][]byte, error) {
+	vals := make([][]byte, len(p.keys))
+	for i := range p.keys {
+		b, err := p.Bytes(i)
+		if err != nil && err != ErrNotFound {
+			return nil, err
+		}
+		vals[i] = b
+	}
+	return vals, nil
+}
+
+// iter returns a pooled iterator for key, starting at the planned table. It
+// must be returned with putIter.
+func (step *planStep) iter(key []byte) *CdbIterator {
+	c := step.db
+	iter := iterPool.Get().(*CdbIterator)
+	*iter = CdbIterator{db: c, r: c.r, key: key, ctx: context.Background(), khash: step.h, lay: step.lay}
+	if _, iter.initErr = c.layout(); iter.initErr != nil {
+		return iter
+	}
+	if c.negCache != nil && c.negCache.contains(step.h) {
+		iter.initErr = ErrNotFound
+		return iter
+	}
+	iter.setTable(step.hpos, step.hslots)
+	return iter
+}
+
+// deleted returns true if the step's database has only tombstones for key.
+func (step *planStep) deleted(key []byte) (bool, error) {
+	if step.db == nil {
+		return deleted(step.g, key)
+	}
+	if !step.db.tombstones {
+		return false, nil
+	}
+	iter := step.iter(key)
+	defer putIter(iter)
+	return iter.deleted()
+}
@@ -0,0 +1,52 @@
+package cdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupPlan(t *testing.T) {
+	keys := [][]byte{[]byte("one"), []byte("two"), []byte("four"), []byte("missing")}
+	delta := []rec{{"two", []string{"two!"}}, {"four", []string{"4"}}}
+	var shards [2][]rec
+	for _, r := range append(append([]rec(nil), records...), rec{"four", []string{"4"}}) {
+		i := ShardFor([]byte(r.key), 2)
+		shards[i] = append(shards[i], r)
+	}
+	for _, tc := range []struct {
+		name     string
+		g        Getter
+		expected []string
+	}{
+		{"stack", NewStack(newDB(records), NewStack(newDB(nil), newDB(delta))), []string{"1", "two!", "4", ""}},
+		{"sharded", NewSharded(newDB(shards[0]), newDB(shards[1])), []string{"1", "2", "4", ""}},
+	} {
+		p, err := NewLookupPlan(tc.g, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals, err := p.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i, v := range vals {
+			got = append(got, string(v))
+			if ok, err := p.Exists(i); err != nil || ok != (v != nil) {
+				t.Errorf("%s: expected Exists(%s) to be %v, got: %v, %v", tc.name, p.Key(i), v != nil, ok, err)
+			}
+			if b, err := tc.g.Bytes(p.Key(i)); string(b) != string(v) || (err == nil) != (v != nil) {
+				t.Errorf("%s: expected the plan to match Bytes(%s), got: %q and %q, %v", tc.name, p.Key(i), v, b, err)
+			}
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %q, got: %q", tc.name, tc.expected, got)
+		}
+		// The empty layer never has to be probed.
+		for i := 0; i < p.Len(); i++ {
+			if n := p.Databases(i); n > 2 {
+				t.Errorf("%s: expected at most 2 databases to probe for %s, got: %d", tc.name, p.Key(i), n)
+			}
+		}
+	}
+}
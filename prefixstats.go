@@ -0,0 +1,81 @@
+package cdb
+
+import (
+	"bytes"
+	"sort"
+)
+
+// PrefixStats is the size of the records whose keys share a prefix.
+type PrefixStats struct {
+	Prefix     string
+	Records    int64
+	KeyBytes   int64
+	ValueBytes int64
+}
+
+// A PrefixFunc returns the prefix of key that the key's record is counted
+// under by StatsByPrefix.
+type PrefixFunc func(key []byte) []byte
+
+// PrefixLen returns a PrefixFunc that groups keys by their first n bytes.
+// Keys shorter than n are their own prefix.
+func PrefixLen(n int) PrefixFunc {
+	return func(key []byte) []byte {
+		if len(key) > n {
+			return key[:n]
+		}
+		return key
+	}
+}
+
+// PrefixDelim returns a PrefixFunc that groups keys by everything before the
+// first of any of the bytes in delims, so PrefixDelim(":/") puts "user:1" and
+// "user/2" under "user". Keys without any of the delimiters are grouped under
+// the empty prefix.
+func PrefixDelim(delims string) PrefixFunc {
+	return func(key []byte) []byte {
+		i := bytes.IndexAny(key, delims)
+		if i < 0 {
+			return nil
+		}
+		return key[:i]
+	}
+}
+
+// StatsByPrefix returns the number of records and the bytes of keys and
+// values stored under each prefix returned by prefix, sorted by prefix. Value
+// sizes are the sizes stored in the database, before any ValueTransform.
+//
+// Threadsafe.
+func (c *Cdb) StatsByPrefix(prefix PrefixFunc) ([]PrefixStats, error) {
+	byPrefix := make(map[string]*PrefixStats)
+	var kbuf []byte
+	err := c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		if uint32(cap(kbuf)) < klen {
+			kbuf = make([]byte, klen)
+		}
+		kbuf = kbuf[:klen]
+		if err := readFull(c.r, kbuf, int64(pos)+8); err != nil {
+			return err
+		}
+		p := prefix(kbuf)
+		s := byPrefix[string(p)]
+		if s == nil {
+			s = &PrefixStats{Prefix: string(p)}
+			byPrefix[s.Prefix] = s
+		}
+		s.Records++
+		s.KeyBytes += int64(klen)
+		s.ValueBytes += int64(dlen)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]PrefixStats, 0, len(byPrefix))
+	for _, s := range byPrefix {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Prefix < stats[j].Prefix })
+	return stats, nil
+}
@@ -0,0 +1,42 @@
+package cdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestStatsByPrefix(t *testing.T) {
+	db := New(bytes.NewReader(newDBBytes([]rec{
+		{"user:1", []string{"ann", "a"}},
+		{"user/2", []string{"bob"}},
+		{"team:x", []string{"red"}},
+		{"plain", []string{"pp"}},
+	})))
+
+	stats, err := db.StatsByPrefix(PrefixDelim(":/"))
+	if err != nil {
+		t.Fatalf("StatsByPrefix error: %v", err)
+	}
+	expected := []PrefixStats{
+		{Prefix: "", Records: 1, KeyBytes: 5, ValueBytes: 2},
+		{Prefix: "team", Records: 1, KeyBytes: 6, ValueBytes: 3},
+		{Prefix: "user", Records: 3, KeyBytes: 18, ValueBytes: 7},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, stats)
+	}
+
+	stats, err = db.StatsByPrefix(PrefixLen(2))
+	if err != nil {
+		t.Fatalf("StatsByPrefix error: %v", err)
+	}
+	expected = []PrefixStats{
+		{Prefix: "pl", Records: 1, KeyBytes: 5, ValueBytes: 2},
+		{Prefix: "te", Records: 1, KeyBytes: 6, ValueBytes: 3},
+		{Prefix: "us", Records: 3, KeyBytes: 18, ValueBytes: 7},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, stats)
+	}
+}
@@ -0,0 +1,80 @@
+package cdb
+
+import "context"
+
+// PreparedKey is a key with its hash worked out, for services that look up
+// the same keys in many databases. Lookups with it skip hashing the key,
+// except in databases opened WithHash, which need their own hash.
+type PreparedKey struct {
+	key []byte
+	h   uint32
+}
+
+// Prepare returns key prepared for lookups. The key isn't copied, so it
+// mustn't be modified while the PreparedKey is in use.
+func Prepare(key []byte) PreparedKey {
+	return PreparedKey{key: key, h: checksum(key)}
+}
+
+// Key returns the key.
+func (k PreparedKey) Key() []byte {
+	return k.key
+}
+
+// Table returns the index of the hash table the key is in, in databases
+// using the cdb hash.
+func (k PreparedKey) Table() int {
+	return int(k.h % 256)
+}
+
+// hashIn returns the key's hash in c.
+func (k PreparedKey) hashIn(c *Cdb) uint32 {
+	if c.hash != nil {
+		return c.hash(k.key)
+	}
+	return k.h
+}
+
+// getPreparedIter is getIter for a prepared key.
+func (c *Cdb) getPreparedIter(k PreparedKey) *CdbIterator {
+	iter := iterPool.Get().(*CdbIterator)
+	iter.resetHashed(context.Background(), c, k.key, k.hashIn(c))
+	return iter
+}
+
+// ExistsPrepared is Exists for a prepared key.
+//
+// Threadsafe.
+func (c *Cdb) ExistsPrepared(k PreparedKey) (bool, error) {
+	defer c.observeSince(c.now())
+	iter := c.getPreparedIter(k)
+	err := iter.nextLive()
+	putIter(iter)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BytesPrepared is Bytes for a prepared key.
+//
+// Threadsafe.
+func (c *Cdb) BytesPrepared(k PreparedKey) ([]byte, error) {
+	defer c.observeSince(c.now())
+	iter := c.getPreparedIter(k)
+	b, err := iter.NextBytes()
+	putIter(iter)
+	return b, err
+}
+
+// IteratePrepared is Iterate for a prepared key.
+//
+// Threadsafe.
+func (c *Cdb) IteratePrepared(k PreparedKey) *CdbIterator {
+	iter := new(CdbIterator)
+	iter.resetHashed(context.Background(), c, k.key, k.hashIn(c))
+	return iter
+}
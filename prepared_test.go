@@ -0,0 +1,34 @@
+package cdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPreparedKey(t *testing.T) {
+	dbs := []*Cdb{
+		newDB(records),
+		New(bytes.NewReader(convertBytes(t, newDB(records), Format{Hash: fnvHash})), WithHash(fnvHash)),
+	}
+	for i, db := range dbs {
+		k := Prepare([]byte("three"))
+		if ok, err := db.ExistsPrepared(k); !ok || err != nil {
+			t.Errorf("db %d: expected three to exist, got: %v, %v", i, ok, err)
+		}
+		if b, err := db.BytesPrepared(k); err != nil || string(b) != "3" {
+			t.Errorf("db %d: expected 3, got: %q, %v", i, b, err)
+		}
+		iter := db.IteratePrepared(k)
+		for _, expected := range []string{"3", "33", "333"} {
+			if b, err := iter.NextBytes(); err != nil || string(b) != expected {
+				t.Errorf("db %d: expected %s, got: %q, %v", i, expected, b, err)
+			}
+		}
+		if _, err := db.BytesPrepared(Prepare([]byte("missing"))); err != ErrNotFound {
+			t.Errorf("db %d: expected ErrNotFound, got: %v", i, err)
+		}
+	}
+	if k := Prepare([]byte("one")); k.Table() != int(checksum([]byte("one"))%256) || string(k.Key()) != "one" {
+		t.Errorf("unexpected prepared key: %+v", k)
+	}
+}
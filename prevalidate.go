@@ -0,0 +1,81 @@
+package cdb
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// PrevalidationStats reports how much of a database PrevalidateWithBudget
+// checked.
+type PrevalidationStats struct {
+	// Tables is the number of hash tables checked in full, out of 256.
+	Tables int
+	// Records is the number of records checked.
+	Records int64
+	// Confidence is the fraction of the hash slots of the database that were
+	// checked, from 0 to 1.
+	Confidence float64
+	// Elapsed is the time spent checking.
+	Elapsed time.Duration
+}
+
+// PrevalidateWithBudget spends at most budget checking the hash tables of db,
+// in random order, for a service that wants some assurance at startup without
+// waiting for a full Verify. Each slot of a checked table must point to a
+// record in the data section whose key has the slot's hash, as in Verify, but
+// the records aren't checked to be in exactly one table. It returns what it
+// managed to check, and a CorruptError if it found damage. Running out of
+// budget isn't an error, but ctx being done is.
+//
+// Threadsafe.
+func PrevalidateWithBudget(ctx context.Context, db *Cdb, budget time.Duration) (*PrevalidationStats, error) {
+	start := time.Now()
+	stats := new(PrevalidationStats)
+	defer func() { stats.Elapsed = time.Since(start) }()
+	lay, err := db.layout()
+	if err != nil {
+		return stats, err
+	}
+	header := make([]byte, lay.headerSize())
+	if err := readFull(db.r, header, 0); err != nil {
+		return stats, err
+	}
+	var total, checked uint64
+	for i := uint64(0); i < 256; i++ {
+		_, nslots := lay.entry(header[i*lay.entrySize():])
+		total += nslots
+	}
+
+	budgetCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+	for _, i := range rand.Perm(256) {
+		var n int64
+		err := db.walkSlots(i, func(recPos uint64, klen, dlen uint32) error {
+			n++
+			if n%64 == 0 {
+				return budgetCtx.Err()
+			}
+			return nil
+		})
+		if err == nil {
+			err = budgetCtx.Err()
+		}
+		stats.Records += n
+		if err != nil {
+			if budgetCtx.Err() != nil && ctx.Err() == nil {
+				// The budget ran out.
+				break
+			}
+			return stats, err
+		}
+		_, nslots := lay.entry(header[uint64(i)*lay.entrySize():])
+		checked += nslots
+		stats.Tables++
+	}
+	stats.Confidence = 1
+	if total > 0 {
+		stats.Confidence = float64(checked) / float64(total)
+	}
+	return stats, nil
+}
@@ -0,0 +1,43 @@
+package cdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPrevalidateWithBudget(t *testing.T) {
+	stats, err := PrevalidateWithBudget(context.Background(), newDB(records), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Tables != 256 || stats.Records != 6 || stats.Confidence != 1 {
+		t.Errorf("expected the whole database to be checked, got: %+v", stats)
+	}
+
+	// Point a slot at the middle of a record.
+	b := newDBBytes(records)
+	db := New(bytes.NewReader(b))
+	iter := db.Iterate([]byte("one"))
+	if err := iter.next(); err != nil {
+		t.Fatal(err)
+	}
+	putNum(b[iter.kpos-8+4:], headerSize+1)
+	if _, err := PrevalidateWithBudget(context.Background(), New(bytes.NewReader(b)), time.Minute); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got: %v", err)
+	}
+
+	// A spent budget stops checking without an error.
+	stats, err = PrevalidateWithBudget(context.Background(), newDB(records), 0)
+	if err != nil || stats.Tables != 0 || stats.Confidence != 0 {
+		t.Errorf("expected nothing to be checked, got: %+v, %v", stats, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := PrevalidateWithBudget(ctx, newDB(records), time.Minute); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+}
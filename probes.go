@@ -0,0 +1,60 @@
+package cdb
+
+import "sync/atomic"
+
+// maxProbeSpan is the most slots a lookup reads in one go.
+const maxProbeSpan = 8
+
+// tableProbes learns how many slots lookups in a hash table read, so that
+// lookups can fetch that many with one read instead of one read per slot.
+// Lookups start reading one slot at a time, and read more as chains in the
+// table turn out to be long.
+type tableProbes struct {
+	// avg is a moving average of the slots read per lookup, times 16.
+	avg uint32
+}
+
+// observe records a lookup that read n slots. Updates racing with each other
+// may be lost, which only slows learning down.
+func (t *tableProbes) observe(n uint32) {
+	avg := atomic.LoadUint32(&t.avg)
+	x := n * 16
+	if x > maxProbeSpan*16 {
+		x = maxProbeSpan * 16
+	}
+	// Weigh the new lookup as 1/8 of the average.
+	atomic.StoreUint32(&t.avg, avg-avg/8+x/8)
+}
+
+// span returns the number of slots to read at once.
+func (t *tableProbes) span() uint32 {
+	p := (atomic.LoadUint32(&t.avg) + 15) / 16
+	span := uint32(1)
+	for span < p && span < maxProbeSpan {
+		span *= 2
+	}
+	return span
+}
+
+// ProbeStats describes what lookups have learned about the hash tables.
+type ProbeStats struct {
+	// AvgSlots is the moving average of the slots read by lookups in each
+	// table.
+	AvgSlots [256]float64
+	// Span is the number of slots lookups in each table currently fetch with
+	// one read.
+	Span [256]int
+}
+
+// ProbeStats returns the slots per read that lookups have learned for each
+// hash table, for tuning.
+//
+// Threadsafe.
+func (c *Cdb) ProbeStats() ProbeStats {
+	var stats ProbeStats
+	for i := range c.probes {
+		stats.AvgSlots[i] = float64(atomic.LoadUint32(&c.probes[i].avg)) / 16
+		stats.Span[i] = int(c.probes[i].span())
+	}
+	return stats
+}
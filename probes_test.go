@@ -0,0 +1,66 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// countingReads counts ReadAt calls.
+type countingReads struct {
+	r     *bytes.Reader
+	reads int
+}
+
+func (c *countingReads) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+func TestAdaptiveProbeSpan(t *testing.T) {
+	// Keys that all start at the same slot of the 128 in table 7 make a long
+	// chain.
+	const start = 7 + 256*5
+	var recs []rec
+	var keys [][]byte
+	for i := 0; len(keys) < 64; i++ {
+		key := make([]byte, 4)
+		binary.LittleEndian.PutUint32(key, uint32(i))
+		if checksum(key)%(256*128) == start {
+			keys = append(keys, key)
+			recs = append(recs, rec{string(key), []string{"v"}})
+		}
+	}
+	cr := &countingReads{r: bytes.NewReader(newDBBytes(recs))}
+	db := New(cr)
+	if span := db.ProbeStats().Span[7]; span != 1 {
+		t.Fatalf("expected lookups to start with one slot, got: %d", span)
+	}
+
+	missing := make([]byte, 5)
+	for i := 0; checksum(missing)%(256*128) != start; i++ {
+		binary.LittleEndian.PutUint32(missing, uint32(i))
+	}
+	lookups := func() int {
+		cr.reads = 0
+		for i := 0; i < 50; i++ {
+			if ok, err := db.Exists(missing); ok || err != nil {
+				t.Fatalf("Exists: expected a miss, got: %v, %v", ok, err)
+			}
+		}
+		return cr.reads
+	}
+	first := lookups()
+	stats := db.ProbeStats()
+	if stats.Span[7] != maxProbeSpan || stats.AvgSlots[7] <= 1 {
+		t.Errorf("expected long chains to raise the span to %d, got: %d (avg %v)", maxProbeSpan, stats.Span[7], stats.AvgSlots[7])
+	}
+	if second := lookups(); second >= first {
+		t.Errorf("expected fewer reads once the span is learned, got %d then %d", first, second)
+	}
+	for _, key := range keys {
+		if ok, err := db.Exists(key); !ok || err != nil {
+			t.Errorf("Exists(%x): expected a hit, got: %v, %v", key, ok, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProbeTimeoutError is returned by an iterator that took longer than its
+// probe timeout to find the next value. It matches context.DeadlineExceeded
+// with errors.Is, and has a Timeout method like net.Error.
+type ProbeTimeoutError struct {
+	Key []byte
+	// Probes is the number of hash slots checked before giving up.
+	Probes uint32
+	// Limit is the probe timeout.
+	Limit time.Duration
+}
+
+func (e *ProbeTimeoutError) Error() string {
+	return fmt.Sprintf("cdb: lookup of %q gave up after %d probes in %v", e.Key, e.Probes, e.Limit)
+}
+
+func (e *ProbeTimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// Timeout returns true.
+func (e *ProbeTimeoutError) Timeout() bool {
+	return true
+}
+
+// SetProbeTimeout limits how long each call to NextBytes or NextReader spends
+// walking the key's hash slots and comparing keys, so a long probe chain on
+// slow storage is abandoned with a ProbeTimeoutError instead of blocking.
+// Reads that hang are left behind, as with IterateContext. Reading the value
+// found isn't limited, so use IterateContext to bound that too. A timeout of
+// 0 removes the limit.
+//
+// Not threadsafe.
+func (iter *CdbIterator) SetProbeTimeout(d time.Duration) {
+	iter.probeTimeout = d
+}
+
+// nextWithin is next, giving up after d.
+func (iter *CdbIterator) nextWithin(d time.Duration) error {
+	ctx, cancel := context.WithTimeout(iter.ctx, d)
+	defer cancel()
+	r := iter.r
+	// The timeout's context is done when the iterator's is, so it replaces
+	// the iterator's context on the reader.
+	iter.r = &ctxReaderAt{ctx, iter.db.r}
+	err := iter.probe()
+	iter.r = r
+	if err != nil && ctx.Err() == context.DeadlineExceeded && iter.ctx.Err() == nil {
+		return &ProbeTimeoutError{Key: append([]byte(nil), iter.key...), Probes: iter.probes, Limit: d}
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package cdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// hangingAfterReaderAt is hangingReaderAt for reads from pos on.
+type hangingAfterReaderAt struct {
+	hangingReaderAt
+	pos int64
+}
+
+func (h *hangingAfterReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < h.pos {
+		return h.r.ReadAt(p, off)
+	}
+	return h.hangingReaderAt.ReadAt(p, off)
+}
+
+func TestProbeTimeout(t *testing.T) {
+	b := newDBBytes(records)
+	iter := newDB(records).Iterate([]byte("three"))
+	iter.SetProbeTimeout(time.Minute)
+	for _, expected := range []string{"3", "33", "333"} {
+		if v, err := iter.NextBytes(); err != nil || string(v) != expected {
+			t.Errorf("expected %s, got: %q, %v", expected, v, err)
+		}
+	}
+	if _, err := iter.NextBytes(); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	// Reads of the hash tables, after the records, hang.
+	var r io.ReaderAt = bytes.NewReader(b)
+	h := &hangingAfterReaderAt{hangingReaderAt{r, make(chan struct{})}, int64(binary.LittleEndian.Uint32(b))}
+	defer close(h.unblock)
+	iter = New(h).Iterate([]byte("three"))
+	iter.SetProbeTimeout(10 * time.Millisecond)
+	_, err := iter.NextBytes()
+	var timeout *ProbeTimeoutError
+	if !errors.As(err, &timeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a ProbeTimeoutError, got: %v", err)
+	}
+	if string(timeout.Key) != "three" || timeout.Limit != 10*time.Millisecond {
+		t.Errorf("unexpected error fields: %+v", timeout)
+	}
+}
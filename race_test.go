@@ -0,0 +1,7 @@
+//go:build race
+
+package cdb
+
+// raceEnabled is true when the race detector is on, which makes some calls
+// allocate.
+const raceEnabled = true
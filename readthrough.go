@@ -0,0 +1,97 @@
+package cdb
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReadThroughMap serves lookups of hot keys from memory, like a sync.Map that
+// fills itself from a database. The first Get of a key reads its first value
+// from the database, and later Gets return the copy in memory. Misses are
+// remembered too.
+//
+// With a TTL, a key is read from the database again once its copy is older
+// than the TTL, so changes reach the map when the database is a Stack or
+// another Getter whose files are swapped as new versions arrive. Call
+// Invalidate to drop every copy at once after a swap instead. A Get that
+// read the old database before Invalidate doesn't put its copy back.
+//
+// A cdbconfig.Source, which reopens its file itself, isn't a Getter: its
+// lookups already go to the current file without reading through a cache,
+// and Subscribe reports the keys a reload changes.
+//
+// Every key looked up stays in memory, so it suits sets of hot keys that are
+// small enough to hold.
+//
+// Threadsafe.
+type ReadThroughMap struct {
+	g   Getter
+	ttl time.Duration
+	// now returns the current time, and is replaced by tests.
+	now func() time.Time
+	// entries maps keys, as strings, to their *readThroughEntry.
+	entries sync.Map
+	// gen is incremented by Invalidate. Entries read in an earlier
+	// generation are ignored, since they may have been stored after it.
+	gen atomic.Uint64
+}
+
+type readThroughEntry struct {
+	val    []byte
+	found  bool
+	loaded time.Time
+	gen    uint64
+}
+
+// NewReadThroughMap returns a ReadThroughMap reading from g, which refreshes
+// keys once they are older than ttl. A ttl of 0 keeps them until Invalidate
+// or Delete.
+func NewReadThroughMap(g Getter, ttl time.Duration) *ReadThroughMap {
+	return &ReadThroughMap{g: g, ttl: ttl, now: time.Now}
+}
+
+// Get returns the first value for key, from memory if it has been read
+// before. Returns ErrNotFound when there is no value, which g may report with
+// any error matching io.EOF. Errors reading the
+// database aren't remembered. The value is shared by every caller, so it
+// must not be modified.
+func (m *ReadThroughMap) Get(key []byte) ([]byte, error) {
+	gen := m.gen.Load()
+	if v, ok := m.entries.Load(string(key)); ok {
+		e := v.(*readThroughEntry)
+		if e.gen == gen && (m.ttl == 0 || m.now().Sub(e.loaded) < m.ttl) {
+			return e.get()
+		}
+	}
+	e := &readThroughEntry{loaded: m.now(), gen: gen}
+	val, err := m.g.Bytes(key)
+	switch {
+	case err == nil:
+		e.val, e.found = val, true
+	case !errors.Is(err, io.EOF):
+		return nil, err
+	}
+	m.entries.Store(string(key), e)
+	return e.get()
+}
+
+func (e *readThroughEntry) get() ([]byte, error) {
+	if !e.found {
+		return nil, ErrNotFound
+	}
+	return e.val, nil
+}
+
+// Delete drops the copy of key, so the next Get reads it again.
+func (m *ReadThroughMap) Delete(key []byte) {
+	m.entries.Delete(string(key))
+}
+
+// Invalidate drops every copy, so each key is read again on its next Get.
+func (m *ReadThroughMap) Invalidate() {
+	m.gen.Add(1)
+	m.entries.Clear()
+}
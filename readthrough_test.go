@@ -0,0 +1,75 @@
+package cdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadThroughMap(t *testing.T) {
+	layer := &countingGetter{Getter: newDB(records)}
+	s := NewStack(layer)
+	m := NewReadThroughMap(s, time.Minute)
+	now := time.Unix(0, 0)
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if b, err := m.Get([]byte("one")); err != nil || string(b) != "1" {
+			t.Fatalf("expected 1, got: %q, %v", b, err)
+		}
+		if _, err := m.Get([]byte("missing")); err != ErrNotFound {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	}
+	if layer.lookups != 2 {
+		t.Errorf("expected each key to be read once, got: %d reads", layer.lookups)
+	}
+
+	// A swapped file is seen once the TTL has passed.
+	s.Swap(0, newDB([]rec{{"one", []string{"new"}}}))
+	if b, _ := m.Get([]byte("one")); string(b) != "1" {
+		t.Errorf("expected the copy before the TTL, got: %q", b)
+	}
+	now = now.Add(time.Minute)
+	if b, err := m.Get([]byte("one")); err != nil || string(b) != "new" {
+		t.Errorf("expected new after the TTL, got: %q, %v", b, err)
+	}
+
+	s.Swap(0, newDB(nil))
+	m.Invalidate()
+	if _, err := m.Get([]byte("one")); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after Invalidate, got: %v", err)
+	}
+}
+
+// afterReadGetter calls after once a lookup has read its value.
+type afterReadGetter struct {
+	Getter
+	after func()
+}
+
+func (g *afterReadGetter) Bytes(key []byte) ([]byte, error) {
+	b, err := g.Getter.Bytes(key)
+	if g.after != nil {
+		g.after()
+	}
+	return b, err
+}
+
+func TestReadThroughMapInvalidateDuringGet(t *testing.T) {
+	s := NewStack(newDB(records))
+	g := &afterReadGetter{Getter: s}
+	m := NewReadThroughMap(g, 0)
+	// The database is swapped and the map invalidated after the Get has
+	// read the old value, but before it stores it.
+	g.after = func() {
+		g.after = nil
+		s.Swap(0, newDB([]rec{{"one", []string{"new"}}}))
+		m.Invalidate()
+	}
+	if b, err := m.Get([]byte("one")); err != nil || string(b) != "1" {
+		t.Fatalf("expected the old value, got: %q, %v", b, err)
+	}
+	if b, err := m.Get([]byte("one")); err != nil || string(b) != "new" {
+		t.Errorf("expected the value after Invalidate, got: %q, %v", b, err)
+	}
+}
@@ -0,0 +1,51 @@
+package cdb
+
+import "io"
+
+// RebuildTables writes a copy of the database in r to w with its hash tables
+// rebuilt from the records, recovering a database whose tables are damaged
+// without going back to the data it was built from. The header entry of the
+// first table still has to give the end of the records. The records are
+// checked to fit before it, and against their checksums if the database has
+// them, and the extensions are kept.
+//
+// The tables are rebuilt with the cdb hash. Databases built with another hash
+// can be rebuilt with ConvertFormat.
+func RebuildTables(r io.ReaderAt, w io.WriteSeeker) error {
+	src := New(r)
+	f, err := src.Format()
+	if err != nil {
+		return err
+	}
+	if err := src.checkRecordBounds(); err != nil {
+		return err
+	}
+	if f.Checksums {
+		if err := src.VerifyChecksums(); err != nil {
+			return err
+		}
+	}
+	return ConvertFormat(w, src, f)
+}
+
+// checkRecordBounds checks that the records end exactly where the first hash
+// table starts, which is the most that can be checked without the tables.
+func (c *Cdb) checkRecordBounds() error {
+	lay, err := c.layout()
+	if err != nil {
+		return err
+	}
+	end, _, err := lay.readEntry(c.r, make([]byte, 16), 0)
+	if err != nil {
+		return err
+	}
+	if end < lay.headerSize() {
+		return corruptf(0, "hash table 0 starts at %d, inside the header", end)
+	}
+	return c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		if pos+8+uint64(klen)+uint64(dlen) > end {
+			return corruptf(pos, "record runs past the end of the data section")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,63 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func rebuildBytes(t *testing.T, b []byte) ([]byte, error) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := RebuildTables(bytes.NewReader(b), tmp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(tmp.Name())
+}
+
+func TestRebuildTables(t *testing.T) {
+	orig := convertBytes(t, New(bytes.NewReader(newDBBytes(records))), Format{Checksums: true})
+
+	// Wipe the hash tables, which run from the end of the records to the end
+	// of the last table.
+	damaged := append([]byte(nil), orig...)
+	start := binary.LittleEndian.Uint32(damaged)
+	last := damaged[255*8:]
+	end := binary.LittleEndian.Uint32(last) + 8*binary.LittleEndian.Uint32(last[4:])
+	for i := start; i < end; i++ {
+		damaged[i] = 0xff
+	}
+	if err := VerifyReader(bytes.NewReader(damaged)); err == nil {
+		t.Fatal("expected the damaged database to fail verification")
+	}
+
+	got, err := rebuildBytes(t, damaged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, orig) {
+		t.Errorf("expected the rebuilt database to match the original")
+	}
+
+	// Damaged records are caught by their checksums.
+	damaged[2048+8] ^= 1
+	if _, err := rebuildBytes(t, damaged); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt for a damaged record, got: %v", err)
+	}
+}
+
+func TestRebuildTablesRecordBounds(t *testing.T) {
+	b := newDBBytes(records)
+	// Make the first record longer than the data section.
+	binary.LittleEndian.PutUint32(b[2048+4:], 1<<20)
+	if _, err := rebuildBytes(t, b); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package cdb
+
+import (
+	"io"
+)
+
+// RecoverRecords scans the records of a partially written database, such as a
+// build that died before its Writer was closed, calling onRecordFn for every
+// complete record. Size is the size of the file. It returns the file position
+// just past the last complete record, which is where the data would continue.
+//
+// A database that was completely written is scanned up to its hash tables.
+// Only the classic layout is supported, since the layout of a partial build
+// can't be told from its empty header.
+//
+// If onRecordFn returns an error, the scan will stop and the error will be
+// returned.
+func RecoverRecords(r io.ReaderAt, size int64, onRecordFn func(keyReader, valReader *io.SectionReader) error) (int64, error) {
+	buf := make([]byte, 8)
+	end := size
+	// Partial builds have an empty header, but finished ones have the end of
+	// the data in the first entry.
+	if eod, _, err := readNums(r, buf, 0); err != nil {
+		return 0, err
+	} else if eod != 0 && int64(eod) <= size {
+		end = int64(eod)
+	}
+	pos := int64(headerSize)
+	for pos+8 <= end {
+		klen, dlen, err := readNums(r, buf, pos)
+		if err != nil {
+			return 0, err
+		}
+		next := pos + 8 + int64(klen) + int64(dlen)
+		if next > end {
+			// The last record was cut off.
+			break
+		}
+		keyReader := io.NewSectionReader(r, pos+8, int64(klen))
+		dataReader := io.NewSectionReader(r, pos+8+int64(klen), int64(dlen))
+		if err := onRecordFn(keyReader, dataReader); err != nil {
+			return 0, err
+		}
+		pos = next
+	}
+	return pos, nil
+}
@@ -0,0 +1,54 @@
+package cdb
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFlushAndRecoverRecords(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp)
+	for _, rec := range records {
+		for _, val := range rec.values {
+			if err := w.Write([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	// The build dies here, leaving the flushed records and half of another.
+	fi, err := tmp.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	flushed := fi.Size()
+	if _, err := tmp.WriteAt([]byte{3, 0, 0, 0, 9, 0, 0, 0, 'k'}, flushed); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	end, err := RecoverRecords(tmp, flushed+9, func(keyReader, valReader *io.SectionReader) error {
+		key, _ := ioutil.ReadAll(keyReader)
+		val, _ := ioutil.ReadAll(valReader)
+		got = append(got, string(key)+"="+string(val))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RecoverRecords error: %v", err)
+	}
+	if end != flushed {
+		t.Errorf("expected end %v, got: %v", flushed, end)
+	}
+	if len(got) != 6 || got[0] != "one=1" || got[5] != "three=333" {
+		t.Errorf("expected the six flushed records, got: %v", got)
+	}
+	w.Close()
+}
@@ -0,0 +1,106 @@
+package cdb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ResumeMake finishes a build by Make or a Writer that died before the
+// database was complete. Partial must read the same file that ws writes to.
+// The complete records already in it are checked against the start of r,
+// which must be the same input the original build was given, and the rest of
+// r is appended to them before the hash tables are written. This avoids
+// rewriting all the data of a huge build that died near the end.
+//
+// If ws has a Truncate method, like *os.File, the file is truncated after the
+// complete records, so what is left of a partial record doesn't remain past
+// the end of the new database.
+//
+// ResumeMake returns an error if the existing records don't match the input.
+func ResumeMake(ws io.WriteSeeker, partial io.ReaderAt, r io.Reader) error {
+	size, err := ws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	state := &resumeState{}
+	hash, sum := cdbHash(), crc32.NewIEEE()
+	end, err := RecoverRecords(partial, size, func(keyReader, valReader *io.SectionReader) error {
+		hash.Reset()
+		sum.Reset()
+		if _, err := io.Copy(io.MultiWriter(hash, sum), keyReader); err != nil {
+			return err
+		}
+		if _, err := io.Copy(sum, valReader); err != nil {
+			return err
+		}
+		_, pos, _ := keyReader.Outer()
+		state.records = append(state.records, resumeRecord{
+			h:    hash.Sum32(),
+			pos:  uint64(pos - 8),
+			klen: uint32(keyReader.Size()),
+			dlen: uint32(valReader.Size()),
+			sum:  sum.Sum32(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	state.end = uint64(end)
+	if t, ok := ws.(interface {
+		Truncate(size int64) error
+	}); ok {
+		if err := t.Truncate(end); err != nil {
+			return err
+		}
+	}
+	rb, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	return makeFrom(ws, &recReader{rb}, textFormat, &makeHooks{resume: state})
+}
+
+// resumeState describes the records already written by a build that is being
+// resumed.
+type resumeState struct {
+	records []resumeRecord
+	// end is the file position just past the last record.
+	end uint64
+}
+
+type resumeRecord struct {
+	h          uint32
+	pos        uint64
+	klen, dlen uint32
+	// sum is the CRC-32 of the key and value.
+	sum uint32
+}
+
+// skip reads the input records that were already written, checking that their
+// keys and values match, and adds their slots to htables. It panics with an error if they
+// don't match.
+func (s *resumeState) skip(rr *recReader, format recordFormat, h hash.Hash32, htables map[uint32][]slot) {
+	sum := crc32.NewIEEE()
+	for i, rec := range s.records {
+		klen, dlen, ok := format.header(rr)
+		if !ok {
+			panic(errors.New("cdb: input ended before the records already written"))
+		}
+		h.Reset()
+		sum.Reset()
+		rr.copyn(io.MultiWriter(h, sum), klen)
+		format.separator(rr)
+		rr.copyn(sum, dlen)
+		format.trailer(rr)
+		if klen != rec.klen || dlen != rec.dlen || h.Sum32() != rec.h || sum.Sum32() != rec.sum {
+			panic(fmt.Errorf("cdb: input record %d doesn't match the record already written at %d", i, rec.pos))
+		}
+		tableNum := rec.h % 256
+		htables[tableNum] = append(htables[tableNum], slot{rec.h, rec.pos})
+	}
+}
@@ -0,0 +1,108 @@
+package cdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestResumeMake(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	// Start a build that dies after the first three records, leaving half of
+	// the fourth.
+	w := NewWriter(tmp)
+	w.Write([]byte("one"), []byte("1"))
+	w.Write([]byte("two"), []byte("2"))
+	w.Write([]byte("two"), []byte("22"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmp.Write([]byte{5, 0, 0, 0, 1, 0, 0, 0, 't', 'h'}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResumeMake(tmp, tmp, bytes.NewReader(data)); err != nil {
+		t.Fatalf("ResumeMake error: %v", err)
+	}
+	checkRecords(t, New(tmp), records)
+	tmp.Seek(0, 0)
+	var dump bytes.Buffer
+	if err := Dump(&dump, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump.Bytes(), data) {
+		t.Errorf("expected %q, got: %q", data, dump.Bytes())
+	}
+}
+
+func TestResumeMakeLongPartialRecord(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	// The partial record is longer than the hash tables and header written
+	// in its place.
+	w := NewWriter(tmp)
+	w.Write([]byte("one"), []byte("1"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	partial := make([]byte, 8+10000)
+	putNum(partial, 5)
+	putNum(partial[4:], 1<<20)
+	if _, err := tmp.Write(partial); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ResumeMake(tmp, tmp, bytes.NewReader(data)); err != nil {
+		t.Fatalf("ResumeMake error: %v", err)
+	}
+	db := New(tmp)
+	checkRecords(t, db, records)
+	if err := db.Verify(); err != nil {
+		t.Errorf("expected nothing past the end of the database, got: %v", err)
+	}
+}
+
+func TestResumeMakeMismatch(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	w := NewWriter(tmp)
+	w.Write([]byte("uno"), []byte("1"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResumeMake(tmp, tmp, bytes.NewReader(data)); err == nil {
+		t.Errorf("expected an error for input that doesn't match")
+	}
+}
+
+func TestResumeMakeValueMismatch(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	// The same key and lengths as the first input record, but another value.
+	w := NewWriter(tmp)
+	w.Write([]byte("one"), []byte("9"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResumeMake(tmp, tmp, bytes.NewReader(data)); err == nil {
+		t.Errorf("expected an error for a value that doesn't match")
+	}
+}
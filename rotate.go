@@ -0,0 +1,161 @@
+package cdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RotatingWriter writes records across a series of databases, each no bigger
+// than a size limit, for consumers that can't handle big files. When the next
+// record would take the current database over the limit, it is closed and
+// the record starts a new one.
+//
+// The databases can be read together as a Stack, base first, using the
+// catalog returned by Close. A key whose records are split between databases
+// only has the values in the last of them visible through the Stack, so keys
+// with many values should be written together.
+//
+// Not threadsafe.
+type RotatingWriter struct {
+	pattern string
+	limit   uint64
+	opts    []WriterOption
+
+	files []string
+	f     *os.File
+	w     *Writer
+	// err is the error that stopped the writer, if it has stopped.
+	err error
+}
+
+// NewRotatingWriter creates the first database and returns a RotatingWriter
+// writing to it. The database files are named by formatting pattern with
+// fmt.Sprintf and the number of the file, counting from 0, like
+// "data-%03d.cdb". Each is built with opts.
+func NewRotatingWriter(pattern string, limit int64, opts ...WriterOption) (*RotatingWriter, error) {
+	rw := &RotatingWriter{pattern: pattern, limit: uint64(limit), opts: opts}
+	if err := rw.next(); err != nil {
+		return nil, err
+	}
+	return rw, nil
+}
+
+// next creates the next database.
+func (rw *RotatingWriter) next() error {
+	name := fmt.Sprintf(rw.pattern, len(rw.files))
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	rw.files = append(rw.files, name)
+	rw.f, rw.w = f, NewWriter(f, rw.opts...)
+	return nil
+}
+
+// finish closes the current database.
+func (rw *RotatingWriter) finish() error {
+	err := rw.w.Close()
+	if closeErr := rw.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Put adds a record to the current database, first starting a new one if
+// the record would take it over the limit. A record too big to fit in an
+// empty database returns ErrTooLarge.
+func (rw *RotatingWriter) Put(key, val []byte) error {
+	if rw.err != nil {
+		return rw.err
+	}
+	klen, dlen := uint64(len(key)), uint64(len(val))
+	if rw.w.sizeWith(klen, dlen) > rw.limit {
+		if rw.w.nrecords == 0 {
+			return fmt.Errorf("%w: a record of %d bytes doesn't fit in %d bytes", ErrTooLarge, 8+klen+dlen, rw.limit)
+		}
+		if err := rw.finish(); err != nil {
+			rw.err = err
+			return err
+		}
+		if err := rw.next(); err != nil {
+			rw.err = err
+			return err
+		}
+		if rw.w.sizeWith(klen, dlen) > rw.limit {
+			return fmt.Errorf("%w: a record of %d bytes doesn't fit in %d bytes", ErrTooLarge, 8+klen+dlen, rw.limit)
+		}
+	}
+	return rw.w.Put(key, val)
+}
+
+// Write adds a record, like Put.
+func (rw *RotatingWriter) Write(key, val []byte) error {
+	return rw.Put(key, val)
+}
+
+// Close finishes the current database, and returns the names of all the
+// databases written and a catalog stacking them, the first as the base and
+// the rest as deltas. The catalog names the members by their base names, so
+// it has to be stored in the same directory, and its Version is left for the
+// caller to set.
+func (rw *RotatingWriter) Close() ([]string, *Catalog, error) {
+	if rw.err == ErrWriterClosed {
+		return nil, nil, ErrWriterClosed
+	}
+	err := rw.finish()
+	if rw.err == nil {
+		rw.err = err
+	}
+	if rw.err != nil {
+		err := rw.err
+		rw.err = ErrWriterClosed
+		return nil, nil, err
+	}
+	rw.err = ErrWriterClosed
+
+	cat := &Catalog{}
+	for i, name := range rw.files {
+		m := CatalogMember{Name: filepath.Base(name), Role: RoleDelta}
+		if i == 0 {
+			m.Role = RoleBase
+		}
+		db, err := Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		m.Fingerprint, err = Fingerprint(db)
+		db.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		cat.Members = append(cat.Members, m)
+	}
+	return append([]string(nil), rw.files...), cat, nil
+}
+
+// sizeWith returns the size the database would be if it were closed after
+// one more record with the given key and data lengths.
+func (w *Writer) sizeWith(klen, dlen uint64) uint64 {
+	lay := w.b.lay
+	size := w.b.pos + 8 + klen + dlen
+	// Hash tables have twice as many slots as records.
+	size += 2 * uint64(w.nrecords+1) * lay.entrySize()
+	// The extension sections, as extensions would return them, without
+	// encoding them for every record.
+	var exts, extSize uint64
+	if len(w.annotations) > 0 {
+		exts, extSize = exts+1, extSize+w.annotationsSize
+	}
+	if !w.buildTime.IsZero() {
+		exts, extSize = exts+1, extSize+8
+	}
+	if w.crc != nil {
+		// The checksums, including the new record's.
+		exts, extSize = exts+1, extSize+uint64(len(w.sums))+4
+	}
+	if exts > 0 {
+		size += uint64(len(extMagic)) + 4 + 8*exts + extSize
+	}
+	return size
+}
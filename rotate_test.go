@@ -0,0 +1,104 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const limit = 2048 + 200
+	rw, err := NewRotatingWriter(filepath.Join(dir, "part-%d.cdb"), limit, Checksums())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := rw.Put([]byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value%02d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Put([]byte("big"), make([]byte, limit)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge for a record bigger than the limit, got: %v", err)
+	}
+	files, cat, err := rw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rw.Close(); err != ErrWriterClosed {
+		t.Errorf("expected ErrWriterClosed, got: %v", err)
+	}
+	if len(files) < 2 || len(cat.Members) != len(files) {
+		t.Fatalf("expected several files in the catalog, got: %v, %+v", files, cat)
+	}
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > limit {
+			t.Errorf("expected %s to be at most %d bytes, got: %d", name, limit, info.Size())
+		}
+		if err := VerifyReader(mustOpen(t, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	name := filepath.Join(dir, "catalog.cdb")
+	writeCatalog(t, name, cat)
+	g, _, err := OpenCatalog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	for i := 0; i < 20; i++ {
+		b, err := g.Bytes([]byte(fmt.Sprintf("key%02d", i)))
+		if expected := fmt.Sprintf("value%02d", i); err != nil || string(b) != expected {
+			t.Errorf("expected %q, got: %q, %v", expected, b, err)
+		}
+	}
+}
+
+func mustOpen(t *testing.T, name string) *os.File {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestSizeWith(t *testing.T) {
+	for n := 1; n <= 10; n++ {
+		w := NewBufferWriter(Checksums(), BuildTime(time.Unix(1, 0)))
+		var expected uint64
+		for i := 0; i < n; i++ {
+			key, val := fmt.Sprintf("key%02d", i), fmt.Sprintf("value%d", i)
+			expected = w.sizeWith(uint64(len(key)), uint64(len(val)))
+			if err := w.Write([]byte(key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+			if i%3 == 0 && i < n-1 {
+				if err := w.Annotate([]byte(fmt.Sprint("meta", i))); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		b, err := w.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint64(len(b)) != expected {
+			t.Errorf("%d records: expected size %d, got %d", n, expected, len(b))
+		}
+	}
+}
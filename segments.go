@@ -0,0 +1,184 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A segment file holds several databases, or segments, one after another,
+// so a base and its deltas can be kept and appended to in one file. Each
+// segment is a complete database, with positions relative to its start. An
+// index follows the last segment: the offset and length of each segment, as
+// 64 bit little endian numbers, then the number of segments as a 32 bit
+// little endian number, then segMagic. Appending a segment writes it over the
+// index, then writes the index again after it.
+const segMagic = "cdbsegs1"
+
+// segTrailerSize is the size of the count and magic that end a segment file.
+const segTrailerSize = 4 + len(segMagic)
+
+// SegmentFile is a segment file being appended to, like an *os.File opened
+// for reading and writing.
+type SegmentFile interface {
+	io.ReaderAt
+	io.WriteSeeker
+	Truncate(size int64) error
+}
+
+// segment is the location of a segment in a segment file.
+type segment struct {
+	off, size uint64
+}
+
+// AppendSegment appends a segment to f, which may be empty, built by calling
+// build with a Writer, and updates the index. Nothing is appended if build
+// fails. The write isn't atomic: a crash part way through leaves a file
+// without an index, so f should be a copy if that matters.
+func AppendSegment(f SegmentFile, build func(*Writer) error, opts ...WriterOption) error {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	segs, start, err := readSegmentIndex(f, size)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(int64(start)); err != nil {
+		return err
+	}
+	w := NewWriter(&offsetSeeker{f, int64(start)}, opts...)
+	err = build(w)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Put back the index of the segments there were.
+		writeSegmentIndex(f, segs, start)
+		return err
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	segs = append(segs, segment{start, uint64(end) - start})
+	return writeSegmentIndex(f, segs, uint64(end))
+}
+
+// writeSegmentIndex writes the index of segs at pos, the end of the last
+// segment.
+func writeSegmentIndex(f SegmentFile, segs []segment, pos uint64) error {
+	if err := f.Truncate(int64(pos)); err != nil {
+		return err
+	}
+	if _, err := f.Seek(int64(pos), io.SeekStart); err != nil {
+		return err
+	}
+	buf := make([]byte, 0, 16*len(segs)+segTrailerSize)
+	for _, s := range segs {
+		buf = binary.LittleEndian.AppendUint64(buf, s.off)
+		buf = binary.LittleEndian.AppendUint64(buf, s.size)
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(segs)))
+	buf = append(buf, segMagic...)
+	_, err := f.Write(buf)
+	return err
+}
+
+// readSegmentIndex returns the segments of the segment file in r, of the
+// given size, and the position of the index. An empty file has no segments.
+func readSegmentIndex(r io.ReaderAt, size int64) ([]segment, uint64, error) {
+	if size == 0 {
+		return nil, 0, nil
+	}
+	if size < int64(segTrailerSize) {
+		return nil, 0, fmt.Errorf("%w: %d bytes is too short for a segment file", ErrInvalidDatabase, size)
+	}
+	trailer := make([]byte, segTrailerSize)
+	if err := readFull(r, trailer, size-int64(segTrailerSize)); err != nil {
+		return nil, 0, err
+	}
+	if string(trailer[4:]) != segMagic {
+		return nil, 0, fmt.Errorf("%w: no segment index", ErrInvalidDatabase)
+	}
+	n := uint64(binary.LittleEndian.Uint32(trailer))
+	if 16*n > uint64(size)-uint64(segTrailerSize) {
+		return nil, 0, corruptf(uint64(size)-uint64(segTrailerSize), "index of %d segments is bigger than the file", n)
+	}
+	pos := uint64(size) - uint64(segTrailerSize) - 16*n
+	index := make([]byte, 16*n)
+	if err := readFull(r, index, int64(pos)); err != nil {
+		return nil, 0, err
+	}
+	segs := make([]segment, n)
+	for i := range segs {
+		s := segment{binary.LittleEndian.Uint64(index[16*i:]), binary.LittleEndian.Uint64(index[16*i+8:])}
+		if s.off > pos || s.size > pos-s.off {
+			return nil, 0, corruptf(pos+16*uint64(i), "segment %d lies outside of the file", i)
+		}
+		segs[i] = s
+	}
+	return segs, pos, nil
+}
+
+// NewSegments returns a Stack of the segments of the segment file in r, of
+// the given size, with later segments overriding earlier ones. The options
+// apply to every segment, so TombstoneEmptyValues lets segments delete keys.
+func NewSegments(r io.ReaderAt, size int64, opts ...Option) (*Stack, error) {
+	segs, _, err := readSegmentIndex(r, size)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]Getter, len(segs))
+	for i, s := range segs {
+		layers[i] = New(io.NewSectionReader(r, int64(s.off), int64(s.size)), opts...)
+	}
+	return NewStack(layers...), nil
+}
+
+// OpenSegments opens the named segment file, like NewSegments. Closing the
+// Stack closes the file.
+func OpenSegments(name string, opts ...Option) (*Stack, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s, err := NewSegments(f, fi.Size(), opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if len(s.layers) == 0 {
+		// There is nothing left to read.
+		f.Close()
+		return s, nil
+	}
+	// The bottom segment closes the file when the Stack closes its layers.
+	s.layers[0].(*Cdb).closer = f
+	return s, nil
+}
+
+// offsetSeeker is an io.WriteSeeker writing to ws from base onwards, so that
+// positions are relative to base.
+type offsetSeeker struct {
+	ws   io.WriteSeeker
+	base int64
+}
+
+func (o *offsetSeeker) Write(p []byte) (int, error) {
+	return o.ws.Write(p)
+}
+
+func (o *offsetSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		offset += o.base
+	}
+	pos, err := o.ws.Seek(offset, whence)
+	return pos - o.base, err
+}
@@ -0,0 +1,61 @@
+package cdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSegments(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "segs")
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	put := func(kvs ...string) func(w *Writer) error {
+		return func(w *Writer) error {
+			for i := 0; i < len(kvs); i += 2 {
+				if err := w.Put([]byte(kvs[i]), []byte(kvs[i+1])); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if err := AppendSegment(f, put("a", "base", "b", "base", "c", "base")); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendSegment(f, put("b", "delta", "c", "")); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("failed")
+	if err := AppendSegment(f, func(w *Writer) error { return failed }); err != failed {
+		t.Fatalf("expected the build error, got: %v", err)
+	}
+
+	s, err := OpenSegments(name, TombstoneEmptyValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.layers) != 2 {
+		t.Fatalf("expected 2 segments, got: %d", len(s.layers))
+	}
+	for key, want := range map[string]string{"a": "base", "b": "delta"} {
+		if b, err := s.Bytes([]byte(key)); err != nil || string(b) != want {
+			t.Errorf("%s: expected %s, got: %q, %v", key, want, b, err)
+		}
+	}
+	if _, err := s.Bytes([]byte("c")); err != ErrNotFound {
+		t.Errorf("expected the tombstone to delete c, got: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(name, newDBBytes(records), 0644)
+	if _, err := OpenSegments(name); !errors.Is(err, ErrInvalidDatabase) {
+		t.Errorf("expected ErrInvalidDatabase for a plain database, got: %v", err)
+	}
+}
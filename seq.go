@@ -0,0 +1,74 @@
+//go:build go1.23
+
+package cdb
+
+import "iter"
+
+// All returns a sequence of every key and value in the database, in the order
+// they were written, for use with range:
+//
+//	for key, val := range db.All() {
+//		...
+//	}
+//
+// Like ForEachBytes, the slices are only valid until the next iteration.
+// Sequences can't return errors, so they stop early if a read fails. Use
+// ForEachBytes, ListKeys or Iterate to see the error.
+//
+// Threadsafe.
+func (c *Cdb) All() iter.Seq2[[]byte, []byte] {
+	return func(yield func(key, val []byte) bool) {
+		c.ForEachBytes(func(key, val []byte) error {
+			if !yield(key, val) {
+				return errStopScan
+			}
+			return nil
+		})
+	}
+}
+
+// Values returns a sequence of the values for key, in the order they were
+// written. Like Iterate, key shouldn't be modified while the sequence is in
+// use. Like All, it stops early if a read fails.
+//
+// Threadsafe.
+func (c *Cdb) Values(key []byte) iter.Seq[[]byte] {
+	return func(yield func(val []byte) bool) {
+		values := c.Iterate(key)
+		for {
+			val, err := values.NextBytes()
+			if err != nil || !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns a sequence of the distinct keys in the database, in the order
+// they were first written. The slices are only valid until the next
+// iteration. Like All, it stops early if a read fails.
+//
+// Threadsafe.
+func (c *Cdb) Keys() iter.Seq[[]byte] {
+	return func(yield func(key []byte) bool) {
+		var key []byte
+		c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+			if uint32(cap(key)) < klen {
+				key = make([]byte, klen)
+			}
+			key = key[:klen]
+			if err := readFull(c.r, key, int64(pos)+8); err != nil {
+				return err
+			}
+			if first, err := c.isFirstRecord(key, pos); err != nil {
+				return err
+			} else if !first {
+				return nil
+			}
+			if !yield(key) {
+				return errStopScan
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,49 @@
+//go:build go1.23
+
+package cdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeq(t *testing.T) {
+	db := newDB(records)
+	var all []string
+	for key, val := range db.All() {
+		all = append(all, string(key)+"="+string(val))
+	}
+	expected := []string{"one=1", "two=2", "two=22", "three=3", "three=33", "three=333"}
+	if !reflect.DeepEqual(all, expected) {
+		t.Errorf("All: expected %v, got: %v", expected, all)
+	}
+
+	var vals []string
+	for val := range db.Values([]byte("three")) {
+		vals = append(vals, string(val))
+	}
+	if expected := []string{"3", "33", "333"}; !reflect.DeepEqual(vals, expected) {
+		t.Errorf("Values: expected %v, got: %v", expected, vals)
+	}
+	for range db.Values([]byte("missing")) {
+		t.Errorf("Values: expected no values for a missing key")
+	}
+
+	var keys []string
+	for key := range db.Keys() {
+		keys = append(keys, string(key))
+	}
+	if expected := []string{"one", "two", "three"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Keys: expected %v, got: %v", expected, keys)
+	}
+
+	// Breaking out of a loop stops the scan.
+	n := 0
+	for range db.All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("expected break to stop All after 1 record, got: %d", n)
+	}
+}
@@ -0,0 +1,103 @@
+package cdb
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Scanner is implemented by the Getters whose records can be enumerated, like
+// Cdb, so that a Sharded made of them can be scanned with ForEachOrdered.
+type Scanner interface {
+	ForEachBytes(onRecordFn func(key, val []byte) error) error
+	AllValues(key []byte) ([][]byte, error)
+}
+
+// ScanOrder is the order ForEachOrdered visits the records of a Sharded in.
+// Both orders only depend on the contents of the shards, so scans of the same
+// shards, like exports, always give the same output.
+type ScanOrder int
+
+const (
+	// ShardOrder visits the shards in order, and the records of each shard
+	// in the order they were written.
+	ShardOrder ScanOrder = iota
+	// KeyOrder visits the keys of all shards sorted bytewise, and the values
+	// of each key in the order they were written. The keys are held in
+	// memory, but values are only read when they are visited.
+	KeyOrder
+)
+
+// ForEachOrdered calls onRecordFn for every key-val pair in every shard, in
+// the given order. Every shard must be a Scanner.
+//
+// The byte slices are only valid for the length of a call to onRecordFn.
+//
+// If onRecordFn returns an error, iteration will stop and the error will be
+// returned.
+//
+// Threadsafe.
+func (s *Sharded) ForEachOrdered(order ScanOrder, onRecordFn func(key, val []byte) error) error {
+	scanners := make([]Scanner, len(s.shards))
+	for i, shard := range s.shards {
+		scanner, ok := shard.(Scanner)
+		if !ok {
+			return fmt.Errorf("cdb: shard %d is a %T, which can't be scanned", i, shard)
+		}
+		scanners[i] = scanner
+	}
+	switch order {
+	case ShardOrder:
+		for _, scanner := range scanners {
+			if err := scanner.ForEachBytes(onRecordFn); err != nil {
+				return err
+			}
+		}
+		return nil
+	case KeyOrder:
+		return forEachByKey(scanners, onRecordFn)
+	}
+	return fmt.Errorf("cdb: unknown ScanOrder %d", order)
+}
+
+// forEachByKey calls onRecordFn for the records of the scanners in KeyOrder.
+func forEachByKey(scanners []Scanner, onRecordFn func(key, val []byte) error) error {
+	type shardKey struct {
+		key   string
+		shard int
+	}
+	var keys []shardKey
+	for i, scanner := range scanners {
+		seen := make(map[string]bool)
+		err := scanner.ForEachBytes(func(key, val []byte) error {
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				keys = append(keys, shardKey{string(key), i})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// A key is normally only in one shard, but if it is in several, visit
+	// them in shard order.
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].key != keys[j].key {
+			return keys[i].key < keys[j].key
+		}
+		return keys[i].shard < keys[j].shard
+	})
+	for _, k := range keys {
+		key := []byte(k.key)
+		vals, err := scanners[k.shard].AllValues(key)
+		if err != nil {
+			return err
+		}
+		for _, val := range vals {
+			if err := onRecordFn(key, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
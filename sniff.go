@@ -0,0 +1,149 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/rand"
+	"unicode/utf8"
+)
+
+// ValueEncoding is a guess at how a value is encoded.
+type ValueEncoding int
+
+const (
+	EncodingBinary   ValueEncoding = iota // None of the others.
+	EncodingEmpty                         // Zero length.
+	EncodingGzip                          // Starts with the gzip magic number.
+	EncodingJSON                          // Valid JSON.
+	EncodingText                          // UTF-8 without control characters other than whitespace.
+	EncodingProtobuf                      // Parses as protocol buffer wire format.
+)
+
+var encodingNames = [...]string{"binary", "empty", "gzip", "json", "text", "protobuf"}
+
+func (e ValueEncoding) String() string {
+	if e < 0 || int(e) >= len(encodingNames) {
+		return "unknown"
+	}
+	return encodingNames[e]
+}
+
+// SniffValue guesses how val is encoded. Checks are made in the order of the
+// ValueEncoding constants, so a JSON string is reported as JSON rather than
+// text, and text is never reported as protobuf.
+func SniffValue(val []byte) ValueEncoding {
+	switch {
+	case len(val) == 0:
+		return EncodingEmpty
+	case len(val) >= 2 && val[0] == 0x1f && val[1] == 0x8b:
+		return EncodingGzip
+	case json.Valid(val):
+		return EncodingJSON
+	case isText(val):
+		return EncodingText
+	case isProtobuf(val):
+		return EncodingProtobuf
+	}
+	return EncodingBinary
+}
+
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range b {
+		if c < ' ' && c != '\t' && c != '\n' && c != '\r' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// isProtobuf returns true if b is a sequence of well formed protocol buffer
+// fields. Embedded messages aren't checked.
+func isProtobuf(b []byte) bool {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag>>3 == 0 {
+			return false
+		}
+		b = b[n:]
+		switch tag & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return false
+			}
+			b = b[n:]
+		case 1: // 64 bit
+			if len(b) < 8 {
+				return false
+			}
+			b = b[8:]
+		case 2: // length delimited
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return false
+			}
+			b = b[n+int(l):]
+		case 5: // 32 bit
+			if len(b) < 4 {
+				return false
+			}
+			b = b[4:]
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SniffReport counts the encodings of a sample of values.
+type SniffReport struct {
+	// Records is the number of records in the database.
+	Records int
+	// Sampled is the number of values sniffed.
+	Sampled int
+	Counts  map[ValueEncoding]int
+}
+
+// SniffValues guesses the encoding of up to n values picked at random from
+// the whole database. Values are sniffed as stored, before any
+// ValueTransform.
+//
+// Threadsafe.
+func (c *Cdb) SniffValues(n int) (*SniffReport, error) {
+	type span struct {
+		pos uint64
+		len uint32
+	}
+	var sample []span
+	rnd := rand.New(rand.NewSource(1))
+	report := &SniffReport{Counts: make(map[ValueEncoding]int)}
+	// Reservoir sample the value positions, then read just those values.
+	err := c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		s := span{pos + 8 + uint64(klen), dlen}
+		report.Records++
+		if len(sample) < n {
+			sample = append(sample, s)
+		} else if i := rnd.Intn(report.Records); i < n {
+			sample[i] = s
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	for _, s := range sample {
+		if uint32(cap(buf)) < s.len {
+			buf = make([]byte, s.len)
+		}
+		buf = buf[:s.len]
+		if err := readFull(c.r, buf, int64(s.pos)); err != nil {
+			return nil, err
+		}
+		report.Counts[SniffValue(buf)]++
+	}
+	report.Sampled = len(sample)
+	return report, nil
+}
@@ -0,0 +1,60 @@
+package cdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func TestSniffValue(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("hello"))
+	zw.Close()
+
+	for _, tc := range []struct {
+		val      []byte
+		expected ValueEncoding
+	}{
+		{nil, EncodingEmpty},
+		{gz.Bytes(), EncodingGzip},
+		{[]byte(`{"a":[1,2]}`), EncodingJSON},
+		{[]byte("42"), EncodingJSON},
+		{[]byte("hello world\n"), EncodingText},
+		{[]byte{0x08, 0x96, 0x01, 0x12, 0x02, 0xff, 0xfe}, EncodingProtobuf},
+		{[]byte{0x00, 0xff, 0x07}, EncodingBinary},
+	} {
+		if e := SniffValue(tc.val); e != tc.expected {
+			t.Errorf("%q: expected %v, got: %v", tc.val, tc.expected, e)
+		}
+	}
+}
+
+func TestSniffValues(t *testing.T) {
+	db := New(bytes.NewReader(newDBBytes([]rec{
+		{"a", []string{`{"x":1}`, `[true]`}},
+		{"b", []string{"plain text"}},
+		{"c", []string{""}},
+	})))
+	report, err := db.SniffValues(10)
+	if err != nil {
+		t.Fatalf("SniffValues error: %v", err)
+	}
+	expected := &SniffReport{
+		Records: 4,
+		Sampled: 4,
+		Counts:  map[ValueEncoding]int{EncodingJSON: 2, EncodingText: 1, EncodingEmpty: 1},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, report)
+	}
+
+	report, err = db.SniffValues(2)
+	if err != nil {
+		t.Fatalf("SniffValues error: %v", err)
+	}
+	if report.Records != 4 || report.Sampled != 2 {
+		t.Errorf("expected 2 of 4 records sampled, got: %+v", report)
+	}
+}
@@ -0,0 +1,226 @@
+package cdb
+
+import (
+	"errors"
+	"hash/fnv"
+	"io"
+	"sync"
+)
+
+// Getter is the read interface shared by Cdb and the readers combining
+// several databases.
+type Getter interface {
+	Exists(key []byte) (bool, error)
+	Bytes(key []byte) ([]byte, error)
+	Reader(key []byte) (*io.SectionReader, error)
+	Close() error
+}
+
+// Stack looks keys up in a series of layers, such as a base database and the
+// deltas built on top of it since, returning the value from the last layer
+// that has the key. Layers opened with TombstoneEmptyValues can delete keys
+// from the layers below.
+//
+// Threadsafe.
+type Stack struct {
+	// mu guards layers, which Swap changes.
+	mu     sync.RWMutex
+	layers []Getter
+	// cache holds the layer that answered for each key. May be nil.
+	cache *stackCache
+}
+
+// NewStack returns a Stack of the layers, from the bottom up, so later layers
+// override earlier ones.
+func NewStack(layers ...Getter) *Stack {
+	return &Stack{layers: append([]Getter(nil), layers...)}
+}
+
+// NewCachedStack is like NewStack, but remembers which layer answered for up
+// to size keys, so repeated lookups skip the layers above it that don't have
+// the key. Concurrent lookups of a key that isn't cached share the work of
+// finding its layer. The cache is emptied by Swap, or by InvalidateCache if a
+// layer starts reading different data some other way.
+func NewCachedStack(size int, layers ...Getter) *Stack {
+	s := NewStack(layers...)
+	if size > 0 {
+		s.cache = newStackCache(size)
+	}
+	return s
+}
+
+// Swap replaces layer i with layer, and returns the layer it replaced, which
+// the caller should close once it is done with it. Lookups already in progress
+// finish before the swap.
+func (s *Stack) Swap(i int, layer Getter) Getter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.layers[i]
+	s.layers[i] = layer
+	if s.cache != nil {
+		s.cache.clear()
+	}
+	return old
+}
+
+// InvalidateCache empties the cache of a Stack made by NewCachedStack.
+// Lookups already in progress finish first, so none of them caches a layer
+// found before the call.
+func (s *Stack) InvalidateCache() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache != nil {
+		s.cache.clear()
+	}
+}
+
+// resolve returns the topmost layer that has key, or -1 if none do. The
+// caller must hold s.mu.
+func (s *Stack) resolve(key []byte) (int, error) {
+	return s.cache.resolve(key, func() (int, error) {
+		for i := len(s.layers) - 1; i >= 0; i-- {
+			if ok, err := s.layers[i].Exists(key); err != nil {
+				return -1, err
+			} else if ok {
+				return i, nil
+			}
+			if del, err := deleted(s.layers[i], key); del || err != nil {
+				return -1, err
+			}
+		}
+		return -1, nil
+	})
+}
+
+// Exists returns true if any layer has the key.
+func (s *Stack) Exists(key []byte) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cache != nil {
+		i, err := s.resolve(key)
+		return i >= 0, err
+	}
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		if ok, err := s.layers[i].Exists(key); ok || err != nil {
+			return ok, err
+		}
+		if del, err := deleted(s.layers[i], key); del || err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// Bytes returns the first value for key in the topmost layer that has it.
+// Returns ErrNotFound when no layer has a value. Layers may report a missing
+// key with any error matching io.EOF.
+func (s *Stack) Bytes(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cache != nil {
+		i, err := s.resolve(key)
+		if err != nil || i < 0 {
+			return nil, notFound(err)
+		}
+		return s.layers[i].Bytes(key)
+	}
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		if b, err := s.layers[i].Bytes(key); !errors.Is(err, io.EOF) {
+			return b, err
+		}
+		if del, err := deleted(s.layers[i], key); del || err != nil {
+			return nil, notFound(err)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Reader is like Bytes, but returns the value as an io.SectionReader.
+func (s *Stack) Reader(key []byte) (*io.SectionReader, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cache != nil {
+		i, err := s.resolve(key)
+		if err != nil || i < 0 {
+			return nil, notFound(err)
+		}
+		return s.layers[i].Reader(key)
+	}
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		if r, err := s.layers[i].Reader(key); !errors.Is(err, io.EOF) {
+			return r, err
+		}
+		if del, err := deleted(s.layers[i], key); del || err != nil {
+			return nil, notFound(err)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// notFound returns err, or ErrNotFound if it is nil.
+func notFound(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrNotFound
+}
+
+// Close closes every layer, returning the first error.
+func (s *Stack) Close() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return closeAll(s.layers)
+}
+
+// Sharded looks keys up in one of a fixed set of shards, chosen by ShardFor.
+//
+// Threadsafe.
+type Sharded struct {
+	shards []Getter
+}
+
+// NewSharded returns a Sharded reading from shards, where shards[i] holds the
+// keys for which ShardFor(key, len(shards)) is i.
+func NewSharded(shards ...Getter) *Sharded {
+	return &Sharded{shards: shards}
+}
+
+// ShardFor returns the shard, out of n, that key belongs in. It uses a
+// different hash to the one used inside databases, so every shard's keys are
+// spread over all of its hash tables.
+func ShardFor(key []byte, n int) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(n))
+}
+
+func (s *Sharded) shard(key []byte) Getter {
+	return s.shards[ShardFor(key, len(s.shards))]
+}
+
+func (s *Sharded) Exists(key []byte) (bool, error) {
+	return s.shard(key).Exists(key)
+}
+
+func (s *Sharded) Bytes(key []byte) ([]byte, error) {
+	return s.shard(key).Bytes(key)
+}
+
+func (s *Sharded) Reader(key []byte) (*io.SectionReader, error) {
+	return s.shard(key).Reader(key)
+}
+
+// Close closes every shard, returning the first error.
+func (s *Sharded) Close() error {
+	return closeAll(s.shards)
+}
+
+func closeAll(gs []Getter) error {
+	var err error
+	for _, g := range gs {
+		if cerr := g.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+package cdb
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingGetter counts the lookups that reach a layer.
+type countingGetter struct {
+	Getter
+	lookups int32
+}
+
+func (g *countingGetter) Exists(key []byte) (bool, error) {
+	atomic.AddInt32(&g.lookups, 1)
+	return g.Getter.Exists(key)
+}
+
+func (g *countingGetter) Bytes(key []byte) ([]byte, error) {
+	atomic.AddInt32(&g.lookups, 1)
+	return g.Getter.Bytes(key)
+}
+
+func TestCachedStack(t *testing.T) {
+	base := newDB([]rec{{"a", []string{"base"}}, {"b", []string{"base"}}})
+	top := &countingGetter{Getter: newDB([]rec{{"b", []string{"top"}}})}
+	s := NewCachedStack(16, base, top)
+
+	for i := 0; i < 3; i++ {
+		if b, err := s.Bytes([]byte("a")); err != nil || string(b) != "base" {
+			t.Fatalf("expected base, got: %q, %v", b, err)
+		}
+	}
+	if top.lookups != 1 {
+		t.Errorf("expected the top layer to be probed once, got: %d", top.lookups)
+	}
+	if b, err := s.Bytes([]byte("b")); err != nil || string(b) != "top" {
+		t.Errorf("expected top, got: %q, %v", b, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.Bytes([]byte("missing")); err != ErrNotFound {
+			t.Errorf("expected ErrNotFound, got: %v", err)
+		}
+	}
+	if ok, err := s.Exists([]byte("missing")); ok || err != nil {
+		t.Errorf("expected a miss, got: %v, %v", ok, err)
+	}
+
+	// Swapping a layer empties the cache.
+	old := s.Swap(1, newDB([]rec{{"a", []string{"new"}}}))
+	if old != top {
+		t.Errorf("expected Swap to return the old layer")
+	}
+	if b, err := s.Bytes([]byte("a")); err != nil || string(b) != "new" {
+		t.Errorf("expected new after the swap, got: %q, %v", b, err)
+	}
+}
+
+// blockingGetter holds Exists until release is closed.
+type blockingGetter struct {
+	countingGetter
+	release chan struct{}
+}
+
+func (g *blockingGetter) Exists(key []byte) (bool, error) {
+	<-g.release
+	return g.countingGetter.Exists(key)
+}
+
+func TestCachedStackCoalesces(t *testing.T) {
+	top := &blockingGetter{countingGetter{Getter: newDB(records)}, make(chan struct{})}
+	s := NewCachedStack(16, top)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := s.Exists([]byte("one")); !ok || err != nil {
+				t.Errorf("expected one to exist, got: %v, %v", ok, err)
+			}
+		}()
+	}
+	close(top.release)
+	wg.Wait()
+	// The lookups that started before the first one finished shared it, and
+	// the rest were cached.
+	if top.lookups != 1 {
+		t.Errorf("expected 1 lookup, got: %d", top.lookups)
+	}
+}
+
+// answerGetter answers Exists with has as it was when the lookup started,
+// after signalling entered and waiting for release.
+type answerGetter struct {
+	Getter
+	has     atomic.Bool
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (g *answerGetter) Exists(key []byte) (bool, error) {
+	has := g.has.Load()
+	select {
+	case g.entered <- struct{}{}:
+	default:
+	}
+	<-g.release
+	return has, nil
+}
+
+func TestCachedStackInvalidateDuringLookup(t *testing.T) {
+	layer := &answerGetter{Getter: newDB(nil), entered: make(chan struct{}, 1), release: make(chan struct{})}
+	s := NewCachedStack(16, layer)
+	looked := make(chan struct{})
+	go func() {
+		defer close(looked)
+		s.Exists([]byte("one"))
+	}()
+	<-layer.entered
+
+	// The layer gains the key while the lookup is in progress, and the cache
+	// is invalidated for it.
+	layer.has.Store(true)
+	invalidated := make(chan struct{})
+	go func() {
+		defer close(invalidated)
+		s.InvalidateCache()
+	}()
+	select {
+	case <-invalidated:
+		t.Error("expected InvalidateCache to wait for the lookup in progress")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(layer.release)
+	<-looked
+	<-invalidated
+	if ok, err := s.Exists([]byte("one")); !ok || err != nil {
+		t.Errorf("expected one to exist after InvalidateCache, got: %v, %v", ok, err)
+	}
+}
+
+func TestShardedForEachOrdered(t *testing.T) {
+	recs := make([][]rec, 3)
+	for _, r := range append(records, rec{"four", []string{"4"}}, rec{"five", []string{"5", "55"}}) {
+		i := ShardFor([]byte(r.key), len(recs))
+		recs[i] = append(recs[i], r)
+	}
+	var shards []Getter
+	var byShard []string
+	for _, shardRecs := range recs {
+		shards = append(shards, newDB(shardRecs))
+		for _, r := range shardRecs {
+			for _, val := range r.values {
+				byShard = append(byShard, r.key+"="+val)
+			}
+		}
+	}
+	s := NewSharded(shards...)
+
+	scan := func(order ScanOrder) []string {
+		var out []string
+		if err := s.ForEachOrdered(order, func(key, val []byte) error {
+			out = append(out, string(key)+"="+string(val))
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	if got := scan(ShardOrder); !reflect.DeepEqual(got, byShard) {
+		t.Errorf("ShardOrder: expected %v, got: %v", byShard, got)
+	}
+	byKey := []string{"five=5", "five=55", "four=4", "one=1", "three=3", "three=33", "three=333", "two=2", "two=22"}
+	if got := scan(KeyOrder); !reflect.DeepEqual(got, byKey) {
+		t.Errorf("KeyOrder: expected %v, got: %v", byKey, got)
+	}
+
+	if err := NewSharded(NewStack()).ForEachOrdered(ShardOrder, nil); err == nil {
+		t.Errorf("expected an error scanning a shard that isn't a Scanner")
+	}
+}
@@ -0,0 +1,88 @@
+package cdb
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// stackCache remembers which layer of a Stack answered for each key, so
+// repeated lookups go straight to it instead of missing in every layer above.
+// It is direct mapped, like negCache, but keeps the keys, since a Stack's
+// layers can use any hash. Concurrent lookups of a key that isn't cached share
+// one resolution.
+//
+// Threadsafe. Lookups of cached keys are lock-free.
+type stackCache struct {
+	entries []atomic.Pointer[stackEntry]
+
+	mu sync.Mutex
+	// calls holds the resolutions in progress, by key.
+	calls map[string]*stackCall
+}
+
+// stackEntry is the layer that answered for a key, or -1 if none did.
+type stackEntry struct {
+	key   string
+	layer int
+}
+
+// stackCall is a resolution in progress, which other lookups of the key wait
+// for.
+type stackCall struct {
+	done  chan struct{}
+	layer int
+	err   error
+}
+
+func newStackCache(size int) *stackCache {
+	return &stackCache{entries: make([]atomic.Pointer[stackEntry], size), calls: make(map[string]*stackCall)}
+}
+
+func (sc *stackCache) index(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(len(sc.entries)))
+}
+
+// get returns the cached layer for key, and whether there was one.
+func (sc *stackCache) get(key []byte) (int, bool) {
+	e := sc.entries[sc.index(key)].Load()
+	if e == nil || e.key != string(key) {
+		return 0, false
+	}
+	return e.layer, true
+}
+
+// resolve returns the cached layer for key, or calls fn to find it, once for
+// all the concurrent callers, and caches the result.
+func (sc *stackCache) resolve(key []byte, fn func() (int, error)) (int, error) {
+	if layer, ok := sc.get(key); ok {
+		return layer, nil
+	}
+	sc.mu.Lock()
+	if call, ok := sc.calls[string(key)]; ok {
+		sc.mu.Unlock()
+		<-call.done
+		return call.layer, call.err
+	}
+	call := &stackCall{done: make(chan struct{})}
+	sc.calls[string(key)] = call
+	sc.mu.Unlock()
+
+	call.layer, call.err = fn()
+	if call.err == nil {
+		sc.entries[sc.index(key)].Store(&stackEntry{string(key), call.layer})
+	}
+	sc.mu.Lock()
+	delete(sc.calls, string(key))
+	sc.mu.Unlock()
+	close(call.done)
+	return call.layer, call.err
+}
+
+func (sc *stackCache) clear() {
+	for i := range sc.entries {
+		sc.entries[i].Store(nil)
+	}
+}
@@ -0,0 +1,88 @@
+package cdb
+
+// TableStats describes one of the 256 hash tables of a database.
+type TableStats struct {
+	// Slots is the size of the table, and Used the number of its slots that
+	// point to records.
+	Slots, Used int
+	// Fill is Used divided by Slots, or 0 for an empty table.
+	Fill float64
+	// LongestProbe is the most slots a lookup of a key in the table reads to
+	// find the key.
+	LongestProbe int
+}
+
+// Stats describes the layout of a database, like the cdbstats program of the
+// original cdb distribution.
+type Stats struct {
+	// Records is the number of records, and KeyBytes and ValueBytes the
+	// total size of their keys and values, as stored.
+	Records    int64
+	KeyBytes   int64
+	ValueBytes int64
+	// Tables describes each hash table.
+	Tables [256]TableStats
+	// LongestProbe is the longest probe of any table.
+	LongestProbe int
+	// Distances counts the records by how many slots past the slot their
+	// hash picks they are stored, from 0 to 9, with the last entry counting
+	// those 10 or more slots away. A lookup of a record reads its distance
+	// plus one slots.
+	Distances [11]int64
+}
+
+// Stats reads the hash tables and the record headers, but no keys or values,
+// and returns statistics about them, for diagnosing slow lookups caused by
+// badly distributed keys, or databases that are larger than expected.
+//
+// Threadsafe.
+func (c *Cdb) Stats() (Stats, error) {
+	var stats Stats
+	err := c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		stats.Records++
+		stats.KeyBytes += int64(klen)
+		stats.ValueBytes += int64(dlen)
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	lay, err := c.layout()
+	if err != nil {
+		return Stats{}, err
+	}
+	buf := make([]byte, 16)
+	for i := range stats.Tables {
+		hpos, hslots, err := lay.readTable(c.r, buf, uint64(i))
+		if err != nil {
+			return Stats{}, err
+		}
+		ts := &stats.Tables[i]
+		ts.Slots = int(hslots)
+		err = lay.forEachSlot(c.r, hpos, hslots, func(n, h, pos uint64) error {
+			if pos == 0 {
+				return nil
+			}
+			ts.Used++
+			distance := (n + hslots - uint64(uint32(h)/256)%hslots) % hslots
+			if probe := int(distance) + 1; probe > ts.LongestProbe {
+				ts.LongestProbe = probe
+			}
+			if distance >= uint64(len(stats.Distances)-1) {
+				distance = uint64(len(stats.Distances) - 1)
+			}
+			stats.Distances[distance]++
+			return nil
+		})
+		if err != nil {
+			return Stats{}, err
+		}
+		if hslots > 0 {
+			ts.Fill = float64(ts.Used) / float64(hslots)
+		}
+		if ts.LongestProbe > stats.LongestProbe {
+			stats.LongestProbe = ts.LongestProbe
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,75 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	stats, err := newDB(records).Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 6 || stats.KeyBytes != 3+3*2+5*3 || stats.ValueBytes != 1+1+2+1+2+3 {
+		t.Errorf("expected 6 records of 24 key and 10 value bytes, got: %+v", stats)
+	}
+	var used, slots int
+	var distances int64
+	for _, ts := range stats.Tables {
+		used += ts.Used
+		slots += ts.Slots
+		if ts.Slots > 0 && ts.Fill != 0.5 {
+			t.Errorf("expected tables half full, got: %+v", ts)
+		}
+	}
+	for _, n := range stats.Distances {
+		distances += n
+	}
+	if used != 6 || slots != 12 || distances != 6 {
+		t.Errorf("expected 6 of 12 slots used, got: %d of %d, with %d distances", used, slots, distances)
+	}
+}
+
+func TestStatsCollisions(t *testing.T) {
+	// Every key has the same hash, so the records form one long chain.
+	same := func(key []byte) uint32 { return 42 }
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp, Hash(same))
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if err := w.Write([]byte(key), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := New(bytes.NewReader(b)).Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.LongestProbe != 4 || stats.Tables[42].LongestProbe != 4 {
+		t.Errorf("expected a longest probe of 4, got: %d", stats.LongestProbe)
+	}
+	if expected := [11]int64{1, 1, 1, 1}; stats.Distances != expected {
+		t.Errorf("expected distances %v, got: %v", expected, stats.Distances)
+	}
+}
+
+func TestStatsHugeTable(t *testing.T) {
+	b := newDBBytes(records)
+	putNum(b[4:], 0x7fffffff)
+	if _, err := New(bytes.NewReader(b)).Stats(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotCanonical is returned by reads from a database opened with
+// StrictCompat that isn't a plain cdb file.
+var ErrNotCanonical = errors.New("not a canonical cdb file")
+
+// StrictCompat returns an Option that refuses databases with anything after
+// the hash tables, such as the extension sections written for annotations,
+// or a trailer added by another tool. Without it such data is ignored, or
+// used if it is understood. The check is made on the first read, and if it
+// fails all reads return an error wrapping ErrNotCanonical, so pipelines
+// can guarantee they only handle files that the original cdb tools read
+// identically.
+func StrictCompat() Option {
+	return func(c *Cdb) {
+		c.wrappers = append(c.wrappers, func(r io.ReaderAt) io.ReaderAt {
+			return &strictGuard{r: r}
+		})
+	}
+}
+
+// strictGuard is an io.ReaderAt that fails if the reader it wraps has data
+// after the hash tables.
+type strictGuard struct {
+	r io.ReaderAt
+	// checked is set to 1, after err, once the check has passed or found data
+	// after the hash tables. A check that fails to read isn't kept, so a
+	// lazily opened file that doesn't exist yet is checked again.
+	checked uint32
+	mu      sync.Mutex
+	err     error
+}
+
+func (g *strictGuard) ReadAt(p []byte, off int64) (int, error) {
+	if err := g.check(); err != nil {
+		return 0, err
+	}
+	return g.r.ReadAt(p, off)
+}
+
+// check returns an error wrapping ErrNotCanonical if there is data after
+// the hash tables, or the error that stopped it looking.
+//
+// Threadsafe.
+func (g *strictGuard) check() error {
+	if atomic.LoadUint32(&g.checked) == 1 {
+		return g.err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.checked == 0 {
+		end, err := tablesEnd(g.r)
+		if err != nil {
+			return underlying(err)
+		}
+		var b [1]byte
+		n, err := g.r.ReadAt(b[:], int64(end))
+		if n > 0 {
+			g.err = fmt.Errorf("%w: data after the hash tables at %d", ErrNotCanonical, end)
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+		atomic.StoreUint32(&g.checked, 1)
+	}
+	return g.err
+}
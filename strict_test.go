@@ -0,0 +1,51 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStrictCompat(t *testing.T) {
+	plain := newDBBytes(records)
+	db := New(bytes.NewReader(plain), StrictCompat())
+	if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected a plain database to be read, got: %q, %v", b, err)
+	}
+
+	trailer := append(append([]byte(nil), plain...), "trailer"...)
+	if _, err := New(bytes.NewReader(trailer)).Bytes([]byte("one")); err != nil {
+		t.Errorf("expected the trailer to be ignored without StrictCompat, got: %v", err)
+	}
+	db = New(bytes.NewReader(trailer), StrictCompat())
+	for i := 0; i < 2; i++ {
+		if _, err := db.Bytes([]byte("one")); !errors.Is(err, ErrNotCanonical) {
+			t.Errorf("expected ErrNotCanonical, got: %v", err)
+		}
+	}
+}
+
+func TestStrictCompatLazyOpen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.cdb")
+	db := LazyOpen(name, StrictCompat())
+	defer db.Close()
+	_, err := db.Bytes([]byte("one"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error before the file appears, got: %v", err)
+	}
+	if n := strings.Count(err.Error(), "read at"); n != 1 {
+		t.Errorf("expected the error to be wrapped once, got: %v", err)
+	}
+
+	// The failed check isn't remembered.
+	if err := os.WriteFile(name, newDBBytes(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := db.Bytes([]byte("one")); err != nil || string(b) != "1" {
+		t.Errorf("expected the file to be read once it appears, got: %q, %v", b, err)
+	}
+}
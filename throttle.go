@@ -0,0 +1,115 @@
+package cdb
+
+import (
+	"io"
+	"runtime"
+	"time"
+)
+
+// RateLimit returns a WriterOption that limits the rate the database is
+// written at to bytesPerSec, so rebuilding a large database on a host that is
+// serving lookups doesn't starve their reads of IO bandwidth.
+func RateLimit(bytesPerSec int64) WriterOption {
+	return func(w *Writer) {
+		w.rateLimit = bytesPerSec
+	}
+}
+
+// LowIOPriority returns a WriterOption that builds the database with the idle
+// IO priority class, like ionice -c3, so its IO only uses disk time that
+// nothing else wants. It only has an effect on Linux, and is ignored if the
+// priority can't be set.
+func LowIOPriority() WriterOption {
+	return func(w *Writer) {
+		w.lowIOPriority = true
+	}
+}
+
+// throttledWriter is an io.WriteSeeker that sleeps after writes to keep the
+// average rate since the first write under rate bytes per second.
+type throttledWriter struct {
+	io.WriteSeeker
+	rate    int64
+	start   time.Time
+	written int64
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+	n, err := t.WriteSeeker.Write(p)
+	t.written += int64(n)
+	due := time.Duration(float64(t.written) / float64(t.rate) * float64(time.Second))
+	if wait := due - time.Since(t.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
+
+// Sync passes syncs on, so Writer.Flush can still sync the output.
+func (t *throttledWriter) Sync() error {
+	if s, ok := t.WriteSeeker.(interface {
+		Sync() error
+	}); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// ioThread is an io.WriteSeeker that does all of its IO on one goroutine
+// locked to an OS thread, for settings that are per thread, like IO priority.
+type ioThread struct {
+	ws  io.WriteSeeker
+	ops chan func()
+}
+
+// newIOThread starts the thread for ws, calling setup on it first. Errors
+// from setup are ignored.
+func newIOThread(ws io.WriteSeeker, setup func() error) *ioThread {
+	t := &ioThread{ws: ws, ops: make(chan func())}
+	go func() {
+		runtime.LockOSThread()
+		// The thread exits with the goroutine, so its settings don't leak.
+		setup()
+		for op := range t.ops {
+			op()
+		}
+	}()
+	return t
+}
+
+// do runs op on the thread and waits for it.
+func (t *ioThread) do(op func()) {
+	done := make(chan struct{})
+	t.ops <- func() {
+		op()
+		close(done)
+	}
+	<-done
+}
+
+func (t *ioThread) Write(p []byte) (n int, err error) {
+	t.do(func() { n, err = t.ws.Write(p) })
+	return
+}
+
+func (t *ioThread) Seek(offset int64, whence int) (n int64, err error) {
+	t.do(func() { n, err = t.ws.Seek(offset, whence) })
+	return
+}
+
+// Sync passes syncs on, like throttledWriter.
+func (t *ioThread) Sync() (err error) {
+	if s, ok := t.ws.(interface {
+		Sync() error
+	}); ok {
+		t.do(func() { err = s.Sync() })
+	}
+	return
+}
+
+// close stops the thread.
+func (t *ioThread) close() {
+	close(t.ops)
+}
@@ -0,0 +1,35 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	start := time.Now()
+	w := NewWriter(tmp, RateLimit(100<<10), LowIOPriority())
+	val := []byte(strings.Repeat("v", 10<<10))
+	for _, key := range []string{"a", "b"} {
+		if err := w.Write([]byte(key), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Over 20KB at 100KB/s.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected writing to take at least 150ms, took: %v", elapsed)
+	}
+	checkRecords(t, New(tmp), []rec{{"a", []string{string(val)}}, {"b", []string{string(val)}}})
+}
@@ -0,0 +1,84 @@
+package cdb
+
+// TombstoneEmptyValues returns an Option that treats empty values as
+// tombstones, for pipelines that mark deleted keys with them. Lookups and
+// iterators skip empty values, so a key whose values are all empty isn't
+// found. Scans like ForEachBytes still see them, so tombstones can be carried
+// into merged databases.
+//
+// In a Stack, a layer opened with the option that has only tombstones for a
+// key hides the key's values in the layers below it, so a delta can delete
+// keys from its base. Write tombstones with Writer.Tombstone.
+func TombstoneEmptyValues() Option {
+	return func(c *Cdb) {
+		c.tombstones = true
+	}
+}
+
+// Tombstone writes a tombstone for key: an empty value, which readers opened
+// with TombstoneEmptyValues take to mean the key is deleted.
+func (w *Writer) Tombstone(key []byte) error {
+	return w.Put(key, nil)
+}
+
+// tombstoner is implemented by readers that can tell a key they have been
+// told is deleted from one they don't have.
+type tombstoner interface {
+	// deleted returns true if the reader has only tombstones for key.
+	deleted(key []byte) (bool, error)
+}
+
+// deleted returns true if g has only tombstones for key.
+func deleted(g Getter, key []byte) (bool, error) {
+	if t, ok := g.(tombstoner); ok {
+		return t.deleted(key)
+	}
+	return false, nil
+}
+
+func (c *Cdb) deleted(key []byte) (bool, error) {
+	if !c.tombstones {
+		return false, nil
+	}
+	iter := c.getIter(key)
+	defer putIter(iter)
+	return iter.deleted()
+}
+
+// deleted returns true if the iterator's key has only tombstones, reading
+// its records from the start.
+func (iter *CdbIterator) deleted() (bool, error) {
+	found := false
+	for {
+		err := iter.next()
+		if err == ErrNotFound {
+			return found, nil
+		} else if err != nil {
+			return false, err
+		}
+		if iter.dlen > 0 {
+			return false, nil
+		}
+		found = true
+	}
+}
+
+// deleted returns true if the topmost layer that has key, or a tombstone for
+// it, has a tombstone.
+func (s *Stack) deleted(key []byte) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		if ok, err := s.layers[i].Exists(key); ok || err != nil {
+			return false, err
+		}
+		if del, err := deleted(s.layers[i], key); del || err != nil {
+			return del, err
+		}
+	}
+	return false, nil
+}
+
+func (s *Sharded) deleted(key []byte) (bool, error) {
+	return deleted(s.shard(key), key)
+}
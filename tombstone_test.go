@@ -0,0 +1,83 @@
+package cdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTombstoneEmptyValues(t *testing.T) {
+	bw := NewBufferWriter()
+	bw.Put([]byte("live"), []byte("1"))
+	bw.Tombstone([]byte("dead"))
+	bw.Put([]byte("mixed"), nil)
+	bw.Put([]byte("mixed"), []byte("2"))
+	data, err := bw.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := New(bytes.NewReader(data))
+	if ok, err := plain.Exists([]byte("dead")); !ok || err != nil {
+		t.Errorf("expected an empty value without the option, got: %v, %v", ok, err)
+	}
+
+	db := New(bytes.NewReader(data), TombstoneEmptyValues())
+	if ok, err := db.Exists([]byte("dead")); ok || err != nil {
+		t.Errorf("expected a tombstoned key to be missing, got: %v, %v", ok, err)
+	}
+	if _, err := db.Bytes([]byte("dead")); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if b, err := db.Bytes([]byte("mixed")); err != nil || string(b) != "2" {
+		t.Errorf("expected the tombstone to be skipped, got: %q, %v", b, err)
+	}
+	if b, err := db.Bytes([]byte("live")); err != nil || string(b) != "1" {
+		t.Errorf("expected 1, got: %q, %v", b, err)
+	}
+}
+
+func TestStackTombstones(t *testing.T) {
+	base := newDB([]rec{{"a", []string{"base"}}, {"b", []string{"base"}}})
+	bw := NewBufferWriter()
+	bw.Tombstone([]byte("a"))
+	data, err := bw.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	delta := New(bytes.NewReader(data), TombstoneEmptyValues())
+
+	for _, s := range []*Stack{NewStack(base, delta), NewCachedStack(16, base, delta)} {
+		if _, err := s.Bytes([]byte("a")); err != ErrNotFound {
+			t.Errorf("expected the delta to delete a, got: %v", err)
+		}
+		if _, err := s.Reader([]byte("a")); err != ErrNotFound {
+			t.Errorf("expected the delta to delete a, got: %v", err)
+		}
+		if ok, err := s.Exists([]byte("a")); ok || err != nil {
+			t.Errorf("expected a miss, got: %v, %v", ok, err)
+		}
+		if b, err := s.Bytes([]byte("b")); err != nil || string(b) != "base" {
+			t.Errorf("expected base, got: %q, %v", b, err)
+		}
+	}
+
+	// A layer above the tombstone brings the key back.
+	top := newDB([]rec{{"a", []string{"top"}}})
+	if b, err := NewStack(base, delta, top).Bytes([]byte("a")); err != nil || string(b) != "top" {
+		t.Errorf("expected top, got: %q, %v", b, err)
+	}
+
+	p, err := NewLookupPlan(NewStack(base, delta), [][]byte{[]byte("a"), []byte("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Bytes(0); err != ErrNotFound {
+		t.Errorf("expected the plan to honor the tombstone, got: %v", err)
+	}
+	if ok, err := p.Exists(0); ok || err != nil {
+		t.Errorf("expected a miss, got: %v, %v", ok, err)
+	}
+	if b, err := p.Bytes(1); err != nil || string(b) != "base" {
+		t.Errorf("expected base, got: %q, %v", b, err)
+	}
+}
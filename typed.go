@@ -0,0 +1,181 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ValueType is the type tag of a typed value. Typed values are stored in an
+// envelope: a byte giving the ValueType, then the encoded value. Integers and
+// floats are 8 bytes, little endian. The envelope is optional, so a database
+// can mix typed and plain values, but the typed getters only read values
+// written by the typed Put methods.
+type ValueType byte
+
+const (
+	TypeString ValueType = iota + 1
+	TypeInt64
+	TypeFloat64
+	TypeBytes
+	TypeJSON
+	// TypeProto values hold an encoded protocol buffer. The package doesn't
+	// depend on a protobuf library, so they are written and read encoded.
+	TypeProto
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeInt64:
+		return "int64"
+	case TypeFloat64:
+		return "float64"
+	case TypeBytes:
+		return "bytes"
+	case TypeJSON:
+		return "json"
+	case TypeProto:
+		return "proto"
+	}
+	return fmt.Sprintf("ValueType(%d)", byte(t))
+}
+
+// PutTyped writes a record whose value is data in an envelope tagged t.
+func (w *Writer) PutTyped(key []byte, t ValueType, data []byte) error {
+	val := make([]byte, 1+len(data))
+	val[0] = byte(t)
+	copy(val[1:], data)
+	return w.Put(key, val)
+}
+
+// PutString writes a record with a typed string value.
+func (w *Writer) PutString(key []byte, s string) error {
+	return w.PutTyped(key, TypeString, []byte(s))
+}
+
+// PutInt64 writes a record with a typed int64 value.
+func (w *Writer) PutInt64(key []byte, n int64) error {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(n))
+	return w.PutTyped(key, TypeInt64, buf[:])
+}
+
+// PutFloat64 writes a record with a typed float64 value.
+func (w *Writer) PutFloat64(key []byte, f float64) error {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+	return w.PutTyped(key, TypeFloat64, buf[:])
+}
+
+// PutTypedBytes writes a record with a typed byte slice value.
+func (w *Writer) PutTypedBytes(key, b []byte) error {
+	return w.PutTyped(key, TypeBytes, b)
+}
+
+// PutJSON writes a record with v encoded as a typed JSON value.
+func (w *Writer) PutJSON(key []byte, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.PutTyped(key, TypeJSON, b)
+}
+
+// PutProto writes a record with a typed value holding an encoded protocol
+// buffer.
+func (w *Writer) PutProto(key, encoded []byte) error {
+	return w.PutTyped(key, TypeProto, encoded)
+}
+
+// Typed returns the type and data of the first value for key, which must have
+// been written by one of the typed Put methods. Returns ErrNotFound when there
+// is no value, and ErrWrongType for an empty value, which has no envelope.
+//
+// Threadsafe.
+func (c *Cdb) Typed(key []byte) (ValueType, []byte, error) {
+	val, err := c.Bytes(key)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(val) == 0 {
+		return 0, nil, fmt.Errorf("%w: %q has an empty value", ErrWrongType, key)
+	}
+	return ValueType(val[0]), val[1:], nil
+}
+
+// typed returns the data of the first value for key, failing with
+// ErrWrongType unless it is tagged t.
+func (c *Cdb) typed(key []byte, t ValueType) ([]byte, error) {
+	got, data, err := c.Typed(key)
+	if err != nil {
+		return nil, err
+	}
+	if got != t {
+		return nil, fmt.Errorf("%w: %q is %v, not %v", ErrWrongType, key, got, t)
+	}
+	if (t == TypeInt64 || t == TypeFloat64) && len(data) != 8 {
+		return nil, fmt.Errorf("%w: %q holds %d bytes for %v", ErrWrongType, key, len(data), t)
+	}
+	return data, nil
+}
+
+// TypedString returns the first value for key, which must be a typed string.
+//
+// Threadsafe.
+func (c *Cdb) TypedString(key []byte) (string, error) {
+	data, err := c.typed(key, TypeString)
+	return string(data), err
+}
+
+// TypedInt64 returns the first value for key, which must be a typed int64.
+//
+// Threadsafe.
+func (c *Cdb) TypedInt64(key []byte) (int64, error) {
+	data, err := c.typed(key, TypeInt64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(data)), nil
+}
+
+// TypedFloat64 returns the first value for key, which must be a typed
+// float64.
+//
+// Threadsafe.
+func (c *Cdb) TypedFloat64(key []byte) (float64, error) {
+	data, err := c.typed(key, TypeFloat64)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+}
+
+// TypedBytes returns the first value for key, which must be typed bytes.
+//
+// Threadsafe.
+func (c *Cdb) TypedBytes(key []byte) ([]byte, error) {
+	return c.typed(key, TypeBytes)
+}
+
+// TypedJSON decodes the first value for key, which must be typed JSON, into
+// v.
+//
+// Threadsafe.
+func (c *Cdb) TypedJSON(key []byte, v interface{}) error {
+	data, err := c.typed(key, TypeJSON)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// TypedProto returns the encoded protocol buffer in the first value for key,
+// which must be a typed proto, for the caller to unmarshal.
+//
+// Threadsafe.
+func (c *Cdb) TypedProto(key []byte) ([]byte, error) {
+	return c.typed(key, TypeProto)
+}
@@ -0,0 +1,57 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTypedValues(t *testing.T) {
+	bw := NewBufferWriter()
+	bw.PutString([]byte("s"), "hello")
+	bw.PutInt64([]byte("i"), -42)
+	bw.PutFloat64([]byte("f"), 1.5)
+	bw.PutTypedBytes([]byte("b"), []byte{0, 1})
+	bw.PutJSON([]byte("j"), map[string]int{"a": 1})
+	bw.PutProto([]byte("p"), []byte{8, 1})
+	bw.Put([]byte("empty"), nil)
+	data, err := bw.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := New(bytes.NewReader(data))
+
+	if s, err := db.TypedString([]byte("s")); err != nil || s != "hello" {
+		t.Errorf("expected hello, got: %q, %v", s, err)
+	}
+	if n, err := db.TypedInt64([]byte("i")); err != nil || n != -42 {
+		t.Errorf("expected -42, got: %d, %v", n, err)
+	}
+	if f, err := db.TypedFloat64([]byte("f")); err != nil || f != 1.5 {
+		t.Errorf("expected 1.5, got: %v, %v", f, err)
+	}
+	if b, err := db.TypedBytes([]byte("b")); err != nil || !reflect.DeepEqual(b, []byte{0, 1}) {
+		t.Errorf("expected [0 1], got: %v, %v", b, err)
+	}
+	var m map[string]int
+	if err := db.TypedJSON([]byte("j"), &m); err != nil || m["a"] != 1 {
+		t.Errorf("expected a:1, got: %v, %v", m, err)
+	}
+	if b, err := db.TypedProto([]byte("p")); err != nil || !reflect.DeepEqual(b, []byte{8, 1}) {
+		t.Errorf("expected [8 1], got: %v, %v", b, err)
+	}
+	if typ, _, err := db.Typed([]byte("f")); err != nil || typ != TypeFloat64 {
+		t.Errorf("expected float64, got: %v, %v", typ, err)
+	}
+
+	if _, err := db.TypedInt64([]byte("s")); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType reading a string as int64, got: %v", err)
+	}
+	if _, err := db.TypedString([]byte("empty")); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType for an empty value, got: %v", err)
+	}
+	if _, err := db.TypedString([]byte("missing")); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package cdb
+
+import (
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// VerifyProgress reports how far Verify has got.
+type VerifyProgress struct {
+	// Tables is the number of hash tables checked so far, out of 256.
+	Tables int
+	// Records is the number of records checked so far.
+	Records int64
+}
+
+// VerifyOption configures Verify.
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	workers  int
+	progress func(VerifyProgress)
+}
+
+// VerifyWorkers returns a VerifyOption that checks up to n hash tables at
+// once. The default is runtime.GOMAXPROCS(0).
+func VerifyWorkers(n int) VerifyOption {
+	return func(vc *verifyConfig) {
+		if n > 0 {
+			vc.workers = n
+		}
+	}
+}
+
+// VerifyOnProgress returns a VerifyOption that calls fn each time a hash table
+// has been checked. Calls are never concurrent, and the counts only go up, so
+// fn can drive a progress bar directly.
+func VerifyOnProgress(fn func(VerifyProgress)) VerifyOption {
+	return func(vc *verifyConfig) {
+		vc.progress = fn
+	}
+}
+
+// Verify checks the structure of the database: that the hash tables lie
+// after the records without overlapping each other, that the records fill the
+// data section exactly, that every hash table slot belongs in its table and
+// points to the start of a record whose key hashes to the slot's hash, that
+// every record is pointed to by exactly one slot, and that the file ends where
+// the database does. The 256 tables are checked in parallel. It returns the
+// first problem found, as a CorruptError when the database is corrupt.
+//
+// Verify keeps the position of every record in memory while it runs.
+//
+// Threadsafe.
+func (c *Cdb) Verify(opts ...VerifyOption) error {
+	vc := verifyConfig{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&vc)
+	}
+	positions, err := c.verifyLayout()
+	if err != nil {
+		return err
+	}
+	// reached marks the records that a slot points to.
+	reached := make([]uint32, len(positions))
+
+	tables := make(chan int)
+	var (
+		mu       sync.Mutex
+		progress VerifyProgress
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < vc.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tables {
+				var n int64
+				err := c.walkSlots(i, func(recPos uint64, _, _ uint32) error {
+					j := sort.Search(len(positions), func(j int) bool { return positions[j] >= recPos })
+					if j == len(positions) || positions[j] != recPos {
+						return corruptf(recPos, "slot points into the middle of a record")
+					}
+					if !atomic.CompareAndSwapUint32(&reached[j], 0, 1) {
+						return corruptf(recPos, "more than one slot points to the record")
+					}
+					n++
+					return nil
+				})
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				progress.Tables++
+				progress.Records += n
+				if vc.progress != nil && firstErr == nil {
+					vc.progress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < 256; i++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		tables <- i
+	}
+	close(tables)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	for j, r := range reached {
+		if r == 0 {
+			return corruptf(positions[j], "record isn't in the hash tables")
+		}
+	}
+	return nil
+}
+
+// verifyLayout checks where the records, hash tables and extensions are, and
+// returns the positions of the records.
+func (c *Cdb) verifyLayout() ([]uint64, error) {
+	lay, err := c.layout()
+	if err != nil {
+		return nil, err
+	}
+	size := lay.entrySize()
+	header := make([]byte, lay.headerSize())
+	if err := readFull(c.r, header, 0); err != nil {
+		return nil, err
+	}
+	// The records end where the first table starts.
+	dataEnd, _ := lay.entry(header)
+	if dataEnd < lay.headerSize() {
+		return nil, corruptf(0, "hash table 0 starts at %d, inside the header", dataEnd)
+	}
+	type span struct{ table, pos, end uint64 }
+	spans := make([]span, 256)
+	for i := range spans {
+		pos, nslots := lay.entry(header[uint64(i)*size:])
+		if pos < dataEnd {
+			return nil, corruptf(uint64(i)*size, "hash table %d starts at %d, before the end of the records at %d", i, pos, dataEnd)
+		}
+		spans[i] = span{uint64(i), pos, pos + nslots*size}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].pos < spans[j].pos })
+	for i := 1; i < len(spans); i++ {
+		if prev := spans[i-1]; prev.end > spans[i].pos {
+			return nil, corruptf(spans[i].table*size, "hash table %d overlaps hash table %d", spans[i].table, prev.table)
+		}
+	}
+
+	var positions []uint64
+	err = c.scanRecords(0, func(pos uint64, klen, dlen uint32) error {
+		positions = append(positions, pos)
+		if pos+8+uint64(klen)+uint64(dlen) > dataEnd {
+			return corruptf(pos, "record runs past the end of the data section")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, end, err := readExtensions(c.r)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1)
+	if end > 0 {
+		if err := readFull(c.r, buf, int64(end)-1); err != nil {
+			return nil, err
+		}
+	}
+	n, err := c.r.ReadAt(buf, int64(end))
+	if n > 0 {
+		return nil, corruptf(end, "file continues past the end of the database")
+	}
+	if err != nil && err != io.EOF {
+		return nil, readErr(int64(end), n, len(buf), err)
+	}
+	return positions, nil
+}
+
+// VerifyReader is Verify for the database in r. Databases built with a
+// different hash have to be opened with WithHash and checked with Verify.
+func VerifyReader(r io.ReaderAt, opts ...VerifyOption) error {
+	return New(r).Verify(opts...)
+}
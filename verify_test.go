@@ -0,0 +1,89 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	db := New(bytes.NewReader(newDBBytes(records)))
+	var calls int
+	var last VerifyProgress
+	err := db.Verify(VerifyWorkers(4), VerifyOnProgress(func(p VerifyProgress) {
+		calls++
+		if p.Tables < last.Tables || p.Records < last.Records {
+			t.Errorf("progress went backwards: %+v after %+v", p, last)
+		}
+		last = p
+	}))
+	if err != nil {
+		t.Fatalf("Verify error: %v", err)
+	}
+	if calls != 256 || last.Tables != 256 || last.Records != 6 {
+		t.Errorf("expected 256 calls ending at 256 tables and 6 records, got %d calls ending at %+v", calls, last)
+	}
+
+	// Point a slot past the data section.
+	b := newDBBytes(records)
+	for i := 0; i < 256; i++ {
+		hpos, hslots := binary.LittleEndian.Uint32(b[i*8:]), binary.LittleEndian.Uint32(b[i*8+4:])
+		for n := uint32(0); n < hslots; n++ {
+			if spos := hpos + n*8; binary.LittleEndian.Uint32(b[spos+4:]) != 0 {
+				putNum(b[spos+4:], uint32(len(b)))
+			}
+		}
+	}
+	if err := New(bytes.NewReader(b)).Verify(); !errors.Is(err, ErrBadFormat) {
+		t.Errorf("expected a ErrBadFormat, got: %v", err)
+	}
+}
+
+func TestVerifyReader(t *testing.T) {
+	if err := VerifyReader(bytes.NewReader(newDBBytes(records))); err != nil {
+		t.Fatalf("VerifyReader error: %v", err)
+	}
+	// slots returns the positions of the used slots of the table of key.
+	slots := func(b []byte, key string) []uint32 {
+		table := checksum([]byte(key)) % 256
+		hpos, hslots := binary.LittleEndian.Uint32(b[table*8:]), binary.LittleEndian.Uint32(b[table*8+4:])
+		var used []uint32
+		for n := uint32(0); n < hslots; n++ {
+			if spos := hpos + n*8; binary.LittleEndian.Uint32(b[spos+4:]) != 0 {
+				used = append(used, spos)
+			}
+		}
+		return used
+	}
+	for _, tc := range []struct {
+		name    string
+		corrupt func(b []byte) []byte
+	}{
+		{"trailing data", func(b []byte) []byte {
+			return append(b, 0)
+		}},
+		{"unreachable record", func(b []byte) []byte {
+			putNum(b[slots(b, "one")[0]+4:], 0)
+			return b
+		}},
+		{"shared record", func(b []byte) []byte {
+			// Both records of two have the same hash.
+			used := slots(b, "two")
+			copy(b[used[1]:used[1]+8], b[used[0]:used[0]+8])
+			return b
+		}},
+		{"overlapping tables", func(b []byte) []byte {
+			// Move the last table back onto the one before it.
+			putNum(b[255*8:], binary.LittleEndian.Uint32(b[254*8:]))
+			putNum(b[255*8+4:], 2)
+			putNum(b[254*8+4:], 2)
+			return b
+		}},
+	} {
+		b := tc.corrupt(newDBBytes(records))
+		if err := VerifyReader(bytes.NewReader(b)); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: expected ErrCorrupt, got: %v", tc.name, err)
+		}
+	}
+}
@@ -2,50 +2,332 @@ package cdb
 
 import (
 	"fmt"
+	"hash"
 	"io"
+	"math"
+	"time"
 )
 
-// Writer provides a simple interface for creating CDBs by wrapping the Make
-// function.
+// Writer provides a simple interface for creating CDBs. Records are written
+// to the output as they are added, and the hash tables and header are written
+// by Close, so keys and values can contain any bytes.
 //
 // Not threadsafe.
 type Writer struct {
-	pipeWriter *io.PipeWriter
-	doneCh     chan error
-	makeErr    error
+	b *builder
+	// err is the error that stopped the build, if it has stopped.
+	err error
+	// tee receives a copy of every record written, if it is non-nil.
+	tee     io.Writer
+	teeJSON bool
+	// nrecords is the number of records written, and lastPos is the file
+	// position of the last record.
+	nrecords    int
+	lastPos     uint64
+	annotations []annotation
+	// annotationsSize is the size of the annotations section, kept up to date
+	// so sizeWith doesn't have to encode it.
+	annotationsSize uint64
+	// closed is true once Close has been called.
+	closed bool
+	// buildTime is recorded in the database if it isn't zero.
+	buildTime time.Time
+	// hash hashes keys instead of the cdb hash, if it isn't nil.
+	hash func(key []byte) uint32
+	// cdbHash hashes keys if hash is nil.
+	keyHasher hash.Hash32
+	// wide selects the 64 bit layout.
+	wide bool
+	// crc checksums each record if it isn't nil, and sums holds the
+	// checksums of the records written so far.
+	crc  hash.Hash32
+	sums []byte
+	// rateLimit is the most bytes per second to write, or 0 for no limit.
+	rateLimit int64
+	// lowIOPriority is true if the builder should use idle IO priority, and
+	// lowIO is then the thread doing its IO.
+	lowIOPriority bool
+	lowIO         *ioThread
+	// duplicates is the DuplicatePolicy, and dups tracks the keys written
+	// if it isn't AllowDuplicates.
+	duplicates DuplicatePolicy
+	dups       *dupState
+	// header holds the header of the record being written.
+	header [8]byte
+	// metrics collects the stats of the build, if BuildMetrics is used.
+	metrics *buildMetrics
 }
 
-func NewWriter(ws io.WriteSeeker) *Writer {
-	pipeReader, pipeWriter := io.Pipe()
-	w := &Writer{
-		pipeWriter: pipeWriter,
-		doneCh:     make(chan error, 1),
+// WriterOption configures a Writer.
+type WriterOption func(*Writer)
+
+// Tee returns a WriterOption that writes every record to w as cdbmake text,
+// in the format accepted by Make, while the database is built. The final
+// newline is written by Close. An error writing to w stops the build, like an
+// error writing the database, so the copy never leaves out a record the
+// database has.
+func Tee(w io.Writer) WriterOption {
+	return func(wr *Writer) {
+		wr.tee, wr.teeJSON = w, false
+	}
+}
+
+// TeeJSON is like Tee, but writes the records as JSON Lines, one
+// {"key":...,"value":...} object per line. Keys and values that aren't valid
+// UTF-8 are written base64 encoded in key_base64 and value_base64 instead.
+func TeeJSON(w io.Writer) WriterOption {
+	return func(wr *Writer) {
+		wr.tee, wr.teeJSON = w, true
+	}
+}
+
+// Hash returns a WriterOption that builds the database with fn as the hash
+// of keys instead of the cdb hash, for example a stronger hash to protect
+// against keys chosen to collide. Only readers opened with the WithHash
+// Option and the same fn can look keys up in it.
+func Hash(fn func(key []byte) uint32) WriterOption {
+	return func(w *Writer) {
+		w.hash = fn
+	}
+}
+
+func NewWriter(ws io.WriteSeeker, opts ...WriterOption) *Writer {
+	w := &Writer{keyHasher: cdbHash()}
+	for _, opt := range opts {
+		opt(w)
+	}
+	var dupErr error
+	if w.duplicates != AllowDuplicates {
+		w.dups, dupErr = newDupState(w.duplicates, ws)
+	}
+	if w.rateLimit > 0 {
+		ws = &throttledWriter{WriteSeeker: ws, rate: w.rateLimit}
+	}
+	if w.lowIOPriority {
+		w.lowIO = newIOThread(ws, setLowIOPriority)
+		ws = w.lowIO
+	}
+	w.b = newBuilder(ws, layout{wide: w.wide})
+	w.b.metrics = w.metrics
+	w.err = w.b.start()
+	if dupErr != nil {
+		w.err = dupErr
 	}
-	go func() {
-		defer pipeReader.Close()
-		w.doneCh <- Make(ws, pipeReader)
-	}()
 	return w
 }
 
+// Put adds a record to the database, writing it straight to the output. Keys
+// and values can be up to 4GB long; longer ones return ErrValueTooLarge. An
+// error writing the output stops the build, and is returned by every later
+// call.
+func (w *Writer) Put(key, val []byte) error {
+	if err := w.checkErr(); err != nil {
+		return err
+	}
+	if uint64(len(key)) > math.MaxUint32 || uint64(len(val)) > math.MaxUint32 {
+		return ErrValueTooLarge
+	}
+	if skip, err := w.checkDuplicate(key); skip || err != nil {
+		return err
+	}
+	klen, dlen := uint32(len(key)), uint32(len(val))
+	rw := w.recordWriter()
+	if err := w.writeHeader(rw, klen, dlen); err != nil {
+		return err
+	}
+	if _, err := rw.Write(key); err != nil {
+		return w.fail(err)
+	}
+	if _, err := rw.Write(val); err != nil {
+		return w.fail(err)
+	}
+	if err := w.wrote(key, dlen); err != nil {
+		return err
+	}
+	return w.teeRecord(key, val)
+}
+
+// PutReader adds a record whose value is the next valLen bytes of val,
+// streaming it to the output so it never has to be in memory, unless the
+// record is teed. Exactly valLen bytes are read, even if the record is
+// dropped as a duplicate. If val ends early, PutReader returns io.ErrUnexpectedEOF
+// and the build stops, since the output has part of a record.
+func (w *Writer) PutReader(key []byte, val io.Reader, valLen int64) error {
+	if err := w.checkErr(); err != nil {
+		return err
+	}
+	if valLen < 0 {
+		return fmt.Errorf("cdb: negative value length %d", valLen)
+	}
+	if uint64(len(key)) > math.MaxUint32 || uint64(valLen) > math.MaxUint32 {
+		return ErrValueTooLarge
+	}
+	if w.tee != nil {
+		b := make([]byte, valLen)
+		if _, err := io.ReadFull(val, b); err != nil {
+			return w.fail(unexpectedEOF(err))
+		}
+		return w.Put(key, b)
+	}
+	return w.putFrom(key, val, uint32(valLen))
+}
+
+// Write adds a record to the database, like Put.
 func (w *Writer) Write(key, val []byte) error {
-	select {
-	case err := <-w.doneCh:
-		w.makeErr = err
-	default:
+	return w.Put(key, val)
+}
+
+// keyHash returns the hash of key the database is built with.
+func (w *Writer) keyHash(key []byte) uint32 {
+	if w.hash != nil {
+		return w.hash(key)
 	}
-	if w.makeErr != nil {
-		return w.makeErr
+	w.keyHasher.Reset()
+	w.keyHasher.Write(key)
+	return w.keyHasher.Sum32()
+}
+
+// writeHeader writes the header of a record with the given key and data
+// lengths to rw.
+func (w *Writer) writeHeader(rw io.Writer, klen, dlen uint32) error {
+	putNum(w.header[:], klen)
+	putNum(w.header[4:], dlen)
+	if _, err := rw.Write(w.header[:]); err != nil {
+		return w.fail(err)
 	}
-	_, err := fmt.Fprintf(w.pipeWriter, "+%v,%v:%s->%s\n", len(key), len(val), key, val)
-	return err
+	return nil
+}
+
+// wrote adds the slot of a record with key and the given data length, once
+// all of it has been written to the builder.
+func (w *Writer) wrote(key []byte, dlen uint32) error {
+	w.nrecords++
+	w.lastPos = w.b.pos
+	if w.crc != nil {
+		var sum [4]byte
+		putNum(sum[:], w.crc.Sum32())
+		w.sums = append(w.sums, sum[:]...)
+	}
+	h := w.keyHash(key)
+	if err := w.b.add(h, uint32(len(key)), dlen); err != nil {
+		return w.fail(err)
+	}
+	w.addKey(key, h, w.lastPos)
+	return nil
+}
+
+// recordWriter returns the writer to write the next record to the builder
+// with, which checksums it if the Checksums option is set.
+func (w *Writer) recordWriter() io.Writer {
+	if w.crc == nil {
+		return w.b.wb
+	}
+	w.crc.Reset()
+	return io.MultiWriter(w.b.wb, w.crc)
+}
+
+// fail stops the build with err, since the output may hold part of a record,
+// and returns it.
+func (w *Writer) fail(err error) error {
+	if w.err == nil {
+		w.err = err
+	}
+	return w.err
 }
 
+// teeRecord writes the record to the tee, if there is one. The record is
+// already in the database, so an error stops the build.
+func (w *Writer) teeRecord(key, val []byte) error {
+	if w.tee == nil {
+		return nil
+	}
+	var err error
+	if w.teeJSON {
+		err = WriteJSONLine(w.tee, key, val)
+	} else {
+		err = writeRecord(w.tee, key, val)
+	}
+	if err != nil {
+		return w.fail(err)
+	}
+	return nil
+}
+
+// checkErr returns the error the build stopped with, if it has, or
+// ErrWriterClosed if the Writer is closed.
+func (w *Writer) checkErr() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	return w.err
+}
+
+// Flush writes all records written so far to the underlying WriteSeeker, and
+// syncs it if it has a Sync method, like *os.File. The hash tables and header
+// are still only written by Close, but a build that dies after a Flush leaves
+// complete records behind, which can be read with RecoverRecords.
+func (w *Writer) Flush() error {
+	if err := w.checkErr(); err != nil {
+		return err
+	}
+	if err := flush(w.b.wb, w.b.w); err != nil {
+		return w.fail(err)
+	}
+	return nil
+}
+
+// Close finishes the database. Calling any method of the Writer afterwards
+// returns ErrWriterClosed.
 func (w *Writer) Close() error {
-	if w.makeErr != nil {
-		return w.makeErr
+	if w.closed {
+		return ErrWriterClosed
+	}
+	w.closed = true
+	if w.lowIO != nil {
+		defer w.lowIO.close()
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.metrics != nil && w.dups != nil {
+		w.metrics.stats.Records -= int64(w.dups.dropped)
+		w.metrics.stats.Duplicates += int64(w.dups.dropped)
+	}
+	if err := w.dropDuplicates(); err != nil {
+		w.err = err
+		return err
+	}
+	if err := w.b.finish(w.extensions()); err != nil {
+		w.err = err
+		return err
+	}
+	if w.tee != nil && !w.teeJSON {
+		if _, err := w.tee.Write([]byte("\n")); err != nil {
+			w.err = err
+			return err
+		}
 	}
-	w.pipeWriter.Write([]byte("\n"))
-	w.pipeWriter.Close()
-	return <-w.doneCh
+	return nil
+}
+
+// extensions returns the extension sections to write after the hash tables.
+// It is called by Close once all records have been written.
+func (w *Writer) extensions() []extension {
+	var exts []extension
+	if len(w.annotations) > 0 {
+		exts = append(exts, extension{extAnnotations, encodeAnnotations(w.annotations)})
+	}
+	if !w.buildTime.IsZero() {
+		exts = append(exts, extension{extBuildTime, encodeBuildTime(w.buildTime)})
+	}
+	if w.crc != nil {
+		exts = append(exts, extension{extChecksums, w.sums})
+	}
+	return exts
+}
+
+// writeRecord writes the record to w in cdbmake format.
+func writeRecord(w io.Writer, key, val []byte) error {
+	_, err := fmt.Fprintf(w, "+%v,%v:%s->%s\n", len(key), len(val), key, val)
+	return err
 }
@@ -0,0 +1,269 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriterTee(t *testing.T) {
+	for _, tc := range []struct {
+		opt      func(*bytes.Buffer) WriterOption
+		expected string
+	}{
+		{func(b *bytes.Buffer) WriterOption { return Tee(b) }, string(data)},
+		{func(b *bytes.Buffer) WriterOption { return TeeJSON(b) }, `{"key":"one","value":"1"}
+{"key":"two","value":"2"}
+{"key":"two","value":"22"}
+{"key":"three","value":"3"}
+{"key":"three","value":"33"}
+{"key":"three","value":"333"}
+`},
+	} {
+		tmp, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmp.Name())
+		var tee bytes.Buffer
+		w := NewWriter(tmp, tc.opt(&tee))
+		for _, rec := range records {
+			for _, val := range rec.values {
+				if err := w.Write([]byte(rec.key), []byte(val)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if tee.String() != tc.expected {
+			t.Errorf("expected tee output %q, got: %q", tc.expected, tee.String())
+		}
+	}
+}
+
+// failingWriter fails every write with err.
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestWriterTeeError(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	teeErr := errors.New("tee failed")
+	w := NewWriter(tmp, Tee(failingWriter{teeErr}))
+	if err := w.Put([]byte("one"), []byte("1")); err != teeErr {
+		t.Fatalf("expected the tee error, got: %v", err)
+	}
+	// The build stops, so the tee never misses a record of the database.
+	if err := w.Put([]byte("two"), []byte("2")); err != teeErr {
+		t.Errorf("expected the tee error to stop the build, got: %v", err)
+	}
+	if err := w.Close(); err != teeErr {
+		t.Errorf("expected Close to return the tee error, got: %v", err)
+	}
+}
+
+func TestWriteJSONRecordBinary(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJSONLine(&b, []byte("k"), []byte{0xff, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\"key\":\"k\",\"value_base64\":\"/wA=\"}\n"; b.String() != expected {
+		t.Errorf("expected %q, got: %q", expected, b.String())
+	}
+}
+
+var adversarialRecords = []rec{
+	{"", []string{"", "empty key"}},
+	{"new\nline", []string{"\n", "\n\n+1,1:a->b\n"}},
+	{"->", []string{"->", "a->b->c"}},
+	{"+3,5:key->value\n", []string{"+0,0:->\n\n"}},
+	{"\x00\xff\x1f\x8b", []string{"\x28\xb5\x2f\xfd", "\x00"}},
+}
+
+func TestWriterAdversarialRecords(t *testing.T) {
+	var tee bytes.Buffer
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	w := NewWriter(tmp, Tee(&tee))
+	for _, rec := range adversarialRecords {
+		for _, val := range rec.values {
+			if err := w.Write([]byte(rec.key), []byte(val)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, New(bytes.NewReader(b)), adversarialRecords)
+
+	// The cdbmake text is parsed by length, so it round trips too.
+	var dump bytes.Buffer
+	if err := Dump(&dump, bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump.Bytes(), tee.Bytes()) {
+		t.Errorf("expected Dump output to match the tee, got: %q and %q", dump.Bytes(), tee.Bytes())
+	}
+	tmp2, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp2.Name())
+	if err := Make(tmp2, &tee); err != nil {
+		t.Fatalf("Make error: %v", err)
+	}
+	checkRecords(t, New(tmp2), adversarialRecords)
+}
+
+func TestWriterPutMatchesMake(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := Make(tmp, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	made, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if put := newDBBytes(records); !bytes.Equal(put, made) {
+		t.Errorf("expected Put to build the same database as Make")
+	}
+}
+
+func TestWriterPutReader(t *testing.T) {
+	for _, opts := range [][]WriterOption{nil, {Tee(ioutil.Discard)}} {
+		tmp, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmp.Name())
+		w := NewWriter(tmp, opts...)
+		for _, rec := range records {
+			for _, val := range rec.values {
+				if err := w.PutReader([]byte(rec.key), strings.NewReader(val+"trailing"), int64(len(val))); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, newDBBytes(records)) {
+			t.Errorf("expected PutReader to build the same database as Put")
+		}
+
+		w = NewWriter(tmp, opts...)
+		if err := w.PutReader([]byte("short"), strings.NewReader("abc"), 4); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected io.ErrUnexpectedEOF for a short value, got: %v", err)
+		}
+		if err := w.Put([]byte("four"), []byte("4")); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected the build to have stopped, got: %v", err)
+		}
+		w.Close()
+	}
+}
+
+// writerAt is an io.WriterAt into memory that can't seek.
+type writerAt struct {
+	b []byte
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(w.b) {
+		w.b = append(w.b, make([]byte, end-len(w.b))...)
+	}
+	return copy(w.b[off:], p), nil
+}
+
+func TestMakeWriterAt(t *testing.T) {
+	var w writerAt
+	if err := MakeWriterAt(&w, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.b, newDBBytes(records)) {
+		t.Errorf("expected MakeWriterAt to build the same database as Make")
+	}
+}
+
+// failingWriteSeeker fails every write once fail is set.
+type failingWriteSeeker struct {
+	io.WriteSeeker
+	fail bool
+}
+
+func (f *failingWriteSeeker) Write(p []byte) (int, error) {
+	if f.fail {
+		return 0, errors.New("write failed")
+	}
+	return f.WriteSeeker.Write(p)
+}
+
+func TestWriterPutErrorSticks(t *testing.T) {
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	ws := &failingWriteSeeker{WriteSeeker: tmp}
+	w := NewWriter(ws)
+	if err := w.Put([]byte("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	ws.fail = true
+	if err := w.Flush(); err == nil {
+		t.Fatal("expected Flush to fail")
+	}
+	ws.fail = false
+	if err := w.Put([]byte("b"), []byte("2")); err == nil || err.Error() != "write failed" {
+		t.Errorf("expected the write error, got: %v", err)
+	}
+	if err := w.Close(); err == nil || err.Error() != "write failed" {
+		t.Errorf("expected the write error from Close, got: %v", err)
+	}
+}
+
+// checkRecords checks that db has exactly the values in recs for each key.
+func checkRecords(t *testing.T, db *Cdb, recs []rec) {
+	for _, rec := range recs {
+		iter := db.Iterate([]byte(rec.key))
+		for _, val := range rec.values {
+			b, err := iter.NextBytes()
+			if err != nil {
+				t.Errorf("key %q: NextBytes error: %v", rec.key, err)
+				break
+			}
+			if string(b) != val {
+				t.Errorf("key %q: expected %q, got: %q", rec.key, val, b)
+			}
+		}
+		if _, err := iter.NextBytes(); err != ErrNotFound {
+			t.Errorf("key %q: expected ErrNotFound after the last value, got: %v", rec.key, err)
+		}
+	}
+}